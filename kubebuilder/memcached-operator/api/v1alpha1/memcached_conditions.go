@@ -0,0 +1,70 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// +kubebuilder:docs-gen:collapse=Apache License
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FindCondition returns the condition of the given type, or nil if the
+// status does not have one.
+func (s *MemcachedStatus) FindCondition(t MemcachedConditionType) *MemcachedCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue reports whether the condition of the given type is True.
+func (s *MemcachedStatus) IsConditionTrue(t MemcachedConditionType) bool {
+	c := s.FindCondition(t)
+	return c != nil && c.Status == corev1.ConditionTrue
+}
+
+// SetCondition adds or updates the condition of c's type. The transition
+// time is only moved when the status of the condition changes.
+func (s *MemcachedStatus) SetCondition(c MemcachedCondition) {
+	existing := s.FindCondition(c.Type)
+	if existing == nil {
+		if c.LastTransitionTime.IsZero() {
+			c.LastTransitionTime = metav1.Now()
+		}
+		s.Conditions = append(s.Conditions, c)
+		return
+	}
+	if existing.Status != c.Status {
+		existing.Status = c.Status
+		existing.LastTransitionTime = metav1.Now()
+	}
+	existing.Reason = c.Reason
+	existing.Message = c.Message
+	existing.ObservedGeneration = c.ObservedGeneration
+}
+
+// RemoveCondition drops the condition of the given type.
+func (s *MemcachedStatus) RemoveCondition(t MemcachedConditionType) {
+	conditions := s.Conditions[:0]
+	for _, c := range s.Conditions {
+		if c.Type != t {
+			conditions = append(conditions, c)
+		}
+	}
+	s.Conditions = conditions
+}
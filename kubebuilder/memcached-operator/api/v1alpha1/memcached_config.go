@@ -0,0 +1,361 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// +kubebuilder:docs-gen:collapse=Apache License
+
+package v1alpha1
+
+import (
+	"fmt"
+	"time"
+
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// DefaultMemoryMB is the item memory used when config.memoryMB is not set.
+	DefaultMemoryMB = 64
+	// DefaultMaxConnections is used when config.maxConnections is not set.
+	DefaultMaxConnections = 1024
+	// DefaultThreads is used when config.threads is not set.
+	DefaultThreads = 4
+	// DefaultPort is the port memcached listens on when config.port is not set.
+	DefaultPort = 11211
+	// DefaultMemoryOverheadPercent is used when
+	// resources.memoryOverheadPercent is not set.
+	DefaultMemoryOverheadPercent = 25
+	// DefaultMaxMemoryOverheadPercent is used when
+	// resources.maxMemoryOverheadPercent is not set.
+	DefaultMaxMemoryOverheadPercent = 200
+	// DefaultMinReplicas is used when autoscaling.minReplicas is not set.
+	DefaultMinReplicas = 1
+	// DefaultTargetCPUUtilization is the average CPU utilization the
+	// autoscaler aims for when autoscaling.metrics is empty.
+	DefaultTargetCPUUtilization = 75
+	// DefaultScaleUpCooldownSeconds is used when
+	// autoscaling.policy.scaleUpCooldownSeconds is not set.
+	DefaultScaleUpCooldownSeconds = 180
+	// DefaultScaleDownCooldownSeconds is used when
+	// autoscaling.policy.scaleDownCooldownSeconds is not set.
+	DefaultScaleDownCooldownSeconds = 900
+	// SizingWindowLayout is the time layout of sizing.windows[].start.
+	SizingWindowLayout = "15:04"
+	// MaxSize is the largest number of memcached pods of an instance.
+	MaxSize = 256
+	// MaxMemoryMB is the most item memory of a single memcached pod.
+	MaxMemoryMB = 65536
+)
+
+// Limits of the memcached configuration.
+const (
+	maxConnections = 65536
+	maxThreads     = 64
+)
+
+var (
+	// DefaultMaxItemSize is used when config.maxItemSize is not set.
+	DefaultMaxItemSize = resource.MustParse("1Mi")
+
+	minItemSize = resource.MustParse("1Ki")
+	maxItemSize = resource.MustParse("1Gi")
+)
+
+// ConfigWithDefaults returns the memcached configuration of this spec with
+// every unset field replaced by its default.
+func (s *MemcachedSpec) ConfigWithDefaults() MemcachedConfig {
+	var c MemcachedConfig
+	if s.Config != nil {
+		s.Config.DeepCopyInto(&c)
+	}
+	if c.MemoryMB == 0 {
+		c.MemoryMB = DefaultMemoryMB
+	}
+	if c.MaxConnections == 0 {
+		c.MaxConnections = DefaultMaxConnections
+	}
+	if c.Threads == 0 {
+		c.Threads = DefaultThreads
+	}
+	if c.MaxItemSize == nil {
+		size := DefaultMaxItemSize.DeepCopy()
+		c.MaxItemSize = &size
+	}
+	if c.Protocol == "" {
+		c.Protocol = ProtocolAuto
+	}
+	if c.Port == 0 {
+		c.Port = DefaultPort
+	}
+	return c
+}
+
+// ResourcesWithDefaults returns the resources section of this spec with every
+// unset field replaced by its default.
+func (s *MemcachedSpec) ResourcesWithDefaults() MemcachedResources {
+	var r MemcachedResources
+	if s.Resources != nil {
+		s.Resources.DeepCopyInto(&r)
+	}
+	if r.MemoryOverheadPercent == nil {
+		overhead := int32(DefaultMemoryOverheadPercent)
+		r.MemoryOverheadPercent = &overhead
+	}
+	if r.MaxMemoryOverheadPercent == nil {
+		max := int32(DefaultMaxMemoryOverheadPercent)
+		if max < *r.MemoryOverheadPercent {
+			max = *r.MemoryOverheadPercent
+		}
+		r.MaxMemoryOverheadPercent = &max
+	}
+	return r
+}
+
+// Validate checks a defaulted resources section at fldPath.
+func (r *MemcachedResources) Validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if *r.MemoryOverheadPercent < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("memoryOverheadPercent"), *r.MemoryOverheadPercent, "must not be negative"))
+	}
+	if *r.MaxMemoryOverheadPercent < *r.MemoryOverheadPercent {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxMemoryOverheadPercent"), *r.MaxMemoryOverheadPercent,
+			fmt.Sprintf("must not be lower than memoryOverheadPercent (%d)", *r.MemoryOverheadPercent)))
+	}
+	return allErrs
+}
+
+// AutoscalingWithDefaults returns the autoscaling section of this spec with
+// every unset field replaced by its default, or nil if autoscaling is off.
+func (s *MemcachedSpec) AutoscalingWithDefaults() *MemcachedAutoscaling {
+	if s.Autoscaling == nil {
+		return nil
+	}
+	a := s.Autoscaling.DeepCopy()
+	if a.MinReplicas == nil {
+		min := int32(DefaultMinReplicas)
+		a.MinReplicas = &min
+	}
+	if a.Policy != nil {
+		if a.Policy.ScaleUpCooldownSeconds == nil {
+			cooldown := int32(DefaultScaleUpCooldownSeconds)
+			a.Policy.ScaleUpCooldownSeconds = &cooldown
+		}
+		if a.Policy.ScaleDownCooldownSeconds == nil {
+			cooldown := int32(DefaultScaleDownCooldownSeconds)
+			a.Policy.ScaleDownCooldownSeconds = &cooldown
+		}
+		return a
+	}
+	if len(a.Metrics) == 0 {
+		target := int32(DefaultTargetCPUUtilization)
+		a.Metrics = []autoscalingv2beta2.MetricSpec{{
+			Type: autoscalingv2beta2.ResourceMetricSourceType,
+			Resource: &autoscalingv2beta2.ResourceMetricSource{
+				Name: corev1.ResourceCPU,
+				Target: autoscalingv2beta2.MetricTarget{
+					Type:               autoscalingv2beta2.UtilizationMetricType,
+					AverageUtilization: &target,
+				},
+			},
+		}}
+	}
+	return a
+}
+
+// Validate checks a defaulted autoscaling section at fldPath against the
+// resources the memcached container asks for.
+func (a *MemcachedAutoscaling) Validate(fldPath *field.Path, resources MemcachedResources) field.ErrorList {
+	var allErrs field.ErrorList
+	if *a.MinReplicas < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minReplicas"), *a.MinReplicas, "must be at least 1"))
+	}
+	if a.MaxReplicas > MaxSize {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxReplicas"), a.MaxReplicas, fmt.Sprintf("must not exceed %d", MaxSize)))
+	}
+	if *a.MinReplicas > a.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minReplicas"), *a.MinReplicas,
+			fmt.Sprintf("must not exceed maxReplicas (%d)", a.MaxReplicas)))
+	}
+	if a.Policy != nil {
+		if len(a.Metrics) > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("metrics"), "must be empty when policy is set"))
+		}
+		p := a.Policy
+		if p.MaxEvictionsPerMinute == nil && p.TargetMemoryUtilizationPercent == nil && p.MinHitRatioPercent == nil {
+			allErrs = append(allErrs, field.Required(fldPath.Child("policy"),
+				"must set at least one of maxEvictionsPerMinute, targetMemoryUtilizationPercent and minHitRatioPercent"))
+		}
+		return allErrs
+	}
+	for i, m := range a.Metrics {
+		// Utilization is relative to the request, so scaling on CPU
+		// utilization needs a CPU request. Memory always has one.
+		if m.Type == autoscalingv2beta2.ResourceMetricSourceType && m.Resource != nil &&
+			m.Resource.Name == corev1.ResourceCPU && m.Resource.Target.Type == autoscalingv2beta2.UtilizationMetricType &&
+			resources.CPU == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("metrics").Index(i), m.Resource.Name,
+				"scaling on CPU utilization requires resources.cpu to be set"))
+		}
+	}
+	return allErrs
+}
+
+// SizingWithDefaults returns the sizing section of this spec with every unset
+// field replaced by its default.
+func (s *MemcachedSpec) SizingWithDefaults() MemcachedSizing {
+	var sizing MemcachedSizing
+	if s.Sizing != nil {
+		s.Sizing.DeepCopyInto(&sizing)
+	}
+	if sizing.Mode == "" {
+		sizing.Mode = SizingRecommend
+	}
+	return sizing
+}
+
+// Validate checks the windows of a sizing section at fldPath.
+func (s *MemcachedSizing) Validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, w := range s.Windows {
+		windowPath := fldPath.Child("windows").Index(i)
+		if _, err := time.Parse(SizingWindowLayout, w.Start); err != nil {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("start"), w.Start, "must be HH:MM"))
+		}
+		if w.Duration.Duration <= 0 || w.Duration.Duration > 24*time.Hour {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("duration"), w.Duration.Duration.String(), "must be between 0 and 24h"))
+		}
+	}
+	return allErrs
+}
+
+// ServiceWithDefaults returns the service section of this spec with every
+// unset field replaced by its default. The default type depends on the
+// workload kind, so it is not filled in by the defaulting webhook.
+func (s *MemcachedSpec) ServiceWithDefaults() MemcachedService {
+	var svc MemcachedService
+	if s.Service != nil {
+		s.Service.DeepCopyInto(&svc)
+	}
+	if svc.Type == "" {
+		svc.Type = ServiceClusterIP
+		if s.WorkloadKind == WorkloadStatefulSet {
+			svc.Type = ServiceHeadless
+		}
+	}
+	if svc.PerPodType == "" {
+		svc.PerPodType = ServiceClusterIP
+	}
+	return svc
+}
+
+// Validate checks a defaulted service section at fldPath against the
+// workload kind.
+func (svc *MemcachedService) Validate(fldPath *field.Path, kind WorkloadKind) field.ErrorList {
+	var allErrs field.ErrorList
+	if kind == WorkloadStatefulSet && svc.Type != ServiceHeadless {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("type"), svc.Type, "must be Headless with workloadKind StatefulSet"))
+	}
+	if svc.PerPod && kind != WorkloadStatefulSet {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("perPod"), "requires workloadKind StatefulSet"))
+	}
+	if svc.PerPodType == ServiceHeadless {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("perPodType"), svc.PerPodType, "must not be Headless"))
+	}
+	return allErrs
+}
+
+// Validate checks the auth section at fldPath against a defaulted
+// configuration. memcached refuses to start with SASL and the text protocol.
+func (a *MemcachedAuth) Validate(fldPath *field.Path, config MemcachedConfig) field.ErrorList {
+	if config.Protocol == ProtocolASCII {
+		return field.ErrorList{field.Forbidden(fldPath, fmt.Sprintf("requires config.protocol binary or auto, got %s", config.Protocol))}
+	}
+	return nil
+}
+
+// MaxUnavailable returns disruption.maxUnavailable, defaulting to a quarter
+// of the size and at least 1. The default follows the size, so it is not
+// filled in by the defaulting webhook.
+func (s *MemcachedSpec) MaxUnavailable() intstr.IntOrString {
+	if s.Disruption != nil && s.Disruption.MaxUnavailable != nil {
+		return *s.Disruption.MaxUnavailable
+	}
+	max := s.Size / 4
+	if max < 1 {
+		max = 1
+	}
+	return intstr.FromInt(int(max))
+}
+
+// validateMaxUnavailable checks that disruption.maxUnavailable, at fldPath,
+// is a non-negative number or a percentage.
+func (s *MemcachedSpec) validateMaxUnavailable(fldPath *field.Path) field.ErrorList {
+	maxUnavailable := s.MaxUnavailable()
+	value, err := intstr.GetValueFromIntOrPercent(&maxUnavailable, int(s.Size), false)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, maxUnavailable.String(), err.Error())}
+	}
+	if value < 0 {
+		return field.ErrorList{field.Invalid(fldPath, maxUnavailable.String(), "must not be negative")}
+	}
+	return nil
+}
+
+// RequiredFeatures returns the optional memcached features this
+// configuration turns on. Every instance runs with -o modern.
+func (c *MemcachedConfig) RequiredFeatures() []Feature {
+	fs := []Feature{FeatureModern}
+	if c.LRUCrawler {
+		fs = append(fs, FeatureLRUCrawler)
+	}
+	if c.LRUMaintainer {
+		fs = append(fs, FeatureLRUMaintainer)
+	}
+	return fs
+}
+
+// Validate checks a defaulted configuration at fldPath for values memcached
+// would refuse to start with.
+func (c *MemcachedConfig) Validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, validateRange(fldPath.Child("memoryMB"), c.MemoryMB, 1, MaxMemoryMB)...)
+	allErrs = append(allErrs, validateRange(fldPath.Child("maxConnections"), c.MaxConnections, 1, maxConnections)...)
+	allErrs = append(allErrs, validateRange(fldPath.Child("threads"), c.Threads, 1, maxThreads)...)
+	allErrs = append(allErrs, validateRange(fldPath.Child("port"), c.Port, 1, 65535)...)
+	if c.MaxItemSize.Cmp(minItemSize) < 0 || c.MaxItemSize.Cmp(maxItemSize) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxItemSize"), c.MaxItemSize.String(),
+			fmt.Sprintf("must be between %s and %s", &minItemSize, &maxItemSize)))
+	} else if c.MaxItemSize.Value() > int64(c.MemoryMB)*1024*1024/2 {
+		// memcached refuses to start when a single item could take more
+		// than half of the cache.
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxItemSize"), c.MaxItemSize.String(),
+			fmt.Sprintf("must not exceed half of memoryMB (%dMB)", c.MemoryMB)))
+	}
+	if c.Threads > c.MaxConnections {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("threads"), c.Threads,
+			fmt.Sprintf("must not exceed maxConnections (%d)", c.MaxConnections)))
+	}
+	return allErrs
+}
+
+// validateRange checks that value, at fldPath, lies between min and max.
+func validateRange(fldPath *field.Path, value, min, max int32) field.ErrorList {
+	if value < min || value > max {
+		return field.ErrorList{field.Invalid(fldPath, value, fmt.Sprintf("must be between %d and %d", min, max))}
+	}
+	return nil
+}
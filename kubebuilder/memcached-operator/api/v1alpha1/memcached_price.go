@@ -0,0 +1,64 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// +kubebuilder:docs-gen:collapse=Apache License
+
+package v1alpha1
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// amountPattern is the plain decimal notation of amounts, without sign,
+// exponent, base prefix or digit separators.
+var amountPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// ParseAmount parses a non-negative decimal amount such as "10" or "0.25"
+// exactly.
+func ParseAmount(amount string) (*big.Rat, error) {
+	if !amountPattern.MatchString(amount) {
+		return nil, fmt.Errorf("%q is not a decimal amount", amount)
+	}
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a decimal amount", amount)
+	}
+	return r, nil
+}
+
+// ParsePrice splits a price of the form "<AMOUNT> <CURRENCY>", for example
+// "10 USD" or "0.25 EUR", into its amount and currency. The amount is exact.
+func ParsePrice(price string) (*big.Rat, string, error) {
+	parts := strings.Fields(price)
+	if len(parts) != 2 {
+		return nil, "", fmt.Errorf("price %q must have the form \"<AMOUNT> <CURRENCY>\"", price)
+	}
+	amount, err := ParseAmount(parts[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("price %q has an invalid amount: %v", price, err)
+	}
+	return amount, parts[1], nil
+}
+
+// ParsedPrice returns the amount and currency of spec.price, or a nil amount
+// when no price is set.
+func (s *MemcachedSpec) ParsedPrice() (*big.Rat, string, error) {
+	if strings.TrimSpace(s.Price) == "" {
+		return nil, "", nil
+	}
+	return ParsePrice(s.Price)
+}
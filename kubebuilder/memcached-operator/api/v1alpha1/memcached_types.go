@@ -22,7 +22,11 @@ package v1alpha1
 /*
  */
 import (
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -39,16 +43,472 @@ type MemcachedSpec struct {
 	// +kubebuilder:validation:MinLength=0
 	// Price is a field representing price per GB for a disk. It is specified in the
 	// the format "<AMOUNT> <CURRENCY>". Example values will be "10 USD", "100 USD"
+	// The controller reads it as the monthly price of a GB of pod memory
+	// and reports the cost of the instance in status.cost.
 	Price string `json:"price"`
 
 	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=256
 	// Size is the size of the memcached deployment
 	Size int32 `json:"size"`
 
-	// This flag tells the controller to suspend subsequent executions, it does
-	// not apply to already started executions.  Defaults to false.
+	// Suspend scales the instance to zero and stops the controller from
+	// changing it until the flag is cleared again. The number of replicas at
+	// the time of suspension is kept in status and restored on resume.
+	// Defaults to false.
 	// +optional
 	Suspend *bool `json:"suspend,omitempty"`
+
+	// Version is the memcached release to run, for example "1.6.9". When
+	// changed, pods are replaced one at a time. Defaults to the tag of Image,
+	// or 1.4.36 when Image is not set either.
+	// +kubebuilder:validation:Pattern=`^v?[0-9]+\.[0-9]+\.[0-9]+$`
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Image overrides the memcached container image. Defaults to the alpine
+	// variant of the official image for Version.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Config tunes the memcached server. Unset fields keep their defaults.
+	// +optional
+	Config *MemcachedConfig `json:"config,omitempty"`
+
+	// Resources controls the container resources derived from the cache size.
+	// +optional
+	Resources *MemcachedResources `json:"resources,omitempty"`
+
+	// Autoscaling makes the controller manage a HorizontalPodAutoscaler that
+	// scales this Memcached through its scale subresource, or scale it
+	// itself when a policy is set.
+	// +optional
+	Autoscaling *MemcachedAutoscaling `json:"autoscaling,omitempty"`
+
+	// Sizing controls the memory and replica recommendation in
+	// status.recommendation, and whether it is applied.
+	// +optional
+	Sizing *MemcachedSizing `json:"sizing,omitempty"`
+
+	// DeletionPolicy says what happens to the memcached pods when this
+	// Memcached is deleted: Delete removes them, Orphan leaves them and their
+	// Services running. Defaults to Delete.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// WorkloadKind selects what runs the memcached pods. A StatefulSet gives
+	// every pod a stable hostname of the form <name>-<ordinal>.<name>, which
+	// consistent-hashing clients need to keep their shards across restarts.
+	// Changing it on a running instance brings up the new workload before
+	// the old one is removed. Defaults to Deployment.
+	// +optional
+	WorkloadKind WorkloadKind `json:"workloadKind,omitempty"`
+	// Service controls how the memcached pods are exposed. A Service named
+	// after the instance is always created.
+	// +optional
+	Service *MemcachedService `json:"service,omitempty"`
+	// Scheduling controls where the memcached pods may run. Unless it sets
+	// an affinity or topology spread of its own, pods are spread across
+	// nodes and zones so that one failure takes out as few shards as
+	// possible.
+	// +optional
+	Scheduling *MemcachedScheduling `json:"scheduling,omitempty"`
+	// Disruption controls the PodDisruptionBudget of the memcached pods.
+	// +optional
+	Disruption *MemcachedDisruption `json:"disruption,omitempty"`
+	// TLS encrypts client connections. It needs memcached 1.5.13 or later.
+	// +optional
+	TLS *MemcachedTLS `json:"tls,omitempty"`
+	// Auth requires clients to authenticate with SASL. Only the binary
+	// protocol supports SASL, so config.protocol must not be ascii.
+	// +optional
+	Auth *MemcachedAuth `json:"auth,omitempty"`
+	// Monitoring controls how the controller observes the memcached pods.
+	// +optional
+	Monitoring *MemcachedMonitoring `json:"monitoring,omitempty"`
+}
+
+// DeletionPolicy says what happens to the objects created for a Memcached
+// when it is deleted.
+// +kubebuilder:validation:Enum=Delete;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete removes every object created for the instance.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan detaches the memcached pods and their Services
+	// from the instance, so that they keep serving after it is gone.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// WorkloadKind is the kind of object that runs the memcached pods.
+// +kubebuilder:validation:Enum=Deployment;StatefulSet
+type WorkloadKind string
+
+const (
+	// WorkloadDeployment runs interchangeable pods from a Deployment.
+	WorkloadDeployment WorkloadKind = "Deployment"
+	// WorkloadStatefulSet runs pods with stable identities from a
+	// StatefulSet behind a headless Service.
+	WorkloadStatefulSet WorkloadKind = "StatefulSet"
+)
+
+// MemcachedServiceType is how a memcached Service is exposed.
+// +kubebuilder:validation:Enum=ClusterIP;Headless;NodePort;LoadBalancer
+type MemcachedServiceType string
+
+const (
+	// ServiceClusterIP exposes the pods on a cluster-internal virtual IP.
+	ServiceClusterIP MemcachedServiceType = "ClusterIP"
+	// ServiceHeadless publishes the address of every pod in DNS instead of
+	// a virtual IP.
+	ServiceHeadless MemcachedServiceType = "Headless"
+	// ServiceNodePort additionally exposes the pods on a port of every node.
+	ServiceNodePort MemcachedServiceType = "NodePort"
+	// ServiceLoadBalancer additionally exposes the pods through a cloud load
+	// balancer.
+	ServiceLoadBalancer MemcachedServiceType = "LoadBalancer"
+)
+
+// MemcachedService configures the Services of a Memcached.
+type MemcachedService struct {
+	// Type of the Service named after the instance. With a StatefulSet it
+	// also gives the pods their DNS names, so it must be Headless there.
+	// Defaults to ClusterIP for a Deployment and Headless for a StatefulSet.
+	// +optional
+	Type MemcachedServiceType `json:"type,omitempty"`
+
+	// Annotations are added to every Service, for example to configure a
+	// cloud load balancer.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// PerPod additionally creates one Service per pod, named after the pod,
+	// for clients that address every memcached node directly. It requires
+	// workloadKind StatefulSet, whose pod names are stable.
+	// +optional
+	PerPod bool `json:"perPod,omitempty"`
+
+	// PerPodType is the type of the per-pod Services. Defaults to ClusterIP.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +optional
+	PerPodType MemcachedServiceType `json:"perPodType,omitempty"`
+}
+
+// MemcachedScheduling controls where memcached pods are scheduled.
+type MemcachedScheduling struct {
+	// NodeSelector restricts the pods to nodes with these labels.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations let the pods run on tainted nodes.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// PriorityClassName is the priority class of the pods.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Affinity is added to the pods. When it has no pod anti-affinity, the
+	// default spreading across nodes and zones is added to it.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints replace the default spreading across nodes
+	// and zones.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+}
+
+// MemcachedDisruption controls how many memcached pods voluntary
+// disruptions, such as node drains, may take down at once.
+type MemcachedDisruption struct {
+	// MaxUnavailable is the number or percentage of pods that may be
+	// unavailable during a voluntary disruption. Defaults to a quarter of
+	// spec.size, and at least 1. While the instance is suspended, any number
+	// of pods may be disrupted.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// MemcachedTLS configures TLS for client connections.
+type MemcachedTLS struct {
+	// SecretName is the name of a kubernetes.io/tls Secret in the same
+	// namespace holding the server certificate chain and key.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+
+	// ClientCASecretName is the name of a Secret in the same namespace
+	// whose ca.crt key holds the CA that client certificates must be signed
+	// by. Client certificates are not required when it is not set.
+	// +optional
+	ClientCASecretName string `json:"clientCASecretName,omitempty"`
+}
+
+// MemcachedAuth configures SASL authentication.
+type MemcachedAuth struct {
+	// SecretName is the name of a Secret in the same namespace listing the
+	// SASL users: every key is a user name and its value the password.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
+
+// MemcachedMonitoring controls how the controller observes a Memcached.
+type MemcachedMonitoring struct {
+	// DisableStats stops the controller from polling the pods for their
+	// statistics. Autoscaling policies and sizing rely on them.
+	// +optional
+	DisableStats bool `json:"disableStats,omitempty"`
+}
+
+// Protocol selects which memcached protocols a server accepts.
+// +kubebuilder:validation:Enum=auto;ascii;binary
+type Protocol string
+
+const (
+	// ProtocolAuto negotiates the protocol per connection.
+	ProtocolAuto Protocol = "auto"
+	// ProtocolASCII only accepts the text protocol.
+	ProtocolASCII Protocol = "ascii"
+	// ProtocolBinary only accepts the binary protocol.
+	ProtocolBinary Protocol = "binary"
+)
+
+// MemcachedConfig is the memcached server configuration.
+type MemcachedConfig struct {
+	// MemoryMB is the item memory in megabytes (-m). Defaults to 64.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65536
+	// +optional
+	MemoryMB int32 `json:"memoryMB,omitempty"`
+
+	// MaxConnections is the maximum number of simultaneous connections (-c).
+	// Defaults to 1024.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65536
+	// +optional
+	MaxConnections int32 `json:"maxConnections,omitempty"`
+
+	// Threads is the number of worker threads (-t). Defaults to 4.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	Threads int32 `json:"threads,omitempty"`
+
+	// MaxItemSize is the largest item memcached stores (-I), for example
+	// "1Mi". It must lie between 1Ki and 1Gi and must not exceed half of
+	// MemoryMB. Defaults to 1Mi.
+	// +optional
+	MaxItemSize *resource.Quantity `json:"maxItemSize,omitempty"`
+
+	// DisableEvictions makes memcached return an error instead of evicting
+	// items when it runs out of memory (-M).
+	// +optional
+	DisableEvictions bool `json:"disableEvictions,omitempty"`
+
+	// LRUCrawler enables the background LRU crawler that reclaims expired
+	// items (-o lru_crawler). Requires memcached 1.4.18.
+	// +optional
+	LRUCrawler bool `json:"lruCrawler,omitempty"`
+
+	// LRUMaintainer enables the segmented LRU maintainer thread
+	// (-o lru_maintainer). Requires memcached 1.4.24.
+	// +optional
+	LRUMaintainer bool `json:"lruMaintainer,omitempty"`
+
+	// EnableUDP also serves the UDP protocol on Port (-U). UDP is disabled by
+	// default.
+	// +optional
+	EnableUDP bool `json:"enableUDP,omitempty"`
+
+	// Protocol restricts the accepted protocols (-B). Defaults to auto.
+	// +optional
+	Protocol Protocol `json:"protocol,omitempty"`
+
+	// Port is the port memcached listens on (-p). Defaults to 11211.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+}
+
+// MemcachedResources controls how container resources are derived from
+// config.memoryMB. Memory requests and limits are both set to the item memory
+// plus MemoryOverheadPercent, which covers connection buffers, the hash table
+// and the process itself.
+type MemcachedResources struct {
+	// MemoryOverheadPercent is added on top of the item memory. Defaults to 25.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=400
+	// +optional
+	MemoryOverheadPercent *int32 `json:"memoryOverheadPercent,omitempty"`
+
+	// MaxMemoryOverheadPercent caps how far the overhead is raised after pods
+	// are OOMKilled. Defaults to 200.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=400
+	// +optional
+	MaxMemoryOverheadPercent *int32 `json:"maxMemoryOverheadPercent,omitempty"`
+
+	// DisableOOMCorrection stops the controller from raising the overhead
+	// when a memcached container is OOMKilled.
+	// +optional
+	DisableOOMCorrection bool `json:"disableOOMCorrection,omitempty"`
+
+	// CPU is the CPU request of the memcached container.
+	// +optional
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+}
+
+// MemcachedAutoscaling configures the HorizontalPodAutoscaler managed for a
+// Memcached.
+type MemcachedAutoscaling struct {
+	// MinReplicas is the lower limit the autoscaler may scale down to.
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper limit the autoscaler may scale up to.
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// Metrics the autoscaler scales on. Defaults to 75% average CPU
+	// utilization, which requires spec.resources.cpu to be set. Must be
+	// empty when a policy is set.
+	// +optional
+	Metrics []autoscalingv2beta2.MetricSpec `json:"metrics,omitempty"`
+
+	// Policy makes the controller scale the instance itself on the
+	// statistics of the memcached pods, instead of managing a
+	// HorizontalPodAutoscaler. It needs the statistics to be polled.
+	// +optional
+	Policy *MemcachedAutoscalingPolicy `json:"policy,omitempty"`
+}
+
+// MemcachedAutoscalingPolicy sets the targets the controller scales a
+// Memcached on. The instance grows by one replica, or more to bring memory
+// utilization to its target, while any target is missed. It shrinks by one
+// replica while memory utilization is low and nothing is evicted.
+type MemcachedAutoscalingPolicy struct {
+	// MaxEvictionsPerMinute is the eviction rate, over all pods, above
+	// which the instance grows.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxEvictionsPerMinute *int64 `json:"maxEvictionsPerMinute,omitempty"`
+
+	// TargetMemoryUtilizationPercent is the share of the item memory the
+	// instance aims to use.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	TargetMemoryUtilizationPercent *int32 `json:"targetMemoryUtilizationPercent,omitempty"`
+
+	// MinHitRatioPercent is the share of gets that must find their item.
+	// The instance grows while the hit ratio is lower.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	MinHitRatioPercent *int32 `json:"minHitRatioPercent,omitempty"`
+
+	// ScaleUpCooldownSeconds is how long to wait after scaling before
+	// growing again. Defaults to 180.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ScaleUpCooldownSeconds *int32 `json:"scaleUpCooldownSeconds,omitempty"`
+
+	// ScaleDownCooldownSeconds is how long to wait after scaling before
+	// shrinking. Defaults to 900.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ScaleDownCooldownSeconds *int32 `json:"scaleDownCooldownSeconds,omitempty"`
+}
+
+// SizingMode says what happens to the sizing recommendation.
+// +kubebuilder:validation:Enum=Recommend;Auto
+type SizingMode string
+
+const (
+	// SizingRecommend only publishes the recommendation in status.
+	SizingRecommend SizingMode = "Recommend"
+	// SizingAuto also applies it to spec.config.memoryMB and spec.size.
+	SizingAuto SizingMode = "Auto"
+)
+
+// MemcachedSizing controls the sizing recommendation of a Memcached.
+type MemcachedSizing struct {
+	// Mode says whether the recommendation is applied. Changing the memory
+	// restarts every pod, emptying its part of the cache. With autoscaling
+	// only the memory is applied. Defaults to Recommend.
+	// +optional
+	Mode SizingMode `json:"mode,omitempty"`
+
+	// Windows are the daily windows in which Auto applies the
+	// recommendation, at most once per window. Without windows it is
+	// applied at most once an hour.
+	// +optional
+	Windows []MemcachedSizingWindow `json:"windows,omitempty"`
+}
+
+// MemcachedSizingWindow is a daily window for applying recommendations.
+type MemcachedSizingWindow struct {
+	// Start is the time of day the window opens, as HH:MM in UTC.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// Duration is how long the window stays open, at most 24h.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// MemcachedConditionType is the type of a Memcached condition.
+type MemcachedConditionType string
+
+const (
+	// MemcachedAvailable means at least one memcached pod is ready to serve.
+	MemcachedAvailable MemcachedConditionType = "Available"
+	// MemcachedProgressing means pods are being created, scaled or replaced.
+	MemcachedProgressing MemcachedConditionType = "Progressing"
+	// MemcachedDegraded means the controller cannot bring the instance to
+	// the desired state. The reason and message say why.
+	MemcachedDegraded MemcachedConditionType = "Degraded"
+	// MemcachedSuspended means spec.suspend is set and the instance has been
+	// scaled to zero.
+	MemcachedSuspended MemcachedConditionType = "Suspended"
+	// MemcachedTerminating means the instance is being deleted and the
+	// controller is tearing down what it created for it.
+	MemcachedTerminating MemcachedConditionType = "Terminating"
+	// MemcachedTLSReady means client connections are encrypted with the
+	// certificate from spec.tls. It is False when that certificate cannot
+	// be used.
+	MemcachedTLSReady MemcachedConditionType = "TLS"
+	// MemcachedAuthReady means clients must authenticate as one of the
+	// users from spec.auth. It is False when those users cannot be read.
+	MemcachedAuthReady MemcachedConditionType = "Auth"
+)
+
+// MemcachedCondition describes one aspect of the state of a Memcached.
+type MemcachedCondition struct {
+	// Type of the condition.
+	Type MemcachedConditionType `json:"type"`
+
+	// Status of the condition, one of True, False or Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+
+	// ObservedGeneration is the .metadata.generation the condition was set
+	// from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastTransitionTime is the last time the condition changed status.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a CamelCase reason for the last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // MemcachedStatus defines the observed state of Memcached
@@ -59,6 +519,197 @@ type MemcachedStatus struct {
 	// Nodes are the names of the memcached pods
 
 	Nodes []string `json:"nodes"`
+
+	// Version is the memcached release every pod is running. It is only
+	// updated once a version change has been fully rolled out.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// MemoryOverheadPercent is the memory overhead currently applied.
+	// +optional
+	MemoryOverheadPercent int32 `json:"memoryOverheadPercent,omitempty"`
+
+	// OOMOverheadPercent is how far the overhead has been raised above
+	// spec.resources.memoryOverheadPercent in response to OOM kills.
+	// +optional
+	OOMOverheadPercent int32 `json:"oomOverheadPercent,omitempty"`
+
+	// OOMKills counts the OOM kills the controller has corrected for.
+	// +optional
+	OOMKills int32 `json:"oomKills,omitempty"`
+
+	// LastOOMKillTime is when the most recent OOM kill happened.
+	// +optional
+	LastOOMKillTime *metav1.Time `json:"lastOOMKillTime,omitempty"`
+
+	// ObservedGeneration is the most recent .metadata.generation the
+	// controller has acted on.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ReadyReplicas is the number of memcached pods ready to serve.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// LastError is the error that stopped the last reconcile, if any.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// Conditions are the latest observations of the instance's state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []MemcachedCondition `json:"conditions,omitempty"`
+
+	// Replicas is the number of memcached pods. It backs the scale
+	// subresource.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector is the label selector of the memcached pods in string form.
+	// It backs the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// SuspendedReplicas is the number of replicas the instance had when it
+	// was suspended. It is restored when the instance is resumed.
+	// +optional
+	SuspendedReplicas *int32 `json:"suspendedReplicas,omitempty"`
+
+	// Stats are the statistics last collected from the memcached pods.
+	// +optional
+	Stats *MemcachedStats `json:"stats,omitempty"`
+
+	// Autoscaling records what spec.autoscaling.policy decided.
+	// +optional
+	Autoscaling *MemcachedAutoscalingStatus `json:"autoscaling,omitempty"`
+
+	// Recommendation is the memory and replica count the statistics call
+	// for.
+	// +optional
+	Recommendation *MemcachedRecommendation `json:"recommendation,omitempty"`
+
+	// Cost is what running the instance costs at its current size, from
+	// spec.price. It is not set without a price.
+	// +optional
+	Cost *MemcachedCost `json:"cost,omitempty"`
+}
+
+// MemcachedAutoscalingStatus records the scaling decisions of the controller.
+type MemcachedAutoscalingStatus struct {
+	// LastScaleTime is when the controller last changed spec.size.
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+
+	// Decisions are the most recent scaling decisions, oldest first.
+	// +optional
+	Decisions []MemcachedScalingDecision `json:"decisions,omitempty"`
+}
+
+// MemcachedScalingDecision is a change of spec.size made by the controller.
+type MemcachedScalingDecision struct {
+	// Time is when the decision was made.
+	Time metav1.Time `json:"time"`
+
+	// From is spec.size before the decision.
+	From int32 `json:"from"`
+
+	// To is spec.size after the decision.
+	To int32 `json:"to"`
+
+	// Reason is the CamelCase name of the target that drove the decision.
+	Reason string `json:"reason"`
+
+	// Message describes the statistics behind the decision.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// MemcachedRecommendation is the size the statistics of a Memcached call
+// for.
+type MemcachedRecommendation struct {
+	// ComputedTime is when the recommendation was computed.
+	ComputedTime metav1.Time `json:"computedTime"`
+
+	// MemoryMB is the recommended item memory of every pod.
+	MemoryMB int32 `json:"memoryMB"`
+
+	// Replicas is the recommended number of pods.
+	Replicas int32 `json:"replicas"`
+
+	// Reason is the CamelCase name of what the recommendation is based on,
+	// WorkingSet or Evictions.
+	Reason string `json:"reason"`
+
+	// Message describes the statistics behind the recommendation.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// AppliedTime is when sizing mode Auto last applied a recommendation.
+	// +optional
+	AppliedTime *metav1.Time `json:"appliedTime,omitempty"`
+}
+
+// MemcachedCost is the cost of a Memcached.
+type MemcachedCost struct {
+	// MonthlyAmount is the cost of a month, as a decimal number with two
+	// decimal places.
+	MonthlyAmount string `json:"monthlyAmount"`
+
+	// Currency is the currency of spec.price.
+	Currency string `json:"currency"`
+
+	// MemoryGB is the memory of all pods the cost is based on, in GB as a
+	// decimal number.
+	MemoryGB string `json:"memoryGB"`
+}
+
+// MemcachedStats are statistics collected from the memcached pods over the
+// text protocol, summed over the pods that answered. Counters start over
+// when a pod restarts.
+type MemcachedStats struct {
+	// CollectedTime is when the statistics were collected.
+	CollectedTime metav1.Time `json:"collectedTime"`
+
+	// Pods is the number of pods that answered.
+	Pods int32 `json:"pods"`
+
+	// GetHits is the number of gets that found their item.
+	GetHits int64 `json:"getHits"`
+
+	// GetMisses is the number of gets that did not find their item.
+	GetMisses int64 `json:"getMisses"`
+
+	// HitRatioPercent is the share of gets that found their item. It is not
+	// set before the first get.
+	// +optional
+	HitRatioPercent *int32 `json:"hitRatioPercent,omitempty"`
+
+	// Evictions is the number of items evicted to make room for new ones.
+	Evictions int64 `json:"evictions"`
+
+	// EvictionsPerMinute is the eviction rate since the previous poll. It
+	// is not set when there is no previous poll or a pod has restarted.
+	// +optional
+	EvictionsPerMinute *int64 `json:"evictionsPerMinute,omitempty"`
+
+	// BytesUsed is the memory used to store items.
+	BytesUsed int64 `json:"bytesUsed"`
+
+	// LimitMaxBytes is the memory the pods may use to store items.
+	LimitMaxBytes int64 `json:"limitMaxBytes"`
+
+	// MallocedBytes is the memory allocated to slabs.
+	MallocedBytes int64 `json:"mallocedBytes"`
+
+	// CurrentConnections is the number of open client connections.
+	CurrentConnections int64 `json:"currentConnections"`
+
+	// OldestItemAgeSeconds is the age of the least recently used item in
+	// the slab class that holds on to items the shortest. With evictions,
+	// it is roughly how long an item survives in the cache.
+	// +optional
+	OldestItemAgeSeconds *int64 `json:"oldestItemAgeSeconds,omitempty"`
 }
 
 /*
@@ -77,8 +728,15 @@ objects are created/updated after the change.
 
 // Memcached is the Schema for the memcacheds API
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.size,statuspath=.status.replicas,selectorpath=.status.selector
 // k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.spec.size`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="Degraded",type=string,JSONPath=`.status.conditions[?(@.type=="Degraded")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // Memcached is the Schema for the memcached API
 type Memcached struct {
 	metav1.TypeMeta   `json:",inline"`
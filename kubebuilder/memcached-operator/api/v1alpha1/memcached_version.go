@@ -0,0 +1,190 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// +kubebuilder:docs-gen:collapse=Apache License
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultVersion is the memcached version run when neither spec.version
+	// nor spec.image is set.
+	DefaultVersion = "1.4.36"
+
+	// DefaultImageRepository is the repository the image is pulled from when
+	// spec.image is not set.
+	DefaultImageRepository = "memcached"
+)
+
+// MinimumVersion is the oldest memcached release the operator knows how to run.
+var MinimumVersion = MemcachedVersion{Major: 1, Minor: 4, Patch: 0}
+
+// MemcachedVersion is a parsed memcached release number.
+// +kubebuilder:object:generate=false
+type MemcachedVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses a memcached version such as "1.6.9". A leading "v" and
+// any suffix after a "-" (for example "1.6.9-alpine") are ignored.
+func ParseVersion(s string) (MemcachedVersion, error) {
+	v := strings.TrimPrefix(s, "v")
+	if i := strings.Index(v, "-"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return MemcachedVersion{}, fmt.Errorf("invalid memcached version %q: expected <major>.<minor>.<patch>", s)
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return MemcachedVersion{}, fmt.Errorf("invalid memcached version %q", s)
+		}
+		nums[i] = n
+	}
+	return MemcachedVersion{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+// String returns the version in <major>.<minor>.<patch> form.
+func (v MemcachedVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 if v is older than, equal to or newer than o.
+func (v MemcachedVersion) Compare(o MemcachedVersion) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether v is the same as or newer than o.
+func (v MemcachedVersion) AtLeast(o MemcachedVersion) bool {
+	return v.Compare(o) >= 0
+}
+
+// Feature is an optional memcached capability that only exists from a
+// given release onwards.
+type Feature string
+
+const (
+	// FeatureModern is the "-o modern" option bundle.
+	FeatureModern Feature = "modern"
+	// FeatureLRUCrawler is the background LRU crawler, "-o lru_crawler".
+	FeatureLRUCrawler Feature = "lru_crawler"
+	// FeatureLRUMaintainer is the segmented LRU maintainer, "-o lru_maintainer".
+	FeatureLRUMaintainer Feature = "lru_maintainer"
+	// FeatureTLS is TLS for client connections, "-Z".
+	FeatureTLS Feature = "tls"
+)
+
+// featureVersions records the first memcached release supporting each Feature.
+var featureVersions = map[Feature]MemcachedVersion{
+	FeatureModern:        {Major: 1, Minor: 4, Patch: 33},
+	FeatureLRUCrawler:    {Major: 1, Minor: 4, Patch: 18},
+	FeatureLRUMaintainer: {Major: 1, Minor: 4, Patch: 24},
+	FeatureTLS:           {Major: 1, Minor: 5, Patch: 13},
+}
+
+// Supports reports whether memcached v understands feature f.
+func (v MemcachedVersion) Supports(f Feature) bool {
+	min, ok := featureVersions[f]
+	if !ok {
+		return false
+	}
+	return v.AtLeast(min)
+}
+
+// RequireFeatures returns an error naming every feature in fs that memcached
+// v does not support, or nil if all of them are available.
+func (v MemcachedVersion) RequireFeatures(fs ...Feature) error {
+	var unsupported []string
+	for _, f := range fs {
+		if !v.Supports(f) {
+			unsupported = append(unsupported, fmt.Sprintf("%s (requires %s)", f, featureVersions[f]))
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("memcached %s does not support %s", v, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// imageTag returns the tag of an image reference, or "" if it has none.
+func imageTag(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	slash := strings.LastIndex(image, "/")
+	colon := strings.LastIndex(image, ":")
+	if colon <= slash {
+		return ""
+	}
+	return image[colon+1:]
+}
+
+// ContainerImage returns the container image to run for this spec.
+func (s *MemcachedSpec) ContainerImage() string {
+	if s.Image != "" {
+		return s.Image
+	}
+	version := s.Version
+	if version == "" {
+		version = DefaultVersion
+	}
+	return fmt.Sprintf("%s:%s-alpine", DefaultImageRepository, version)
+}
+
+// ParsedVersion returns the memcached version this spec asks for. It is
+// spec.version when set, otherwise it is taken from the tag of spec.image,
+// and DefaultVersion when neither is set.
+func (s *MemcachedSpec) ParsedVersion() (MemcachedVersion, error) {
+	var v MemcachedVersion
+	var err error
+	switch {
+	case s.Version != "":
+		v, err = ParseVersion(s.Version)
+	case s.Image != "":
+		tag := imageTag(s.Image)
+		if tag == "" {
+			return v, fmt.Errorf("spec.version is required when spec.image %q has no version tag", s.Image)
+		}
+		if v, err = ParseVersion(tag); err != nil {
+			return v, fmt.Errorf("spec.version is required when the tag of spec.image %q is not a memcached version", s.Image)
+		}
+	default:
+		v, err = ParseVersion(DefaultVersion)
+	}
+	if err != nil {
+		return v, err
+	}
+	if !v.AtLeast(MinimumVersion) {
+		return v, fmt.Errorf("memcached %s is not supported, the minimum version is %s", v, MinimumVersion)
+	}
+	return v, nil
+}
@@ -0,0 +1,71 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import "testing"
+
+func TestParsedVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    MemcachedSpec
+		image   string
+		version string
+		wantErr bool
+	}{
+		{name: "defaults", image: "memcached:1.4.36-alpine", version: "1.4.36"},
+		{name: "version only", spec: MemcachedSpec{Version: "1.6.9"}, image: "memcached:1.6.9-alpine", version: "1.6.9"},
+		{name: "image tag", spec: MemcachedSpec{Image: "registry:5000/cache/memcached:1.5.22"}, image: "registry:5000/cache/memcached:1.5.22", version: "1.5.22"},
+		{name: "image and version", spec: MemcachedSpec{Image: "mirror/memcached:latest", Version: "v1.6.0"}, image: "mirror/memcached:latest", version: "1.6.0"},
+		{name: "untagged image", spec: MemcachedSpec{Image: "registry:5000/memcached"}, wantErr: true},
+		{name: "non version tag", spec: MemcachedSpec{Image: "memcached:latest"}, wantErr: true},
+		{name: "malformed version", spec: MemcachedSpec{Version: "1.6"}, wantErr: true},
+		{name: "too old", spec: MemcachedSpec{Version: "1.2.8"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.spec.ParsedVersion()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got version %s", v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v.String() != tt.version {
+				t.Errorf("version = %s, want %s", v, tt.version)
+			}
+			if image := tt.spec.ContainerImage(); image != tt.image {
+				t.Errorf("image = %s, want %s", image, tt.image)
+			}
+		})
+	}
+}
+
+func TestSupports(t *testing.T) {
+	old := MemcachedVersion{Major: 1, Minor: 4, Patch: 20}
+	if old.Supports(FeatureModern) {
+		t.Errorf("%s should not support %s", old, FeatureModern)
+	}
+	if err := old.RequireFeatures(FeatureModern); err == nil {
+		t.Errorf("expected RequireFeatures to fail for %s", old)
+	}
+	current := MemcachedVersion{Major: 1, Minor: 6, Patch: 0}
+	if err := current.RequireFeatures(FeatureModern); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
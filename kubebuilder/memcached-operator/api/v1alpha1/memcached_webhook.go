@@ -17,9 +17,15 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"errors"
+	"context"
+	"fmt"
 
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -42,6 +48,27 @@ func (r *Memcached) SetupWebhookWithManager(mgr ctrl.Manager) error {
 		Complete()
 }
 
+// PolicyChecker checks a Memcached against the admission policies of the
+// cluster. Check returns an error for every rule the Memcached breaks, and
+// an error of its own when the policies could not be checked. ValidatePolicy
+// returns an error for every part of a MemcachedPolicy spec at fldPath that
+// can't be checked, such as rule expressions that don't compile.
+// +kubebuilder:object:generate=false
+type PolicyChecker interface {
+	Check(ctx context.Context, m *Memcached) (field.ErrorList, error)
+	ValidatePolicy(spec *MemcachedPolicySpec, fldPath *field.Path) field.ErrorList
+}
+
+// policies is consulted by the validating webhooks, if set.
+var policies PolicyChecker
+
+// SetPolicyChecker makes the validating webhooks check Memcacheds against
+// checker when they are created or their spec changes, and MemcachedPolicies
+// with it.
+func SetPolicyChecker(checker PolicyChecker) {
+	policies = checker
+}
+
 // +kubebuilder:webhook:path=/mutate-cache-example-com-v1alpha1-memcached,mutating=true,failurePolicy=fail,groups=cache.example.com,resources=memcacheds,verbs=create;update,versions=v1alpha1,name=mmemcached.kb.io
 
 var _ webhook.Defaulter = &Memcached{}
@@ -56,6 +83,24 @@ func (r *Memcached) Default() {
 	if r.Spec.Suspend == nil {
 		r.Spec.Suspend = new(bool)
 	}
+	if r.Spec.Version == "" && r.Spec.Image == "" {
+		r.Spec.Version = DefaultVersion
+	}
+	config := r.Spec.ConfigWithDefaults()
+	r.Spec.Config = &config
+	resources := r.Spec.ResourcesWithDefaults()
+	r.Spec.Resources = &resources
+	r.Spec.Autoscaling = r.Spec.AutoscalingWithDefaults()
+	if r.Spec.Sizing != nil {
+		sizing := r.Spec.SizingWithDefaults()
+		r.Spec.Sizing = &sizing
+	}
+	if r.Spec.DeletionPolicy == "" {
+		r.Spec.DeletionPolicy = DeletionPolicyDelete
+	}
+	if r.Spec.WorkloadKind == "" {
+		r.Spec.WorkloadKind = WorkloadDeployment
+	}
 }
 
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
@@ -67,14 +112,34 @@ var _ webhook.Validator = &Memcached{}
 func (r *Memcached) ValidateCreate() error {
 	memcachedlog.Info("validate create", "name", r.Name)
 
-	return validateOdd(r.Spec.Size)
+	allErrs := r.validateMemcached()
+	policyErrs, err := r.checkPolicies()
+	if err != nil {
+		return err
+	}
+	return r.toInvalid(append(allErrs, policyErrs...))
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *Memcached) ValidateUpdate(old runtime.Object) error {
 	memcachedlog.Info("validate update", "name", r.Name)
 
-	return validateOdd(r.Spec.Size)
+	oldMemcached, ok := old.(*Memcached)
+	if !ok {
+		return fmt.Errorf("expected a Memcached but got a %T", old)
+	}
+	// Objects stored before a rule or policy was added must still be able
+	// to take finalizers and labels, so only spec changes are checked
+	allErrs := r.validateMemcachedUpdate(oldMemcached)
+	if !equality.Semantic.DeepEqual(r.Spec, oldMemcached.Spec) {
+		allErrs = append(allErrs, r.validateMemcached()...)
+		policyErrs, err := r.checkPolicies()
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, policyErrs...)
+	}
+	return r.toInvalid(allErrs)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -85,9 +150,112 @@ func (r *Memcached) ValidateDelete() error {
 	return nil
 }
 
-func validateOdd(n int32) error {
-	if n%2 == 0 {
-		return errors.New("Cluster size must be an odd number")
+// toInvalid turns allErrs into an Invalid status error, or nil if empty.
+func (r *Memcached) toInvalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "Memcached"}, r.Name, allErrs)
+}
+
+// checkPolicies checks r against the admission policies, if any.
+func (r *Memcached) checkPolicies() (field.ErrorList, error) {
+	if policies == nil {
+		return nil, nil
+	}
+	allErrs, err := policies.Check(context.TODO(), r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the admission policies: %v", err)
+	}
+	return allErrs, nil
+}
+
+func (r *Memcached) validateMemcached() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	if r.Spec.Size < 1 || r.Spec.Size > MaxSize {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("size"), r.Spec.Size, fmt.Sprintf("must be between 1 and %d", MaxSize)))
+	}
+	allErrs = append(allErrs, r.Spec.validatePrice(specPath.Child("price"))...)
+	// The version comes from the image tag when it is not set
+	versionPath, versionValue := specPath.Child("version"), r.Spec.Version
+	if r.Spec.Version == "" && r.Spec.Image != "" {
+		versionPath, versionValue = specPath.Child("image"), r.Spec.Image
+	}
+	version, versionErr := r.Spec.ParsedVersion()
+	if versionErr != nil {
+		allErrs = append(allErrs, field.Invalid(versionPath, versionValue, versionErr.Error()))
+	}
+	config := r.Spec.ConfigWithDefaults()
+	allErrs = append(allErrs, config.Validate(specPath.Child("config"))...)
+	resources := r.Spec.ResourcesWithDefaults()
+	allErrs = append(allErrs, resources.Validate(specPath.Child("resources"))...)
+	allErrs = append(allErrs, r.Spec.validateMaxUnavailable(specPath.Child("disruption", "maxUnavailable"))...)
+	service := r.Spec.ServiceWithDefaults()
+	allErrs = append(allErrs, service.Validate(specPath.Child("service"), r.Spec.WorkloadKind)...)
+	if autoscaling := r.Spec.AutoscalingWithDefaults(); autoscaling != nil {
+		allErrs = append(allErrs, autoscaling.Validate(specPath.Child("autoscaling"), resources)...)
+	}
+	if r.Spec.Auth != nil {
+		allErrs = append(allErrs, r.Spec.Auth.Validate(specPath.Child("auth"), config)...)
+	}
+	sizing := r.Spec.SizingWithDefaults()
+	allErrs = append(allErrs, sizing.Validate(specPath.Child("sizing"))...)
+	// The policy scales on statistics, which are only collected over a
+	// plain text protocol connection
+	if r.Spec.Autoscaling != nil && r.Spec.Autoscaling.Policy != nil &&
+		(r.Spec.TLS != nil || r.Spec.Auth != nil || config.Protocol == ProtocolBinary) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("autoscaling", "policy"), "cannot be used with tls, auth or config.protocol binary"))
+	}
+	if r.Spec.Monitoring != nil && r.Spec.Monitoring.DisableStats &&
+		((r.Spec.Autoscaling != nil && r.Spec.Autoscaling.Policy != nil) || r.Spec.Sizing != nil) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("monitoring", "disableStats"), "cannot be used with autoscaling.policy or sizing"))
+	}
+	if versionErr == nil {
+		features := config.RequiredFeatures()
+		if r.Spec.TLS != nil {
+			features = append(features, FeatureTLS)
+		}
+		if err := version.RequireFeatures(features...); err != nil {
+			allErrs = append(allErrs, field.Invalid(versionPath, versionValue, err.Error()))
+		}
+	}
+	return allErrs
+}
+
+// validateMemcachedUpdate checks the changes from old against the fields
+// that may not change: the port, the protocol and whether TLS is on, which
+// connected clients and the stats poller depend on, and the whole spec
+// while the Memcached is being deleted.
+func (r *Memcached) validateMemcachedUpdate(old *Memcached) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	// Teardown follows the spec it started with
+	if old.DeletionTimestamp != nil && !equality.Semantic.DeepEqual(r.Spec, old.Spec) {
+		allErrs = append(allErrs, field.Forbidden(specPath, "may not be changed while the Memcached is being deleted"))
+	}
+	// Clients address every pod by host and port, a new port would leave
+	// them all pointing at nothing
+	newConfig, oldConfig := r.Spec.ConfigWithDefaults(), old.Spec.ConfigWithDefaults()
+	allErrs = append(allErrs, apivalidation.ValidateImmutableField(newConfig.Port, oldConfig.Port, specPath.Child("config", "port"))...)
+	// Clients speak one protocol, with or without TLS, and would fail
+	// against the new pods
+	allErrs = append(allErrs, apivalidation.ValidateImmutableField(newConfig.Protocol, oldConfig.Protocol, specPath.Child("config", "protocol"))...)
+	if (r.Spec.TLS == nil) != (old.Spec.TLS == nil) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("tls"), "may not be added or removed after creation"))
+	}
+	return allErrs
+}
+
+// validatePrice checks that spec.price, at fldPath, is empty or a
+// non-negative amount in an ISO 4217 currency.
+func (s *MemcachedSpec) validatePrice(fldPath *field.Path) field.ErrorList {
+	_, currency, err := s.ParsedPrice()
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, s.Price, err.Error())}
+	}
+	if currency != "" && !IsCurrency(currency) {
+		return field.ErrorList{field.Invalid(fldPath, s.Price, fmt.Sprintf("currency %q is not an ISO 4217 code", currency))}
 	}
 	return nil
 }
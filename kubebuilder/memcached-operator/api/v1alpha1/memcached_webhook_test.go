@@ -0,0 +1,353 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// causeFields returns the fields named by the causes of an Invalid error.
+func causeFields(t *testing.T, err error) []string {
+	statusErr, ok := err.(*apierrors.StatusError)
+	if !ok || !apierrors.IsInvalid(err) {
+		t.Fatalf("expected an Invalid error, got %v", err)
+	}
+	var fields []string
+	for _, cause := range statusErr.ErrStatus.Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}
+
+func TestValidateTLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		wantErr bool
+	}{
+		{name: "too old", image: "memcached:1.5.12-alpine", wantErr: true},
+		{name: "first release with TLS", image: "memcached:1.5.13-alpine"},
+		{name: "current", image: "memcached:1.6.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Memcached{Spec: MemcachedSpec{
+				Size:  3,
+				Image: tt.image,
+				TLS:   &MemcachedTLS{SecretName: "memcached-tls"},
+			}}
+			m.Default()
+			err := m.ValidateCreate()
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected TLS on %s to be rejected", tt.image)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol Protocol
+		wantErr  bool
+	}{
+		{name: "auto"},
+		{name: "binary", protocol: ProtocolBinary},
+		{name: "ascii", protocol: ProtocolASCII, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Memcached{Spec: MemcachedSpec{
+				Size:   3,
+				Config: &MemcachedConfig{Protocol: tt.protocol},
+				Auth:   &MemcachedAuth{SecretName: "memcached-users"},
+			}}
+			m.Default()
+			err := m.ValidateCreate()
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected auth with protocol %q to be rejected", tt.protocol)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateAutoscalingPolicy(t *testing.T) {
+	target := int32(80)
+	tests := []struct {
+		name        string
+		autoscaling *MemcachedAutoscaling
+		tls         *MemcachedTLS
+		monitoring  *MemcachedMonitoring
+		wantErr     bool
+	}{
+		{name: "memory target", autoscaling: &MemcachedAutoscaling{
+			MaxReplicas: 9,
+			Policy:      &MemcachedAutoscalingPolicy{TargetMemoryUtilizationPercent: &target},
+		}},
+		{name: "no target", wantErr: true, autoscaling: &MemcachedAutoscaling{
+			MaxReplicas: 9,
+			Policy:      &MemcachedAutoscalingPolicy{},
+		}},
+		{name: "with metrics", wantErr: true, autoscaling: &MemcachedAutoscaling{
+			MaxReplicas: 9,
+			Metrics:     (&MemcachedSpec{Autoscaling: &MemcachedAutoscaling{}}).AutoscalingWithDefaults().Metrics,
+			Policy:      &MemcachedAutoscalingPolicy{TargetMemoryUtilizationPercent: &target},
+		}},
+		{name: "with TLS", wantErr: true, tls: &MemcachedTLS{SecretName: "memcached-tls"}, autoscaling: &MemcachedAutoscaling{
+			MaxReplicas: 9,
+			Policy:      &MemcachedAutoscalingPolicy{TargetMemoryUtilizationPercent: &target},
+		}},
+		{name: "without stats", wantErr: true, monitoring: &MemcachedMonitoring{DisableStats: true}, autoscaling: &MemcachedAutoscaling{
+			MaxReplicas: 9,
+			Policy:      &MemcachedAutoscalingPolicy{TargetMemoryUtilizationPercent: &target},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Memcached{Spec: MemcachedSpec{
+				Size:        3,
+				Autoscaling: tt.autoscaling,
+				TLS:         tt.tls,
+				Monitoring:  tt.monitoring,
+			}}
+			m.Default()
+			err := m.ValidateCreate()
+			if tt.wantErr && err == nil {
+				t.Fatal("expected the autoscaling policy to be rejected")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateSizing(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  MemcachedSizingWindow
+		wantErr bool
+	}{
+		{name: "night", window: MemcachedSizingWindow{Start: "02:30", Duration: metav1.Duration{Duration: 2 * time.Hour}}},
+		{name: "bad start", window: MemcachedSizingWindow{Start: "2:30pm", Duration: metav1.Duration{Duration: time.Hour}}, wantErr: true},
+		{name: "no duration", window: MemcachedSizingWindow{Start: "02:30"}, wantErr: true},
+		{name: "too long", window: MemcachedSizingWindow{Start: "02:30", Duration: metav1.Duration{Duration: 25 * time.Hour}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Memcached{Spec: MemcachedSpec{
+				Size: 3,
+				Sizing: &MemcachedSizing{
+					Mode:    SizingAuto,
+					Windows: []MemcachedSizingWindow{tt.window},
+				},
+			}}
+			m.Default()
+			err := m.ValidateCreate()
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected window %+v to be rejected", tt.window)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateFieldPaths(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      MemcachedSpec
+		wantField string
+	}{
+		{name: "negative size", spec: MemcachedSpec{Size: -1}, wantField: "spec.size"},
+		{name: "too many pods", spec: MemcachedSpec{Size: MaxSize + 1}, wantField: "spec.size"},
+		{name: "bad price", spec: MemcachedSpec{Size: 3, Price: "ten USD"}, wantField: "spec.price"},
+		{name: "unknown currency", spec: MemcachedSpec{Size: 3, Price: "10 XYZ"}, wantField: "spec.price"},
+		{name: "too much memory", spec: MemcachedSpec{Size: 3, Config: &MemcachedConfig{MemoryMB: 100000}}, wantField: "spec.config.memoryMB"},
+		{name: "bad port", spec: MemcachedSpec{Size: 3, Config: &MemcachedConfig{Port: 70000}}, wantField: "spec.config.port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Memcached{ObjectMeta: metav1.ObjectMeta{Name: "example"}, Spec: tt.spec}
+			m.Default()
+			fields := causeFields(t, m.ValidateCreate())
+			if len(fields) != 1 || fields[0] != tt.wantField {
+				t.Fatalf("expected an error on %s, got %v", tt.wantField, fields)
+			}
+		})
+	}
+
+	m := &Memcached{Spec: MemcachedSpec{Size: 3, Price: "0.25 EUR"}}
+	m.Default()
+	if err := m.ValidateCreate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		name      string
+		old       *Memcached
+		new       MemcachedSpec
+		wantField string
+	}{
+		{name: "scale", old: &Memcached{Spec: MemcachedSpec{Size: 3}}, new: MemcachedSpec{Size: 5}},
+		{
+			name:      "change port",
+			old:       &Memcached{Spec: MemcachedSpec{Size: 3}},
+			new:       MemcachedSpec{Size: 3, Config: &MemcachedConfig{Port: 11212}},
+			wantField: "spec.config.port",
+		},
+		{
+			name:      "change protocol",
+			old:       &Memcached{Spec: MemcachedSpec{Size: 3, Config: &MemcachedConfig{Protocol: ProtocolASCII}}},
+			new:       MemcachedSpec{Size: 3, Config: &MemcachedConfig{Protocol: ProtocolBinary}},
+			wantField: "spec.config.protocol",
+		},
+		{
+			name:      "add TLS",
+			old:       &Memcached{Spec: MemcachedSpec{Size: 3, Version: "1.6.9"}},
+			new:       MemcachedSpec{Size: 3, Version: "1.6.9", TLS: &MemcachedTLS{SecretName: "memcached-tls"}},
+			wantField: "spec.tls",
+		},
+		{
+			name: "rotate TLS secret",
+			old:  &Memcached{Spec: MemcachedSpec{Size: 3, Version: "1.6.9", TLS: &MemcachedTLS{SecretName: "memcached-tls"}}},
+			new:  MemcachedSpec{Size: 3, Version: "1.6.9", TLS: &MemcachedTLS{SecretName: "memcached-tls-2"}},
+		},
+		{
+			name:      "scale while deleting",
+			old:       &Memcached{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now}, Spec: MemcachedSpec{Size: 3}},
+			new:       MemcachedSpec{Size: 5},
+			wantField: "spec",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.old.Default()
+			m := &Memcached{ObjectMeta: tt.old.ObjectMeta, Spec: tt.new}
+			m.Default()
+			err := m.ValidateUpdate(tt.old)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			fields := causeFields(t, err)
+			if len(fields) != 1 || fields[0] != tt.wantField {
+				t.Fatalf("expected an error on %s, got %v", tt.wantField, fields)
+			}
+		})
+	}
+}
+
+func TestValidateUpdateLegacy(t *testing.T) {
+	// Stored before prices were validated
+	old := &Memcached{ObjectMeta: metav1.ObjectMeta{Name: "cache"}, Spec: MemcachedSpec{Size: 3, Price: "ten USD"}}
+	old.Default()
+
+	m := old.DeepCopy()
+	m.Finalizers = append(m.Finalizers, "cache.example.com/finalizer")
+	if err := m.ValidateUpdate(old); err != nil {
+		t.Fatalf("adding a finalizer failed: %v", err)
+	}
+
+	m.Spec.Size = 5
+	if fields := causeFields(t, m.ValidateUpdate(old)); len(fields) != 1 || fields[0] != "spec.price" {
+		t.Fatalf("expected an error on spec.price, got %v", fields)
+	}
+}
+
+// rejectAll is a PolicyChecker that rejects every Memcached.
+type rejectAll struct {
+	checked int
+}
+
+func (c *rejectAll) Check(ctx context.Context, m *Memcached) (field.ErrorList, error) {
+	c.checked++
+	return field.ErrorList{field.Forbidden(field.NewPath("spec"), "MemcachedPolicy all rejected the request: no")}, nil
+}
+
+func (c *rejectAll) ValidatePolicy(spec *MemcachedPolicySpec, fldPath *field.Path) field.ErrorList {
+	return field.ErrorList{field.Invalid(fldPath.Child("rules").Index(0).Child("expression"), spec.Rules[0].Expression, "no")}
+}
+
+func TestValidatePolicies(t *testing.T) {
+	m := &Memcached{ObjectMeta: metav1.ObjectMeta{Name: "example"}, Spec: MemcachedSpec{Size: 4}}
+	m.Default()
+	if err := m.ValidateCreate(); err != nil {
+		t.Fatalf("expected an even size to be admitted without policies, got %v", err)
+	}
+
+	checker := &rejectAll{}
+	SetPolicyChecker(checker)
+	defer SetPolicyChecker(nil)
+	if fields := causeFields(t, m.ValidateCreate()); len(fields) != 1 || fields[0] != "spec" {
+		t.Fatalf("expected the policy to reject spec, got %v", fields)
+	}
+
+	// Metadata changes are not checked
+	labeled := m.DeepCopy()
+	labeled.Labels = map[string]string{"team": "cache"}
+	if err := labeled.ValidateUpdate(m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resized := m.DeepCopy()
+	resized.Spec.Size = 5
+	if err := resized.ValidateUpdate(m); err == nil {
+		t.Fatal("expected the policy to reject the new size")
+	}
+	if checker.checked != 2 {
+		t.Errorf("expected 2 checks, got %d", checker.checked)
+	}
+}
+
+func TestValidateMemcachedPolicy(t *testing.T) {
+	p := &MemcachedPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken"},
+		Spec:       MemcachedPolicySpec{Rules: []MemcachedPolicyRule{{Expression: "spec.size >"}}},
+	}
+	if err := p.ValidateCreate(); err != nil {
+		t.Fatalf("expected policies to be admitted without a checker, got %v", err)
+	}
+
+	SetPolicyChecker(&rejectAll{})
+	defer SetPolicyChecker(nil)
+	if fields := causeFields(t, p.ValidateCreate()); len(fields) != 1 || fields[0] != "spec.rules[0].expression" {
+		t.Fatalf("expected an error on spec.rules[0].expression, got %v", fields)
+	}
+	if fields := causeFields(t, p.ValidateUpdate(p.DeepCopy())); len(fields) != 1 || fields[0] != "spec.rules[0].expression" {
+		t.Fatalf("expected an error on spec.rules[0].expression, got %v", fields)
+	}
+	if err := p.ValidateDelete(); err != nil {
+		t.Errorf("expected deletes to be admitted, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// +kubebuilder:docs-gen:collapse=Apache License
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultReportCurrency is used when a report sets no currency.
+	DefaultReportCurrency = "USD"
+	// DefaultTeamLabel is used when a report sets no teamLabel.
+	DefaultTeamLabel = "team"
+)
+
+// MemcachedCostReportSpec defines what a MemcachedCostReport adds up.
+type MemcachedCostReportSpec struct {
+	// Currency the report is in. Defaults to USD.
+	// +kubebuilder:validation:Pattern=`^[A-Z]{3}$`
+	// +optional
+	Currency string `json:"currency,omitempty"`
+
+	// Rates names a ConfigMap that converts other currencies to the report
+	// currency. Every key is a currency code and its value what one unit of
+	// that currency is worth in the report currency, for example
+	// EUR: "1.08". Instances priced in a currency without a rate are left
+	// out of the report.
+	// +optional
+	Rates *ConfigMapReference `json:"rates,omitempty"`
+
+	// TeamLabel is the label of a Memcached that names its team. Defaults
+	// to "team".
+	// +optional
+	TeamLabel string `json:"teamLabel,omitempty"`
+}
+
+// ConfigMapReference names a ConfigMap in any namespace.
+type ConfigMapReference struct {
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// MemcachedCostReportStatus is the cost of the Memcacheds in the cluster.
+type MemcachedCostReportStatus struct {
+	// Currency the amounts are in.
+	// +optional
+	Currency string `json:"currency,omitempty"`
+
+	// MonthlyTotal is the monthly cost of all instances in the report, as a
+	// decimal number with two decimal places.
+	// +optional
+	MonthlyTotal string `json:"monthlyTotal,omitempty"`
+
+	// Instances is the number of instances in the report.
+	// +optional
+	Instances int32 `json:"instances,omitempty"`
+
+	// Namespaces is the cost per namespace, by name.
+	// +optional
+	Namespaces []MemcachedCostEntry `json:"namespaces,omitempty"`
+
+	// Teams is the cost per team, by name. Instances without the team
+	// label are only counted in their namespace.
+	// +optional
+	Teams []MemcachedCostEntry `json:"teams,omitempty"`
+
+	// UnconvertedCurrencies are the currencies instances are priced in that
+	// have no rate.
+	// +optional
+	UnconvertedCurrencies []string `json:"unconvertedCurrencies,omitempty"`
+
+	// LastUpdateTime is when the amounts last changed.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// LastError is the error that stopped the last update, if any.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// MemcachedCostEntry is the cost of a group of instances.
+type MemcachedCostEntry struct {
+	// Name of the namespace or team.
+	Name string `json:"name"`
+
+	// MonthlyAmount is the monthly cost of the group, as a decimal number
+	// with two decimal places.
+	MonthlyAmount string `json:"monthlyAmount"`
+
+	// Instances is the number of instances in the group.
+	Instances int32 `json:"instances"`
+}
+
+// +kubebuilder:object:root=true
+
+// MemcachedCostReport adds up the cost of the Memcacheds in the cluster per
+// namespace and team.
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Currency",type=string,JSONPath=`.status.currency`
+// +kubebuilder:printcolumn:name="Monthly",type=string,JSONPath=`.status.monthlyTotal`
+// +kubebuilder:printcolumn:name="Instances",type=integer,JSONPath=`.status.instances`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type MemcachedCostReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MemcachedCostReportSpec   `json:"spec,omitempty"`
+	Status MemcachedCostReportStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MemcachedCostReportList contains a list of MemcachedCostReport
+type MemcachedCostReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MemcachedCostReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MemcachedCostReport{}, &MemcachedCostReportList{})
+}
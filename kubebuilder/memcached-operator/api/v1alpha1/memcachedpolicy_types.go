@@ -0,0 +1,91 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// +kubebuilder:docs-gen:collapse=Apache License
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MemcachedPolicySpec defines the rules Memcacheds must follow.
+type MemcachedPolicySpec struct {
+	// NamespaceSelector selects the namespaces whose Memcacheds the policy
+	// applies to. The policy applies to every namespace when it is not set.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Rules every Memcached in the selected namespaces must follow.
+	// +kubebuilder:validation:MinItems=1
+	Rules []MemcachedPolicyRule `json:"rules"`
+}
+
+// MemcachedPolicyRule is an expression a Memcached must satisfy.
+type MemcachedPolicyRule struct {
+	// Expression must evaluate to true for the Memcached to be admitted. It
+	// is written in Go expression syntax over the v1alpha1 fields of the
+	// defaulted Memcached, reached through spec and metadata, for example
+	// `spec.size % 2 == 1` or `spec.tls != nil || metadata.labels.public != "true"`.
+	// Missing fields are nil. The operators are those of Go on numbers,
+	// strings and booleans, and len() returns the length of a string, list
+	// or map.
+	// +kubebuilder:validation:MinLength=1
+	Expression string `json:"expression"`
+
+	// Message tells the user why the request was rejected. Defaults to the
+	// expression.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// MemcachedPolicyStatus reports whether a MemcachedPolicy can be checked.
+type MemcachedPolicyStatus struct {
+	// ObservedGeneration is the generation of the spec the status is about.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Errors lists why the policy can't be checked, for example rule
+	// expressions that don't compile. Memcacheds are admitted without the
+	// policy until they are fixed.
+	// +optional
+	Errors []string `json:"errors,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MemcachedPolicy is a set of rules the validating webhook checks Memcacheds
+// against when they are created or their spec changes.
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+type MemcachedPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MemcachedPolicySpec   `json:"spec,omitempty"`
+	Status MemcachedPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MemcachedPolicyList contains a list of MemcachedPolicy
+type MemcachedPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MemcachedPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MemcachedPolicy{}, &MemcachedPolicyList{})
+}
@@ -0,0 +1,74 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// +kubebuilder:docs-gen:collapse=Apache License
+
+package v1alpha1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var memcachedpolicylog = logf.Log.WithName("memcachedpolicy-resource")
+
+func (r *MemcachedPolicy) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// A policy that can't be checked would otherwise only show on its status,
+// so the webhook rejects it up front.
+// +kubebuilder:webhook:verbs=create;update,path=/validate-cache-example-com-v1alpha1-memcachedpolicy,mutating=false,failurePolicy=fail,groups=cache.example.com,resources=memcachedpolicies,versions=v1alpha1,name=vmemcachedpolicy.kb.io
+
+var _ webhook.Validator = &MemcachedPolicy{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *MemcachedPolicy) ValidateCreate() error {
+	memcachedpolicylog.Info("validate create", "name", r.Name)
+
+	return r.validateMemcachedPolicy()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *MemcachedPolicy) ValidateUpdate(old runtime.Object) error {
+	memcachedpolicylog.Info("validate update", "name", r.Name)
+
+	return r.validateMemcachedPolicy()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *MemcachedPolicy) ValidateDelete() error {
+	memcachedpolicylog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+func (r *MemcachedPolicy) validateMemcachedPolicy() error {
+	if policies == nil {
+		return nil
+	}
+	allErrs := policies.ValidatePolicy(&r.Spec, field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "MemcachedPolicy"}, r.Name, allErrs)
+}
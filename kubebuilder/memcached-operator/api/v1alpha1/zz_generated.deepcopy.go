@@ -21,9 +21,28 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/api/autoscaling/v2beta2"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapReference) DeepCopyInto(out *ConfigMapReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapReference.
+func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Memcached) DeepCopyInto(out *Memcached) {
 	*out = *in
@@ -51,6 +70,318 @@ func (in *Memcached) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedAuth) DeepCopyInto(out *MemcachedAuth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedAuth.
+func (in *MemcachedAuth) DeepCopy() *MemcachedAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedAutoscaling) DeepCopyInto(out *MemcachedAutoscaling) {
+	*out = *in
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]v2beta2.MetricSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(MemcachedAutoscalingPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedAutoscaling.
+func (in *MemcachedAutoscaling) DeepCopy() *MemcachedAutoscaling {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedAutoscaling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedAutoscalingPolicy) DeepCopyInto(out *MemcachedAutoscalingPolicy) {
+	*out = *in
+	if in.MaxEvictionsPerMinute != nil {
+		in, out := &in.MaxEvictionsPerMinute, &out.MaxEvictionsPerMinute
+		*out = new(int64)
+		**out = **in
+	}
+	if in.TargetMemoryUtilizationPercent != nil {
+		in, out := &in.TargetMemoryUtilizationPercent, &out.TargetMemoryUtilizationPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinHitRatioPercent != nil {
+		in, out := &in.MinHitRatioPercent, &out.MinHitRatioPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ScaleUpCooldownSeconds != nil {
+		in, out := &in.ScaleUpCooldownSeconds, &out.ScaleUpCooldownSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ScaleDownCooldownSeconds != nil {
+		in, out := &in.ScaleDownCooldownSeconds, &out.ScaleDownCooldownSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedAutoscalingPolicy.
+func (in *MemcachedAutoscalingPolicy) DeepCopy() *MemcachedAutoscalingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedAutoscalingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedAutoscalingStatus) DeepCopyInto(out *MemcachedAutoscalingStatus) {
+	*out = *in
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Decisions != nil {
+		in, out := &in.Decisions, &out.Decisions
+		*out = make([]MemcachedScalingDecision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedAutoscalingStatus.
+func (in *MemcachedAutoscalingStatus) DeepCopy() *MemcachedAutoscalingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedAutoscalingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedCondition) DeepCopyInto(out *MemcachedCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedCondition.
+func (in *MemcachedCondition) DeepCopy() *MemcachedCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedConfig) DeepCopyInto(out *MemcachedConfig) {
+	*out = *in
+	if in.MaxItemSize != nil {
+		in, out := &in.MaxItemSize, &out.MaxItemSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedConfig.
+func (in *MemcachedConfig) DeepCopy() *MemcachedConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedCost) DeepCopyInto(out *MemcachedCost) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedCost.
+func (in *MemcachedCost) DeepCopy() *MemcachedCost {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedCost)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedCostEntry) DeepCopyInto(out *MemcachedCostEntry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedCostEntry.
+func (in *MemcachedCostEntry) DeepCopy() *MemcachedCostEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedCostEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedCostReport) DeepCopyInto(out *MemcachedCostReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedCostReport.
+func (in *MemcachedCostReport) DeepCopy() *MemcachedCostReport {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedCostReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MemcachedCostReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedCostReportList) DeepCopyInto(out *MemcachedCostReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MemcachedCostReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedCostReportList.
+func (in *MemcachedCostReportList) DeepCopy() *MemcachedCostReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedCostReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MemcachedCostReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedCostReportSpec) DeepCopyInto(out *MemcachedCostReportSpec) {
+	*out = *in
+	if in.Rates != nil {
+		in, out := &in.Rates, &out.Rates
+		*out = new(ConfigMapReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedCostReportSpec.
+func (in *MemcachedCostReportSpec) DeepCopy() *MemcachedCostReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedCostReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedCostReportStatus) DeepCopyInto(out *MemcachedCostReportStatus) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]MemcachedCostEntry, len(*in))
+		copy(*out, *in)
+	}
+	if in.Teams != nil {
+		in, out := &in.Teams, &out.Teams
+		*out = make([]MemcachedCostEntry, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnconvertedCurrencies != nil {
+		in, out := &in.UnconvertedCurrencies, &out.UnconvertedCurrencies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedCostReportStatus.
+func (in *MemcachedCostReportStatus) DeepCopy() *MemcachedCostReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedCostReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedDisruption) DeepCopyInto(out *MemcachedDisruption) {
+	*out = *in
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedDisruption.
+func (in *MemcachedDisruption) DeepCopy() *MemcachedDisruption {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedDisruption)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MemcachedList) DeepCopyInto(out *MemcachedList) {
 	*out = *in
@@ -83,6 +414,305 @@ func (in *MemcachedList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedMonitoring) DeepCopyInto(out *MemcachedMonitoring) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedMonitoring.
+func (in *MemcachedMonitoring) DeepCopy() *MemcachedMonitoring {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedMonitoring)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedPolicy) DeepCopyInto(out *MemcachedPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedPolicy.
+func (in *MemcachedPolicy) DeepCopy() *MemcachedPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MemcachedPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedPolicyList) DeepCopyInto(out *MemcachedPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MemcachedPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedPolicyList.
+func (in *MemcachedPolicyList) DeepCopy() *MemcachedPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MemcachedPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedPolicyRule) DeepCopyInto(out *MemcachedPolicyRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedPolicyRule.
+func (in *MemcachedPolicyRule) DeepCopy() *MemcachedPolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedPolicyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedPolicySpec) DeepCopyInto(out *MemcachedPolicySpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]MemcachedPolicyRule, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedPolicySpec.
+func (in *MemcachedPolicySpec) DeepCopy() *MemcachedPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedPolicyStatus) DeepCopyInto(out *MemcachedPolicyStatus) {
+	*out = *in
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedPolicyStatus.
+func (in *MemcachedPolicyStatus) DeepCopy() *MemcachedPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedRecommendation) DeepCopyInto(out *MemcachedRecommendation) {
+	*out = *in
+	in.ComputedTime.DeepCopyInto(&out.ComputedTime)
+	if in.AppliedTime != nil {
+		in, out := &in.AppliedTime, &out.AppliedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedRecommendation.
+func (in *MemcachedRecommendation) DeepCopy() *MemcachedRecommendation {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedRecommendation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedResources) DeepCopyInto(out *MemcachedResources) {
+	*out = *in
+	if in.MemoryOverheadPercent != nil {
+		in, out := &in.MemoryOverheadPercent, &out.MemoryOverheadPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxMemoryOverheadPercent != nil {
+		in, out := &in.MaxMemoryOverheadPercent, &out.MaxMemoryOverheadPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedResources.
+func (in *MemcachedResources) DeepCopy() *MemcachedResources {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedResources)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedScalingDecision) DeepCopyInto(out *MemcachedScalingDecision) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedScalingDecision.
+func (in *MemcachedScalingDecision) DeepCopy() *MemcachedScalingDecision {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedScalingDecision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedScheduling) DeepCopyInto(out *MemcachedScheduling) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedScheduling.
+func (in *MemcachedScheduling) DeepCopy() *MemcachedScheduling {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedScheduling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedService) DeepCopyInto(out *MemcachedService) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedService.
+func (in *MemcachedService) DeepCopy() *MemcachedService {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedSizing) DeepCopyInto(out *MemcachedSizing) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]MemcachedSizingWindow, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedSizing.
+func (in *MemcachedSizing) DeepCopy() *MemcachedSizing {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedSizing)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedSizingWindow) DeepCopyInto(out *MemcachedSizingWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedSizingWindow.
+func (in *MemcachedSizingWindow) DeepCopy() *MemcachedSizingWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedSizingWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MemcachedSpec) DeepCopyInto(out *MemcachedSpec) {
 	*out = *in
@@ -91,6 +721,56 @@ func (in *MemcachedSpec) DeepCopyInto(out *MemcachedSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(MemcachedConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(MemcachedResources)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(MemcachedAutoscaling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sizing != nil {
+		in, out := &in.Sizing, &out.Sizing
+		*out = new(MemcachedSizing)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(MemcachedService)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Scheduling != nil {
+		in, out := &in.Scheduling, &out.Scheduling
+		*out = new(MemcachedScheduling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Disruption != nil {
+		in, out := &in.Disruption, &out.Disruption
+		*out = new(MemcachedDisruption)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(MemcachedTLS)
+		**out = **in
+	}
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(MemcachedAuth)
+		**out = **in
+	}
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		*out = new(MemcachedMonitoring)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedSpec.
@@ -103,6 +783,37 @@ func (in *MemcachedSpec) DeepCopy() *MemcachedSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedStats) DeepCopyInto(out *MemcachedStats) {
+	*out = *in
+	in.CollectedTime.DeepCopyInto(&out.CollectedTime)
+	if in.HitRatioPercent != nil {
+		in, out := &in.HitRatioPercent, &out.HitRatioPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.EvictionsPerMinute != nil {
+		in, out := &in.EvictionsPerMinute, &out.EvictionsPerMinute
+		*out = new(int64)
+		**out = **in
+	}
+	if in.OldestItemAgeSeconds != nil {
+		in, out := &in.OldestItemAgeSeconds, &out.OldestItemAgeSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedStats.
+func (in *MemcachedStats) DeepCopy() *MemcachedStats {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MemcachedStatus) DeepCopyInto(out *MemcachedStatus) {
 	*out = *in
@@ -111,6 +822,42 @@ func (in *MemcachedStatus) DeepCopyInto(out *MemcachedStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.LastOOMKillTime != nil {
+		in, out := &in.LastOOMKillTime, &out.LastOOMKillTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]MemcachedCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SuspendedReplicas != nil {
+		in, out := &in.SuspendedReplicas, &out.SuspendedReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Stats != nil {
+		in, out := &in.Stats, &out.Stats
+		*out = new(MemcachedStats)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(MemcachedAutoscalingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Recommendation != nil {
+		in, out := &in.Recommendation, &out.Recommendation
+		*out = new(MemcachedRecommendation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cost != nil {
+		in, out := &in.Cost, &out.Cost
+		*out = new(MemcachedCost)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedStatus.
@@ -122,3 +869,18 @@ func (in *MemcachedStatus) DeepCopy() *MemcachedStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedTLS) DeepCopyInto(out *MemcachedTLS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedTLS.
+func (in *MemcachedTLS) DeepCopy() *MemcachedTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedTLS)
+	in.DeepCopyInto(out)
+	return out
+}
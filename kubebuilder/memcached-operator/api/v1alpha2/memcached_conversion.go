@@ -24,8 +24,6 @@ standard packages.
 */
 import (
 	"fmt"
-	"strconv"
-	"strings"
 
 	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/conversion"
@@ -50,16 +48,46 @@ func (src *Memcached) ConvertTo(dstRaw conversion.Hub) error {
 	case *cachev1alpha1.Memcached:
 		dst := dstRaw.(*cachev1alpha1.Memcached)
 
-		// conversion implementation goes here
-		// in our case, we convert the price in structured form to string form.
-		// Spec
-		dst.Spec.Price = fmt.Sprintf("%d %s", src.Spec.Price.Amount, src.Spec.Price.Currency)
 		// ObjectMeta
 		dst.ObjectMeta = src.ObjectMeta
+		// conversion implementation goes here
+		// in our case, we convert the price in structured form to string form.
+		// A price without a string form is kept in an annotation.
+		convertPriceTo(src, dst)
 		// rest of conversion
 		dst.Spec.Size = src.Spec.Size
 		dst.Status.Nodes = src.Status.Nodes
 		dst.Spec.Suspend = src.Spec.Suspend
+		dst.Spec.Version = src.Spec.Version
+		dst.Spec.Image = src.Spec.Image
+		dst.Spec.Config = src.Spec.Config
+		dst.Spec.Resources = src.Spec.Resources
+		dst.Spec.Autoscaling = src.Spec.Autoscaling
+		dst.Spec.Sizing = src.Spec.Sizing
+		dst.Spec.DeletionPolicy = src.Spec.DeletionPolicy
+		dst.Spec.WorkloadKind = src.Spec.WorkloadKind
+		dst.Spec.Service = src.Spec.Service
+		dst.Spec.Scheduling = src.Spec.Scheduling
+		dst.Spec.Disruption = src.Spec.Disruption
+		dst.Spec.TLS = src.Spec.TLS
+		dst.Spec.Auth = src.Spec.Auth
+		dst.Spec.Monitoring = src.Spec.Monitoring
+		dst.Status.Version = src.Status.Version
+		dst.Status.MemoryOverheadPercent = src.Status.MemoryOverheadPercent
+		dst.Status.OOMOverheadPercent = src.Status.OOMOverheadPercent
+		dst.Status.OOMKills = src.Status.OOMKills
+		dst.Status.LastOOMKillTime = src.Status.LastOOMKillTime
+		dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+		dst.Status.ReadyReplicas = src.Status.ReadyReplicas
+		dst.Status.LastError = src.Status.LastError
+		dst.Status.Conditions = src.Status.Conditions
+		dst.Status.Replicas = src.Status.Replicas
+		dst.Status.Selector = src.Status.Selector
+		dst.Status.SuspendedReplicas = src.Status.SuspendedReplicas
+		dst.Status.Stats = src.Status.Stats
+		dst.Status.Autoscaling = src.Status.Autoscaling
+		dst.Status.Recommendation = src.Status.Recommendation
+		dst.Status.Cost = src.Status.Cost
 
 		return nil
 	default:
@@ -78,27 +106,48 @@ func (dst *Memcached) ConvertFrom(srcRaw conversion.Hub) error {
 	case *cachev1alpha1.Memcached:
 		src := srcRaw.(*cachev1alpha1.Memcached)
 
-		// conversion implementation goes here
-		// We parse price amount and currency from the string form and
-		// convert it in structured form.
-		parts := strings.Fields(src.Spec.Price)
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid price")
-		}
-		amount, err := strconv.Atoi(parts[0])
-		if err != nil {
-			return err
-		}
-		dst.Spec.Price = Price{
-			Amount:   int64(amount),
-			Currency: parts[1],
-		}
 		// ObjectMeta
 		dst.ObjectMeta = src.ObjectMeta
 		//rest of the conversion
 		dst.Spec.Size = src.Spec.Size
 		dst.Status.Nodes = src.Status.Nodes
 		dst.Spec.Suspend = src.Spec.Suspend
+		dst.Spec.Version = src.Spec.Version
+		dst.Spec.Image = src.Spec.Image
+		dst.Spec.Config = src.Spec.Config
+		dst.Spec.Resources = src.Spec.Resources
+		dst.Spec.Autoscaling = src.Spec.Autoscaling
+		dst.Spec.Sizing = src.Spec.Sizing
+		dst.Spec.DeletionPolicy = src.Spec.DeletionPolicy
+		dst.Spec.WorkloadKind = src.Spec.WorkloadKind
+		dst.Spec.Service = src.Spec.Service
+		dst.Spec.Scheduling = src.Spec.Scheduling
+		dst.Spec.Disruption = src.Spec.Disruption
+		dst.Spec.TLS = src.Spec.TLS
+		dst.Spec.Auth = src.Spec.Auth
+		dst.Spec.Monitoring = src.Spec.Monitoring
+		dst.Status.Version = src.Status.Version
+		dst.Status.MemoryOverheadPercent = src.Status.MemoryOverheadPercent
+		dst.Status.OOMOverheadPercent = src.Status.OOMOverheadPercent
+		dst.Status.OOMKills = src.Status.OOMKills
+		dst.Status.LastOOMKillTime = src.Status.LastOOMKillTime
+		dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+		dst.Status.ReadyReplicas = src.Status.ReadyReplicas
+		dst.Status.LastError = src.Status.LastError
+		dst.Status.Conditions = src.Status.Conditions
+		dst.Status.Replicas = src.Status.Replicas
+		dst.Status.Selector = src.Status.Selector
+		dst.Status.SuspendedReplicas = src.Status.SuspendedReplicas
+		dst.Status.Stats = src.Status.Stats
+		dst.Status.Autoscaling = src.Status.Autoscaling
+		dst.Status.Recommendation = src.Status.Recommendation
+		dst.Status.Cost = src.Status.Cost
+		// conversion implementation goes here
+		// We parse price amount and currency from the string form and
+		// convert it in structured form. A price that can't be parsed is
+		// kept in an annotation rather than rejected. This comes last, as
+		// the annotation records the rest of the spec.
+		convertPriceFrom(src, dst)
 
 		return nil
 	default:
@@ -0,0 +1,201 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// fuzzIterations is the number of random objects each round trip test
+// converts.
+const fuzzIterations = 200
+
+// newFuzzer returns a fuzzer that mixes well-formed and malformed prices,
+// which random strings alone would hardly ever produce.
+func newFuzzer(seed int64) *fuzz.Fuzzer {
+	amounts := []Decimal{"", "0", "10", "9.99", "1.", "-1", "abc", "1e3", " 5"}
+	currencies := []string{"", "USD", "EUR", "U SD", "USD ", "\tGBP"}
+	prices := []string{"", "10 USD", "9.99 USD", "0.5 EUR", "10  USD", " 10 USD", "10 USD ", "abc", "10", "1/2 USD", "-1 USD"}
+	return fuzz.NewWithSeed(seed).NilChance(0.3).NumElements(0, 2).MaxDepth(6).Funcs(
+		func(p *Price, c fuzz.Continue) {
+			if c.RandBool() {
+				c.FuzzNoCustom(p)
+				return
+			}
+			p.Amount = amounts[c.Intn(len(amounts))]
+			p.Currency = currencies[c.Intn(len(currencies))]
+		},
+		func(s *cachev1alpha1.MemcachedSpec, c fuzz.Continue) {
+			c.FuzzNoCustom(s)
+			if c.RandBool() {
+				s.Price = prices[c.Intn(len(prices))]
+			}
+		},
+	)
+}
+
+func TestConvertRoundTripFromSpoke(t *testing.T) {
+	f := newFuzzer(1)
+	for i := 0; i < fuzzIterations; i++ {
+		original := &Memcached{}
+		f.Fuzz(original)
+		original.TypeMeta = metav1.TypeMeta{}
+
+		hub := &cachev1alpha1.Memcached{}
+		if err := original.DeepCopy().ConvertTo(hub); err != nil {
+			t.Fatalf("failed to convert %+v to the hub: %v", original, err)
+		}
+		got := &Memcached{}
+		if err := got.ConvertFrom(hub); err != nil {
+			t.Fatalf("failed to convert %+v from the hub: %v", hub, err)
+		}
+		if !equality.Semantic.DeepEqual(original, got) {
+			t.Fatalf("round trip through the hub changed the object:\nwant %+v\ngot  %+v", original, got)
+		}
+	}
+}
+
+func TestConvertRoundTripFromHub(t *testing.T) {
+	f := newFuzzer(2)
+	for i := 0; i < fuzzIterations; i++ {
+		original := &cachev1alpha1.Memcached{}
+		f.Fuzz(original)
+		original.TypeMeta = metav1.TypeMeta{}
+
+		spoke := &Memcached{}
+		if err := spoke.ConvertFrom(original.DeepCopy()); err != nil {
+			t.Fatalf("failed to convert %+v from the hub: %v", original, err)
+		}
+		got := &cachev1alpha1.Memcached{}
+		if err := spoke.ConvertTo(got); err != nil {
+			t.Fatalf("failed to convert %+v to the hub: %v", spoke, err)
+		}
+		if !equality.Semantic.DeepEqual(original, got) {
+			t.Fatalf("round trip through v1alpha2 changed the object:\nwant %+v\ngot  %+v", original, got)
+		}
+	}
+}
+
+func TestConvertPrice(t *testing.T) {
+	tests := []struct {
+		name        string
+		price       string
+		want        Price
+		annotations map[string]string
+	}{
+		{name: "integer", price: "10 USD", want: Price{Amount: "10", Currency: "USD"}},
+		{name: "decimal", price: "9.99 USD", want: Price{Amount: "9.99", Currency: "USD"}},
+		{name: "empty", price: ""},
+		{name: "two spaces", price: "10  USD", annotations: map[string]string{hubPriceAnnotation: "10  USD"}},
+		{name: "no currency", price: "abc", annotations: map[string]string{hubPriceAnnotation: "abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := &cachev1alpha1.Memcached{Spec: cachev1alpha1.MemcachedSpec{Size: 3, Price: tt.price}}
+			m := &Memcached{}
+			if err := m.ConvertFrom(hub); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.Spec.Price != tt.want {
+				t.Errorf("expected price %+v, got %+v", tt.want, m.Spec.Price)
+			}
+			if _, ok := m.Annotations[hubPriceSpecAnnotation]; ok != (tt.annotations != nil) {
+				t.Errorf("expected annotation %s only with a kept price, got %v", hubPriceSpecAnnotation, m.Annotations)
+			}
+			delete(m.Annotations, hubPriceSpecAnnotation)
+			if len(m.Annotations) == 0 {
+				m.Annotations = nil
+			}
+			if !equality.Semantic.DeepEqual(m.Annotations, tt.annotations) {
+				t.Errorf("expected annotations %v, got %v", tt.annotations, m.Annotations)
+			}
+		})
+	}
+}
+
+func TestConvertUnformattablePrice(t *testing.T) {
+	m := &Memcached{Spec: MemcachedSpec{Size: 3, Price: Price{Amount: "9.99"}}}
+	hub := &cachev1alpha1.Memcached{}
+	if err := m.ConvertTo(hub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hub.Spec.Price != "9.99" {
+		t.Errorf("expected price %q, got %q", "9.99", hub.Spec.Price)
+	}
+	if _, ok := hub.Annotations[priceAnnotation]; !ok {
+		t.Errorf("expected the price to be kept in annotation %s", priceAnnotation)
+	}
+
+	// A v1alpha1 client changing the price drops the kept one
+	hub.Spec.Price = "10 USD"
+	got := &Memcached{}
+	if err := got.ConvertFrom(hub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Price{Amount: "10", Currency: "USD"}); got.Spec.Price != want {
+		t.Errorf("expected price %+v, got %+v", want, got.Spec.Price)
+	}
+	if len(got.Annotations) != 0 {
+		t.Errorf("expected no annotations, got %v", got.Annotations)
+	}
+}
+
+func TestConvertClearedPrice(t *testing.T) {
+	hub := &cachev1alpha1.Memcached{Spec: cachev1alpha1.MemcachedSpec{Size: 3, Price: "abc"}}
+	m := &Memcached{}
+	if err := m.ConvertFrom(hub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Unchanged, the price comes back
+	got := &cachev1alpha1.Memcached{}
+	if err := m.DeepCopy().ConvertTo(got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Spec.Price != "abc" {
+		t.Errorf("expected price %q, got %q", "abc", got.Spec.Price)
+	}
+
+	// Removing the annotation clears it
+	cleared := m.DeepCopy()
+	delete(cleared.Annotations, hubPriceAnnotation)
+	got = &cachev1alpha1.Memcached{}
+	if err := cleared.ConvertTo(got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Spec.Price != "" {
+		t.Errorf("expected the price to be cleared, got %q", got.Spec.Price)
+	}
+
+	// A v1alpha2 client writing the spec keeps the empty price it sees
+	m.Spec.Size = 5
+	got = &cachev1alpha1.Memcached{}
+	if err := m.ConvertTo(got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Spec.Price != "" {
+		t.Errorf("expected the price to be cleared, got %q", got.Spec.Price)
+	}
+	if len(got.Annotations) != 0 {
+		t.Errorf("expected no annotations, got %v", got.Annotations)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// +kubebuilder:docs-gen:collapse=Apache License
+
+package v1alpha2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// Decimal is an exact, non-negative decimal number written as a string, for
+// example "9.99". Unlike resource.Quantity it keeps the digits as written,
+// so prices convert to and from v1alpha1 without rounding.
+// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+type Decimal string
+
+// decimalPattern matches the strings a Decimal may hold.
+var decimalPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// Valid reports whether d is a non-negative decimal number.
+func (d Decimal) Valid() bool {
+	return decimalPattern.MatchString(string(d))
+}
+
+const (
+	// hubPriceAnnotation keeps a v1alpha1 price that has no structured form,
+	// so converting back to v1alpha1 restores it. A v1alpha2 client clears
+	// the price by removing the annotation or by changing the spec.
+	hubPriceAnnotation = "cache.example.com/v1alpha1-price"
+	// hubPriceSpecAnnotation is a hash of the v1alpha2 spec the price in
+	// hubPriceAnnotation was converted along with. The price is only
+	// restored while the spec is unchanged, so a client that edits the spec
+	// sees the empty price it wrote.
+	hubPriceSpecAnnotation = "cache.example.com/v1alpha1-price-spec"
+	// priceAnnotation keeps a v1alpha2 price, as JSON, that has no
+	// "<amount> <currency>" form, so converting back restores it.
+	priceAnnotation = "cache.example.com/v1alpha2-price"
+)
+
+// formatPrice returns the v1alpha1 form of p, "<amount> <currency>" or ""
+// for no price, and whether parsePrice reads it back as p.
+func formatPrice(p Price) (string, bool) {
+	if p == (Price{}) {
+		return "", true
+	}
+	s := strings.TrimSpace(string(p.Amount) + " " + p.Currency)
+	return s, p.Amount.Valid() && p.Currency != "" && !strings.ContainsAny(p.Currency, " \t\n\v\f\r")
+}
+
+// parsePrice reads a v1alpha1 price, reporting false when it has no
+// structured form that formats back to the same string.
+func parsePrice(s string) (Price, bool) {
+	if s == "" {
+		return Price{}, true
+	}
+	parts := strings.Split(s, " ")
+	if len(parts) != 2 {
+		return Price{}, false
+	}
+	p := Price{Amount: Decimal(parts[0]), Currency: parts[1]}
+	if formatted, ok := formatPrice(p); !ok || formatted != s {
+		return Price{}, false
+	}
+	return p, true
+}
+
+// convertPriceTo sets the v1alpha1 price of dst from src, falling back to a
+// price kept by convertPriceFrom when the spec was not edited since, and
+// keeps a price v1alpha1 can't hold in an annotation. dst.ObjectMeta must
+// already be copied from src.
+func convertPriceTo(src *Memcached, dst *cachev1alpha1.Memcached) {
+	annotations := withoutAnnotation(withoutAnnotation(src.Annotations, hubPriceAnnotation), hubPriceSpecAnnotation)
+	price, ok := formatPrice(src.Spec.Price)
+	if hubPrice, found := src.Annotations[hubPriceAnnotation]; found && src.Spec.Price == (Price{}) &&
+		src.Annotations[hubPriceSpecAnnotation] == specHash(src.Spec) {
+		price = hubPrice
+	} else if !ok {
+		data, _ := json.Marshal(src.Spec.Price)
+		annotations = withAnnotation(annotations, priceAnnotation, string(data))
+	}
+	dst.Spec.Price = price
+	dst.Annotations = annotations
+}
+
+// convertPriceFrom sets the price of dst from the v1alpha1 src, preferring
+// a price kept by convertPriceTo that still matches, and keeps a price
+// that can't be parsed in an annotation. dst.ObjectMeta and the rest of
+// dst.Spec must already be copied from src.
+func convertPriceFrom(src *cachev1alpha1.Memcached, dst *Memcached) {
+	annotations := withoutAnnotation(src.Annotations, priceAnnotation)
+	price, ok := parsePrice(src.Spec.Price)
+	if data, found := src.Annotations[priceAnnotation]; found {
+		var kept Price
+		if err := json.Unmarshal([]byte(data), &kept); err == nil {
+			if formatted, _ := formatPrice(kept); formatted == src.Spec.Price {
+				price, ok = kept, true
+			}
+		}
+	}
+	dst.Spec.Price = price
+	if !ok {
+		annotations = withAnnotation(annotations, hubPriceAnnotation, src.Spec.Price)
+		annotations = withAnnotation(annotations, hubPriceSpecAnnotation, specHash(dst.Spec))
+	}
+	dst.Annotations = annotations
+}
+
+// specHash fingerprints spec, to tell whether a client changed it.
+func specHash(spec MemcachedSpec) string {
+	data, _ := json.Marshal(spec)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// withoutAnnotation returns a copy of annotations without key, or nil if
+// nothing is left.
+func withoutAnnotation(annotations map[string]string, key string) map[string]string {
+	var out map[string]string
+	for k, v := range annotations {
+		if k == key {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// withAnnotation sets key to value in annotations, which may be nil.
+func withAnnotation(annotations map[string]string, key, value string) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	return annotations
+}
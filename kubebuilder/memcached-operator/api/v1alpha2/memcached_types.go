@@ -25,6 +25,8 @@ package v1alpha2
  */
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -39,23 +41,95 @@ type MemcachedSpec struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
 	// Price is a field representing price per GB for a disk.
+	// The controller reads it as the monthly price of a GB of pod memory
+	// and reports the cost of the instance in status.cost.
 	Price Price `json:"price"`
 
 	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=256
 	// Size is the size of the memcached deployment
 	Size int32 `json:"size"`
 
-	// This flag tells the controller to suspend subsequent executions, it does
-	// not apply to already started executions.  Defaults to false.
+	// Suspend scales the instance to zero and stops the controller from
+	// changing it until the flag is cleared again. The number of replicas at
+	// the time of suspension is kept in status and restored on resume.
+	// Defaults to false.
 	// +optional
 	Suspend *bool `json:"suspend,omitempty"`
+
+	// Version is the memcached release to run, for example "1.6.9". When
+	// changed, pods are replaced one at a time. Defaults to the tag of Image,
+	// or 1.4.36 when Image is not set either.
+	// +kubebuilder:validation:Pattern=`^v?[0-9]+\.[0-9]+\.[0-9]+$`
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Image overrides the memcached container image. Defaults to the alpine
+	// variant of the official image for Version.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Config tunes the memcached server. Unset fields keep their defaults.
+	// +optional
+	Config *cachev1alpha1.MemcachedConfig `json:"config,omitempty"`
+
+	// Resources controls the container resources derived from the cache size.
+	// +optional
+	Resources *cachev1alpha1.MemcachedResources `json:"resources,omitempty"`
+
+	// Autoscaling makes the controller manage a HorizontalPodAutoscaler that
+	// scales this Memcached through its scale subresource, or scale it
+	// itself when a policy is set.
+	// +optional
+	Autoscaling *cachev1alpha1.MemcachedAutoscaling `json:"autoscaling,omitempty"`
+
+	// Sizing controls the memory and replica recommendation in
+	// status.recommendation, and whether it is applied.
+	// +optional
+	Sizing *cachev1alpha1.MemcachedSizing `json:"sizing,omitempty"`
+
+	// DeletionPolicy says what happens to the memcached pods when this
+	// Memcached is deleted: Delete removes them, Orphan leaves them and their
+	// Services running. Defaults to Delete.
+	// +optional
+	DeletionPolicy cachev1alpha1.DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// WorkloadKind selects what runs the memcached pods. A StatefulSet gives
+	// every pod a stable hostname of the form <name>-<ordinal>.<name>, which
+	// consistent-hashing clients need to keep their shards across restarts.
+	// Changing it on a running instance brings up the new workload before
+	// the old one is removed. Defaults to Deployment.
+	// +optional
+	WorkloadKind cachev1alpha1.WorkloadKind `json:"workloadKind,omitempty"`
+	// Service controls how the memcached pods are exposed. A Service named
+	// after the instance is always created.
+	// +optional
+	Service *cachev1alpha1.MemcachedService `json:"service,omitempty"`
+	// Scheduling controls where the memcached pods may run. Unless it sets
+	// an affinity or topology spread of its own, pods are spread across
+	// nodes and zones so that one failure takes out as few shards as
+	// possible.
+	// +optional
+	Scheduling *cachev1alpha1.MemcachedScheduling `json:"scheduling,omitempty"`
+	// Disruption controls the PodDisruptionBudget of the memcached pods.
+	// +optional
+	Disruption *cachev1alpha1.MemcachedDisruption `json:"disruption,omitempty"`
+	// TLS encrypts client connections. It needs memcached 1.5.13 or later.
+	// +optional
+	TLS *cachev1alpha1.MemcachedTLS `json:"tls,omitempty"`
+	// Auth requires clients to authenticate with SASL. Only the binary
+	// protocol supports SASL, so config.protocol must not be ascii.
+	// +optional
+	Auth *cachev1alpha1.MemcachedAuth `json:"auth,omitempty"`
+	// Monitoring controls how the controller observes the memcached pods.
+	// +optional
+	Monitoring *cachev1alpha1.MemcachedMonitoring `json:"monitoring,omitempty"`
 }
 
 // Price represents a generic price value that has amount and currency.
 type Price struct {
-	// specifies the amount value.
+	// specifies the amount value as a decimal string, for example "9.99".
 	// +optional
-	Amount int64 `json:"amount"`
+	Amount Decimal `json:"amount"`
 	// specifies the curreny type.
 	// +optional
 	Currency string `json:"currency"`
@@ -68,13 +142,94 @@ type MemcachedStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	// Nodes are the names of the memcached pods
 	Nodes []string `json:"nodes"`
+
+	// Version is the memcached release every pod is running. It is only
+	// updated once a version change has been fully rolled out.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// MemoryOverheadPercent is the memory overhead currently applied.
+	// +optional
+	MemoryOverheadPercent int32 `json:"memoryOverheadPercent,omitempty"`
+
+	// OOMOverheadPercent is how far the overhead has been raised above
+	// spec.resources.memoryOverheadPercent in response to OOM kills.
+	// +optional
+	OOMOverheadPercent int32 `json:"oomOverheadPercent,omitempty"`
+
+	// OOMKills counts the OOM kills the controller has corrected for.
+	// +optional
+	OOMKills int32 `json:"oomKills,omitempty"`
+
+	// LastOOMKillTime is when the most recent OOM kill happened.
+	// +optional
+	LastOOMKillTime *metav1.Time `json:"lastOOMKillTime,omitempty"`
+
+	// ObservedGeneration is the most recent .metadata.generation the
+	// controller has acted on.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ReadyReplicas is the number of memcached pods ready to serve.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// LastError is the error that stopped the last reconcile, if any.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// Conditions are the latest observations of the instance's state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []cachev1alpha1.MemcachedCondition `json:"conditions,omitempty"`
+
+	// Replicas is the number of memcached pods. It backs the scale
+	// subresource.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector is the label selector of the memcached pods in string form.
+	// It backs the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// SuspendedReplicas is the number of replicas the instance had when it
+	// was suspended. It is restored when the instance is resumed.
+	// +optional
+	SuspendedReplicas *int32 `json:"suspendedReplicas,omitempty"`
+
+	// Stats are the statistics last collected from the memcached pods.
+	// +optional
+	Stats *cachev1alpha1.MemcachedStats `json:"stats,omitempty"`
+
+	// Autoscaling records what spec.autoscaling.policy decided.
+	// +optional
+	Autoscaling *cachev1alpha1.MemcachedAutoscalingStatus `json:"autoscaling,omitempty"`
+
+	// Recommendation is the memory and replica count the statistics call
+	// for.
+	// +optional
+	Recommendation *cachev1alpha1.MemcachedRecommendation `json:"recommendation,omitempty"`
+
+	// Cost is what running the instance costs at its current size, from
+	// spec.price. It is not set without a price.
+	// +optional
+	Cost *cachev1alpha1.MemcachedCost `json:"cost,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 
 // Memcached is the Schema for the memcacheds API
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.size,statuspath=.status.replicas,selectorpath=.status.selector
 // k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.spec.size`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="Degraded",type=string,JSONPath=`.status.conditions[?(@.type=="Degraded")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // Memcached is the Schema for the memcached API
 type Memcached struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -0,0 +1,201 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// +kubebuilder:docs-gen:collapse=Apache License
+
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// +kubebuilder:docs-gen:collapse=Imports
+
+/*
+v1beta1 only moves fields of the hub into sections, so both directions copy
+every field and nothing is lost. The hub can't tell a section that is set
+but empty from a missing one, so those are listed in an annotation. The
+status has the same fields as the hub status and converts as a whole.
+*/
+
+const (
+	// emptySectionsAnnotation lists, separated by commas, the spec sections
+	// that were set but empty, so converting back from the hub restores them.
+	emptySectionsAnnotation = "cache.example.com/v1beta1-empty-sections"
+
+	configSection   = "config"
+	scalingSection  = "scaling"
+	securitySection = "security"
+)
+
+// ConvertTo converts this Memcached to the Hub version (v1alpha1).
+func (src *Memcached) ConvertTo(dstRaw conversion.Hub) error {
+	switch t := dstRaw.(type) {
+	case *cachev1alpha1.Memcached:
+		dst := dstRaw.(*cachev1alpha1.Memcached)
+
+		// ObjectMeta
+		dst.ObjectMeta = src.ObjectMeta
+		dst.Annotations = withoutAnnotation(src.Annotations, emptySectionsAnnotation)
+		if empty := src.Spec.emptySections(); len(empty) > 0 {
+			dst.Annotations = withAnnotation(dst.Annotations, emptySectionsAnnotation, strings.Join(empty, ","))
+		}
+		// Spec
+		dst.Spec = cachev1alpha1.MemcachedSpec{
+			Size:           src.Spec.Size,
+			Suspend:        src.Spec.Suspend,
+			Version:        src.Spec.Version,
+			Image:          src.Spec.Image,
+			Price:          src.Spec.Price,
+			DeletionPolicy: src.Spec.DeletionPolicy,
+			WorkloadKind:   src.Spec.WorkloadKind,
+			Scheduling:     src.Spec.Scheduling,
+			Service:        src.Spec.Service,
+			Monitoring:     src.Spec.Monitoring,
+		}
+		if config := src.Spec.Config; config != nil {
+			dst.Spec.Config = config.Server
+			dst.Spec.Resources = config.Resources
+		}
+		if scaling := src.Spec.Scaling; scaling != nil {
+			dst.Spec.Autoscaling = scaling.Autoscaling
+			dst.Spec.Sizing = scaling.Sizing
+			dst.Spec.Disruption = scaling.Disruption
+		}
+		if security := src.Spec.Security; security != nil {
+			dst.Spec.TLS = security.TLS
+			dst.Spec.Auth = security.Auth
+		}
+		// Status
+		dst.Status = cachev1alpha1.MemcachedStatus(src.Status)
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %v", t)
+	}
+}
+
+// ConvertFrom converts from the Hub version (v1alpha1) to this version.
+// Sections the hub has no fields for are left out, unless they were set but
+// empty when converted to the hub.
+func (dst *Memcached) ConvertFrom(srcRaw conversion.Hub) error {
+	switch t := srcRaw.(type) {
+	case *cachev1alpha1.Memcached:
+		src := srcRaw.(*cachev1alpha1.Memcached)
+
+		// ObjectMeta
+		dst.ObjectMeta = src.ObjectMeta
+		dst.Annotations = withoutAnnotation(src.Annotations, emptySectionsAnnotation)
+		// Spec
+		dst.Spec = MemcachedSpec{
+			Size:           src.Spec.Size,
+			Suspend:        src.Spec.Suspend,
+			Version:        src.Spec.Version,
+			Image:          src.Spec.Image,
+			Price:          src.Spec.Price,
+			DeletionPolicy: src.Spec.DeletionPolicy,
+			WorkloadKind:   src.Spec.WorkloadKind,
+			Scheduling:     src.Spec.Scheduling,
+			Service:        src.Spec.Service,
+			Monitoring:     src.Spec.Monitoring,
+		}
+		if src.Spec.Config != nil || src.Spec.Resources != nil {
+			dst.Spec.Config = &MemcachedConfig{
+				Server:    src.Spec.Config,
+				Resources: src.Spec.Resources,
+			}
+		}
+		if src.Spec.Autoscaling != nil || src.Spec.Sizing != nil || src.Spec.Disruption != nil {
+			dst.Spec.Scaling = &MemcachedScaling{
+				Autoscaling: src.Spec.Autoscaling,
+				Sizing:      src.Spec.Sizing,
+				Disruption:  src.Spec.Disruption,
+			}
+		}
+		if src.Spec.TLS != nil || src.Spec.Auth != nil {
+			dst.Spec.Security = &MemcachedSecurity{
+				TLS:  src.Spec.TLS,
+				Auth: src.Spec.Auth,
+			}
+		}
+		if empty, found := src.Annotations[emptySectionsAnnotation]; found {
+			dst.Spec.restoreEmptySections(strings.Split(empty, ","))
+		}
+		// Status
+		dst.Status = MemcachedStatus(src.Status)
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %v", t)
+	}
+}
+
+// emptySections returns the sections of s that are set but hold nothing.
+func (s *MemcachedSpec) emptySections() []string {
+	var empty []string
+	if s.Config != nil && *s.Config == (MemcachedConfig{}) {
+		empty = append(empty, configSection)
+	}
+	if s.Scaling != nil && *s.Scaling == (MemcachedScaling{}) {
+		empty = append(empty, scalingSection)
+	}
+	if s.Security != nil && *s.Security == (MemcachedSecurity{}) {
+		empty = append(empty, securitySection)
+	}
+	return empty
+}
+
+// restoreEmptySections sets the given sections of s that are missing to
+// empty ones.
+func (s *MemcachedSpec) restoreEmptySections(sections []string) {
+	for _, section := range sections {
+		switch {
+		case section == configSection && s.Config == nil:
+			s.Config = &MemcachedConfig{}
+		case section == scalingSection && s.Scaling == nil:
+			s.Scaling = &MemcachedScaling{}
+		case section == securitySection && s.Security == nil:
+			s.Security = &MemcachedSecurity{}
+		}
+	}
+}
+
+// withoutAnnotation returns a copy of annotations without key, or nil if
+// nothing is left.
+func withoutAnnotation(annotations map[string]string, key string) map[string]string {
+	var out map[string]string
+	for k, v := range annotations {
+		if k == key {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// withAnnotation sets key to value in annotations, which may be nil.
+func withAnnotation(annotations map[string]string, key, value string) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	return annotations
+}
@@ -0,0 +1,136 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// fuzzIterations is the number of random objects each round trip test
+// converts.
+const fuzzIterations = 200
+
+// newFuzzer returns a fuzzer that leaves a third of the spec sections it
+// sets empty.
+func newFuzzer(seed int64) *fuzz.Fuzzer {
+	return fuzz.NewWithSeed(seed).NilChance(0.3).NumElements(0, 2).MaxDepth(6).Funcs(
+		func(c *MemcachedConfig, cont fuzz.Continue) {
+			if cont.Intn(3) > 0 {
+				cont.FuzzNoCustom(c)
+			}
+		},
+		func(s *MemcachedScaling, cont fuzz.Continue) {
+			if cont.Intn(3) > 0 {
+				cont.FuzzNoCustom(s)
+			}
+		},
+		func(s *MemcachedSecurity, cont fuzz.Continue) {
+			if cont.Intn(3) > 0 {
+				cont.FuzzNoCustom(s)
+			}
+		},
+	)
+}
+
+func TestConvertRoundTripFromSpoke(t *testing.T) {
+	f := newFuzzer(1)
+	for i := 0; i < fuzzIterations; i++ {
+		original := &Memcached{}
+		f.Fuzz(original)
+		original.TypeMeta = metav1.TypeMeta{}
+
+		hub := &cachev1alpha1.Memcached{}
+		if err := original.DeepCopy().ConvertTo(hub); err != nil {
+			t.Fatalf("failed to convert %+v to the hub: %v", original, err)
+		}
+		got := &Memcached{}
+		if err := got.ConvertFrom(hub); err != nil {
+			t.Fatalf("failed to convert %+v from the hub: %v", hub, err)
+		}
+		if !equality.Semantic.DeepEqual(original, got) {
+			t.Fatalf("round trip through the hub changed the object:\nwant %+v\ngot  %+v", original, got)
+		}
+	}
+}
+
+func TestConvertRoundTripFromHub(t *testing.T) {
+	f := newFuzzer(2)
+	for i := 0; i < fuzzIterations; i++ {
+		original := &cachev1alpha1.Memcached{}
+		f.Fuzz(original)
+		original.TypeMeta = metav1.TypeMeta{}
+
+		spoke := &Memcached{}
+		if err := spoke.ConvertFrom(original.DeepCopy()); err != nil {
+			t.Fatalf("failed to convert %+v from the hub: %v", original, err)
+		}
+		got := &cachev1alpha1.Memcached{}
+		if err := spoke.ConvertTo(got); err != nil {
+			t.Fatalf("failed to convert %+v to the hub: %v", spoke, err)
+		}
+		if !equality.Semantic.DeepEqual(original, got) {
+			t.Fatalf("round trip through v1beta1 changed the object:\nwant %+v\ngot  %+v", original, got)
+		}
+	}
+}
+
+func TestConvertSections(t *testing.T) {
+	hub := &cachev1alpha1.Memcached{Spec: cachev1alpha1.MemcachedSpec{
+		Size: 3,
+		TLS:  &cachev1alpha1.MemcachedTLS{SecretName: "memcached-tls"},
+	}}
+	m := &Memcached{}
+	if err := m.ConvertFrom(hub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Spec.Security == nil || m.Spec.Security.TLS != hub.Spec.TLS {
+		t.Errorf("expected tls in the security section, got %+v", m.Spec.Security)
+	}
+	if m.Spec.Config != nil || m.Spec.Scaling != nil {
+		t.Errorf("expected no config or scaling section, got %+v and %+v", m.Spec.Config, m.Spec.Scaling)
+	}
+}
+
+func TestConvertEmptySections(t *testing.T) {
+	m := &Memcached{Spec: MemcachedSpec{
+		Size:     3,
+		Config:   &MemcachedConfig{},
+		Security: &MemcachedSecurity{},
+	}}
+	hub := &cachev1alpha1.Memcached{}
+	if err := m.ConvertTo(hub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := hub.Annotations[emptySectionsAnnotation]; got != "config,security" {
+		t.Errorf("expected the empty sections in annotation %s, got %q", emptySectionsAnnotation, got)
+	}
+	got := &Memcached{}
+	if err := got.ConvertFrom(hub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !equality.Semantic.DeepEqual(m, got) {
+		t.Errorf("round trip through the hub changed the object:\nwant %+v\ngot  %+v", m, got)
+	}
+	if len(got.Annotations) != 0 {
+		t.Errorf("expected no annotations, got %v", got.Annotations)
+	}
+}
@@ -0,0 +1,263 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// +kubebuilder:docs-gen:collapse=Apache License
+
+/*
+Since we're in a v1beta1 package, controller-gen will assume this is for the v1beta1
+version automatically.  We could override that with the [`+versionName`
+marker](/reference/markers/crd.md).
+*/
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// +kubebuilder:docs-gen:collapse=Imports
+
+// MemcachedSpec defines the desired state of Memcached. Compared to
+// v1alpha1, the options are grouped into config, scaling, security and
+// monitoring sections. An empty section is the same as leaving it out.
+// +k8s:openapi-gen=true
+type MemcachedSpec struct {
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=256
+	// Size is the size of the memcached deployment
+	Size int32 `json:"size"`
+
+	// Suspend scales the instance to zero and stops the controller from
+	// changing it until the flag is cleared again. The number of replicas at
+	// the time of suspension is kept in status and restored on resume.
+	// Defaults to false.
+	// +optional
+	Suspend *bool `json:"suspend,omitempty"`
+
+	// Version is the memcached release to run, for example "1.6.9". When
+	// changed, pods are replaced one at a time. Defaults to the tag of Image,
+	// or 1.4.36 when Image is not set either.
+	// +kubebuilder:validation:Pattern=`^v?[0-9]+\.[0-9]+\.[0-9]+$`
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Image overrides the memcached container image. Defaults to the alpine
+	// variant of the official image for Version.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Price is the monthly price of a GB of pod memory, written as
+	// "<amount> <currency>", for example "9.99 USD". The controller reports
+	// the cost of the instance in status.cost.
+	// +optional
+	Price string `json:"price,omitempty"`
+
+	// DeletionPolicy says what happens to the memcached pods when this
+	// Memcached is deleted: Delete removes them, Orphan leaves them and their
+	// Services running. Defaults to Delete.
+	// +optional
+	DeletionPolicy cachev1alpha1.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// WorkloadKind selects what runs the memcached pods. A StatefulSet gives
+	// every pod a stable hostname of the form <name>-<ordinal>.<name>.
+	// Defaults to Deployment.
+	// +optional
+	WorkloadKind cachev1alpha1.WorkloadKind `json:"workloadKind,omitempty"`
+
+	// Config tunes the memcached server and its container.
+	// +optional
+	Config *MemcachedConfig `json:"config,omitempty"`
+
+	// Scaling controls how many pods run and how many may be down at once.
+	// +optional
+	Scaling *MemcachedScaling `json:"scaling,omitempty"`
+
+	// Scheduling controls where the memcached pods may run. Unless it sets
+	// an affinity or topology spread of its own, pods are spread across
+	// nodes and zones.
+	// +optional
+	Scheduling *cachev1alpha1.MemcachedScheduling `json:"scheduling,omitempty"`
+
+	// Service controls how the memcached pods are exposed. A Service named
+	// after the instance is always created.
+	// +optional
+	Service *cachev1alpha1.MemcachedService `json:"service,omitempty"`
+
+	// Security controls encryption and authentication of client
+	// connections.
+	// +optional
+	Security *MemcachedSecurity `json:"security,omitempty"`
+
+	// Monitoring controls how the controller observes the memcached pods.
+	// +optional
+	Monitoring *cachev1alpha1.MemcachedMonitoring `json:"monitoring,omitempty"`
+}
+
+// MemcachedConfig groups the options of the memcached server and its
+// container.
+type MemcachedConfig struct {
+	// Server tunes the memcached server. Unset fields keep their defaults.
+	// +optional
+	Server *cachev1alpha1.MemcachedConfig `json:"server,omitempty"`
+
+	// Resources controls the container resources derived from the cache
+	// size.
+	// +optional
+	Resources *cachev1alpha1.MemcachedResources `json:"resources,omitempty"`
+}
+
+// MemcachedScaling groups the options that size a Memcached.
+type MemcachedScaling struct {
+	// Autoscaling makes the controller manage a HorizontalPodAutoscaler that
+	// scales this Memcached through its scale subresource, or scale it
+	// itself when a policy is set.
+	// +optional
+	Autoscaling *cachev1alpha1.MemcachedAutoscaling `json:"autoscaling,omitempty"`
+
+	// Sizing controls the memory and replica recommendation in
+	// status.recommendation, and whether it is applied.
+	// +optional
+	Sizing *cachev1alpha1.MemcachedSizing `json:"sizing,omitempty"`
+
+	// Disruption controls the PodDisruptionBudget of the memcached pods.
+	// +optional
+	Disruption *cachev1alpha1.MemcachedDisruption `json:"disruption,omitempty"`
+}
+
+// MemcachedSecurity groups the options that protect client connections.
+type MemcachedSecurity struct {
+	// TLS encrypts client connections. It needs memcached 1.5.13 or later.
+	// +optional
+	TLS *cachev1alpha1.MemcachedTLS `json:"tls,omitempty"`
+
+	// Auth requires clients to authenticate with SASL. Only the binary
+	// protocol supports SASL, so config.server.protocol must not be ascii.
+	// +optional
+	Auth *cachev1alpha1.MemcachedAuth `json:"auth,omitempty"`
+}
+
+// MemcachedStatus defines the observed state of Memcached. It holds the
+// same fields as the v1alpha1 status, so the two convert into each other.
+// +k8s:openapi-gen=true
+type MemcachedStatus struct {
+	// Nodes are the names of the memcached pods
+	Nodes []string `json:"nodes"`
+
+	// Version is the memcached release every pod is running. It is only
+	// updated once a version change has been fully rolled out.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// MemoryOverheadPercent is the memory overhead currently applied.
+	// +optional
+	MemoryOverheadPercent int32 `json:"memoryOverheadPercent,omitempty"`
+
+	// OOMOverheadPercent is how far the overhead has been raised above
+	// spec.resources.memoryOverheadPercent in response to OOM kills.
+	// +optional
+	OOMOverheadPercent int32 `json:"oomOverheadPercent,omitempty"`
+
+	// OOMKills counts the OOM kills the controller has corrected for.
+	// +optional
+	OOMKills int32 `json:"oomKills,omitempty"`
+
+	// LastOOMKillTime is when the most recent OOM kill happened.
+	// +optional
+	LastOOMKillTime *metav1.Time `json:"lastOOMKillTime,omitempty"`
+
+	// ObservedGeneration is the most recent .metadata.generation the
+	// controller has acted on.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ReadyReplicas is the number of memcached pods ready to serve.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// LastError is the error that stopped the last reconcile, if any.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// Conditions are the latest observations of the instance's state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []cachev1alpha1.MemcachedCondition `json:"conditions,omitempty"`
+
+	// Replicas is the number of memcached pods. It backs the scale
+	// subresource.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector is the label selector of the memcached pods in string form.
+	// It backs the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// SuspendedReplicas is the number of replicas the instance had when it
+	// was suspended. It is restored when the instance is resumed.
+	// +optional
+	SuspendedReplicas *int32 `json:"suspendedReplicas,omitempty"`
+
+	// Stats are the statistics last collected from the memcached pods.
+	// +optional
+	Stats *cachev1alpha1.MemcachedStats `json:"stats,omitempty"`
+
+	// Autoscaling records what spec.autoscaling.policy decided.
+	// +optional
+	Autoscaling *cachev1alpha1.MemcachedAutoscalingStatus `json:"autoscaling,omitempty"`
+
+	// Recommendation is the memory and replica count the statistics call
+	// for.
+	// +optional
+	Recommendation *cachev1alpha1.MemcachedRecommendation `json:"recommendation,omitempty"`
+
+	// Cost is what running the instance costs at its current size, from
+	// spec.price. It is not set without a price.
+	// +optional
+	Cost *cachev1alpha1.MemcachedCost `json:"cost,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// Memcached is the Schema for the memcacheds API
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.size,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.spec.size`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="Degraded",type=string,JSONPath=`.status.conditions[?(@.type=="Degraded")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type Memcached struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MemcachedSpec   `json:"spec,omitempty"`
+	Status MemcachedStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MemcachedList contains a list of Memcached
+type MemcachedList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Memcached `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Memcached{}, &MemcachedList{})
+}
@@ -0,0 +1,56 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// newTestReconciler returns a MemcachedReconciler backed by a fake client
+// that already holds objs.
+func newTestReconciler(objs ...runtime.Object) *MemcachedReconciler {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(cachev1alpha1.AddToScheme(scheme))
+	return &MemcachedReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme, objs...),
+		Scheme:   scheme,
+		Log:      ctrl.Log,
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+// testMemcached returns the Memcached the controller tests start from, an
+// instance named cache in namespace ns with an empty spec.
+func testMemcached() *cachev1alpha1.Memcached {
+	return &cachev1alpha1.Memcached{
+		ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "ns", UID: "uid"},
+	}
+}
+
+// keyOf returns the name a test looks objects up by.
+func keyOf(m *cachev1alpha1.Memcached) types.NamespacedName {
+	return types.NamespacedName{Name: m.Name, Namespace: m.Namespace}
+}
@@ -0,0 +1,198 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// Where the rendered SASL configuration is mounted in the memcached
+// container, and the keys of the Secret holding it.
+const (
+	saslMountPath = "/etc/memcached/sasl"
+	saslVolume    = "sasl"
+	// saslConfKey is read by Cyrus SASL, which looks for <app>.conf in
+	// SASL_CONF_PATH.
+	saslConfKey = "memcached.conf"
+	// saslPasswordsKey is the password database memcached reads from
+	// MEMCACHED_SASL_PWDB, one user:password per line.
+	saslPasswordsKey = "memcached-sasl-db"
+)
+
+// saslConf only offers PLAIN, which is what the password database checks.
+const saslConf = "mech_list: plain\n"
+
+// saslSecretName returns the name of the Secret the SASL configuration of m
+// is rendered into.
+func saslSecretName(m *cachev1alpha1.Memcached) string {
+	return m.Name + "-sasl"
+}
+
+// renderSASLPasswords turns a Secret of SASL users into the contents of a
+// memcached password database. It fails when the Secret has no users or a
+// password that cannot be written to the database.
+func renderSASLPasswords(users *corev1.Secret) ([]byte, error) {
+	names := make([]string, 0, len(users.Data))
+	for name := range users.Data {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("Secret %s has no users", users.Name)
+	}
+	sort.Strings(names)
+
+	var db bytes.Buffer
+	for _, name := range names {
+		password := users.Data[name]
+		if len(password) == 0 {
+			return nil, fmt.Errorf("Secret %s has an empty password for user %s", users.Name, name)
+		}
+		// The database is line based. Secret keys cannot hold a colon, so
+		// user names need no checking.
+		if bytes.ContainsAny(password, "\r\n") {
+			return nil, fmt.Errorf("Secret %s has a password with a line break for user %s", users.Name, name)
+		}
+		fmt.Fprintf(&db, "%s:%s\n", name, password)
+	}
+	return db.Bytes(), nil
+}
+
+// saslSecretForMemcached returns the Secret holding the SASL configuration
+// and password database of m.
+func (r *MemcachedReconciler) saslSecretForMemcached(m *cachev1alpha1.Memcached, passwords []byte) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      saslSecretName(m),
+			Namespace: m.Namespace,
+			Labels:    labelsForMemcached(m.Name),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			saslConfKey:      []byte(saslConf),
+			saslPasswordsKey: passwords,
+		},
+	}
+	// Set Memcached instance as the owner and controller
+	ctrl.SetControllerReference(m, secret, r.Scheme)
+	return secret
+}
+
+// applyAuth mounts the rendered SASL configuration of m into the memcached
+// container of pod and points memcached at it.
+func applyAuth(pod *corev1.PodSpec, m *cachev1alpha1.Memcached) {
+	if m.Spec.Auth == nil {
+		return
+	}
+	pod.Volumes = append(pod.Volumes, corev1.Volume{
+		Name:         saslVolume,
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: saslSecretName(m)}},
+	})
+	for i := range pod.Containers {
+		c := &pod.Containers[i]
+		if c.Name != "memcached" {
+			continue
+		}
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: saslVolume, MountPath: saslMountPath, ReadOnly: true})
+		c.Env = append(c.Env,
+			corev1.EnvVar{Name: "SASL_CONF_PATH", Value: saslMountPath},
+			corev1.EnvVar{Name: "MEMCACHED_SASL_PWDB", Value: path.Join(saslMountPath, saslPasswordsKey)})
+	}
+}
+
+// reconcileAuth renders the users from spec.auth into the SASL Secret of m
+// and returns the Auth condition. The error is set when the users cannot be
+// read, in which case the rendered Secret and the pods are left alone:
+// pods started without a password database would only crash.
+func (r *MemcachedReconciler) reconcileAuth(ctx context.Context, log logr.Logger, m *cachev1alpha1.Memcached) (cachev1alpha1.MemcachedCondition, error) {
+	cond := cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedAuthReady,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: m.Generation,
+		Reason:             ReasonAuthDisabled,
+	}
+
+	found := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: saslSecretName(m), Namespace: m.Namespace}, found)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get SASL Secret")
+		return cond, err
+	}
+	exists := err == nil
+
+	auth := m.Spec.Auth
+	if auth == nil {
+		// Authentication was turned off, the rendered passwords go too
+		if exists && metav1.IsControlledBy(found, m) {
+			log.Info("Deleting SASL Secret", "Secret.Namespace", found.Namespace, "Secret.Name", found.Name)
+			if err := r.Delete(ctx, found); err != nil && !errors.IsNotFound(err) {
+				log.Error(err, "Failed to delete SASL Secret")
+				return cond, err
+			}
+		}
+		return cond, nil
+	}
+
+	users := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: auth.SecretName, Namespace: m.Namespace}, users); err != nil {
+		if errors.IsNotFound(err) {
+			err = fmt.Errorf("Secret %s not found", auth.SecretName)
+		}
+		cond.Reason, cond.Message = ReasonInvalidAuthSecret, err.Error()
+		return cond, err
+	}
+	passwords, err := renderSASLPasswords(users)
+	if err != nil {
+		cond.Reason, cond.Message = ReasonInvalidAuthSecret, err.Error()
+		return cond, err
+	}
+
+	desired := r.saslSecretForMemcached(m, passwords)
+	switch {
+	case !exists:
+		log.Info("Creating SASL Secret", "Secret.Namespace", desired.Namespace, "Secret.Name", desired.Name)
+		if err := r.Create(ctx, desired); err != nil {
+			log.Error(err, "Failed to create SASL Secret")
+			return cond, err
+		}
+	case !metav1.IsControlledBy(found, m):
+		return cond, fmt.Errorf("Secret %s/%s exists and is not owned by this Memcached", found.Namespace, found.Name)
+	case !bytes.Equal(found.Data[saslConfKey], desired.Data[saslConfKey]) ||
+		!bytes.Equal(found.Data[saslPasswordsKey], desired.Data[saslPasswordsKey]):
+		log.Info("Updating SASL Secret", "Secret.Namespace", found.Namespace, "Secret.Name", found.Name)
+		found.Data = desired.Data
+		if err := r.Update(ctx, found); err != nil {
+			log.Error(err, "Failed to update SASL Secret")
+			return cond, err
+		}
+	}
+
+	cond.Status, cond.Reason = corev1.ConditionTrue, ReasonAuthEnabled
+	cond.Message = fmt.Sprintf("%d SASL users from Secret %s", len(users.Data), auth.SecretName)
+	return cond, nil
+}
@@ -0,0 +1,96 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestReconcileAuth(t *testing.T) {
+	m := testMemcached()
+	m.Spec = cachev1alpha1.MemcachedSpec{
+		Size: 3,
+		Auth: &cachev1alpha1.MemcachedAuth{SecretName: "users"},
+	}
+	r := newTestReconciler(m)
+	ctx := context.TODO()
+	rendered := types.NamespacedName{Name: saslSecretName(m), Namespace: m.Namespace}
+
+	// A missing Secret is reported and nothing is rendered
+	cond, err := r.reconcileAuth(ctx, r.Log, m)
+	if err == nil || cond.Reason != ReasonInvalidAuthSecret {
+		t.Fatalf("missing Secret: got %v, %s", err, cond.Reason)
+	}
+	if err := r.Get(ctx, rendered, &corev1.Secret{}); !errors.IsNotFound(err) {
+		t.Fatalf("SASL Secret rendered without users: %v", err)
+	}
+
+	// So is a malformed one
+	users := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "users", Namespace: m.Namespace},
+		Data:       map[string][]byte{"app": []byte("s3cret\nroot:x")},
+	}
+	if err := r.Create(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+	cond, err = r.reconcileAuth(ctx, r.Log, m)
+	if err == nil || cond.Reason != ReasonInvalidAuthSecret {
+		t.Fatalf("malformed Secret: got %v, %s", err, cond.Reason)
+	}
+
+	users.Data = map[string][]byte{"app": []byte("s3cret"), "admin": []byte("p:w")}
+	if err := r.Update(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+	cond, err = r.reconcileAuth(ctx, r.Log, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cond.Status != corev1.ConditionTrue || cond.Reason != ReasonAuthEnabled {
+		t.Errorf("condition = %s %s, want True %s", cond.Status, cond.Reason, ReasonAuthEnabled)
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, rendered, secret); err != nil {
+		t.Fatal(err)
+	}
+	if db, want := string(secret.Data[saslPasswordsKey]), "admin:p:w\napp:s3cret\n"; db != want {
+		t.Errorf("password database = %q, want %q", db, want)
+	}
+	if !metav1.IsControlledBy(secret, m) {
+		t.Errorf("SASL Secret is not owned by the Memcached")
+	}
+
+	// Turning authentication off removes the rendered passwords
+	m.Spec.Auth = nil
+	cond, err = r.reconcileAuth(ctx, r.Log, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cond.Reason != ReasonAuthDisabled {
+		t.Errorf("reason = %s, want %s", cond.Reason, ReasonAuthDisabled)
+	}
+	if err := r.Get(ctx, rendered, &corev1.Secret{}); !errors.IsNotFound(err) {
+		t.Errorf("SASL Secret kept after auth was turned off: %v", err)
+	}
+}
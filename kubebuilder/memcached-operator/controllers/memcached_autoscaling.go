@@ -0,0 +1,290 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// reconcileHPA keeps the HorizontalPodAutoscaler of a Memcached in line with
+// spec.autoscaling. The autoscaler scales the Memcached itself through its
+// scale subresource, so spec.size stays the single source of truth for the
+// Deployment.
+func (r *MemcachedReconciler) reconcileHPA(ctx context.Context, log logr.Logger, m *cachev1alpha1.Memcached) error {
+	found := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+	err := r.Get(ctx, types.NamespacedName{Name: m.Name, Namespace: m.Namespace}, found)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get HorizontalPodAutoscaler")
+		return err
+	}
+	exists := err == nil
+
+	autoscaling := m.Spec.AutoscalingWithDefaults()
+	if autoscaling == nil || autoscaling.Policy != nil {
+		// Autoscaling was turned off or is done by the controller itself,
+		// only remove an autoscaler we own.
+		if !exists || !metav1.IsControlledBy(found, m) {
+			return nil
+		}
+		log.Info("Deleting HorizontalPodAutoscaler", "HorizontalPodAutoscaler.Namespace", found.Namespace, "HorizontalPodAutoscaler.Name", found.Name)
+		if err := r.Delete(ctx, found); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete HorizontalPodAutoscaler")
+			return err
+		}
+		return nil
+	}
+
+	hpa := r.hpaForMemcached(m, autoscaling)
+	if !exists {
+		log.Info("Creating a new HorizontalPodAutoscaler", "HorizontalPodAutoscaler.Namespace", hpa.Namespace, "HorizontalPodAutoscaler.Name", hpa.Name)
+		if err := r.Create(ctx, hpa); err != nil {
+			log.Error(err, "Failed to create new HorizontalPodAutoscaler")
+			return err
+		}
+		return nil
+	}
+	if equality.Semantic.DeepDerivative(hpa.Spec, found.Spec) {
+		return nil
+	}
+	found.Spec = hpa.Spec
+	if err := r.Update(ctx, found); err != nil {
+		log.Error(err, "Failed to update HorizontalPodAutoscaler")
+		return err
+	}
+	return nil
+}
+
+// hpaForMemcached returns a HorizontalPodAutoscaler that scales the given
+// Memcached within the bounds of a defaulted autoscaling section.
+func (r *MemcachedReconciler) hpaForMemcached(m *cachev1alpha1.Memcached, autoscaling *cachev1alpha1.MemcachedAutoscaling) *autoscalingv2beta2.HorizontalPodAutoscaler {
+	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name,
+			Namespace: m.Namespace,
+			Labels:    labelsForMemcached(m.Name),
+		},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				APIVersion: cachev1alpha1.GroupVersion.String(),
+				Kind:       "Memcached",
+				Name:       m.Name,
+			},
+			MinReplicas: autoscaling.MinReplicas,
+			MaxReplicas: autoscaling.MaxReplicas,
+			Metrics:     autoscaling.Metrics,
+		},
+	}
+	// Set Memcached instance as the owner and controller
+	ctrl.SetControllerReference(m, hpa, r.Scheme)
+	return hpa
+}
+
+// maxScalingDecisions is the number of scaling decisions kept in status.
+const maxScalingDecisions = 10
+
+// Reasons of scaling decisions.
+const (
+	ScalingReasonBelowMinReplicas  = "BelowMinReplicas"
+	ScalingReasonAboveMaxReplicas  = "AboveMaxReplicas"
+	ScalingReasonEvictions         = "Evictions"
+	ScalingReasonMemoryUtilization = "MemoryUtilization"
+	ScalingReasonHitRatio          = "HitRatio"
+)
+
+// reconcileScalingPolicy applies spec.autoscaling.policy to m once fresh
+// statistics are in status. A decision changes spec.size, which the next
+// reconcile rolls out, and is recorded in status and as an event.
+func (r *MemcachedReconciler) reconcileScalingPolicy(ctx context.Context, log logr.Logger, m *cachev1alpha1.Memcached, status *cachev1alpha1.MemcachedStatus) error {
+	autoscaling := m.Spec.AutoscalingWithDefaults()
+	if autoscaling == nil || autoscaling.Policy == nil {
+		status.Autoscaling = nil
+		return nil
+	}
+	// Only decide once per poll
+	if status.Stats == nil || (m.Status.Stats != nil && m.Status.Stats.CollectedTime.Equal(&status.Stats.CollectedTime)) {
+		return nil
+	}
+	var lastScaleTime *metav1.Time
+	if status.Autoscaling != nil {
+		lastScaleTime = status.Autoscaling.LastScaleTime
+	}
+	decision := scalingDecision(m.Spec.Size, autoscaling, status.Stats, lastScaleTime, time.Now())
+	if decision == nil {
+		return nil
+	}
+
+	// Look for an admitted size further in the direction of the decision,
+	// without shrinking below what the statistics ask for
+	limit := autoscaling.MaxReplicas
+	switch {
+	case decision.Reason == ScalingReasonAboveMaxReplicas:
+		limit = *autoscaling.MinReplicas
+	case decision.To < decision.From:
+		limit = decision.From - 1
+	}
+	size, ok, err := r.admittedSize(ctx, m, decision.To, limit)
+	if err != nil {
+		log.Error(err, "Failed to check the admission policies")
+		return err
+	}
+	if !ok {
+		log.Info("No size the admission policies admit", "from", decision.From, "to", decision.To, "limit", limit)
+		r.Recorder.Eventf(m, corev1.EventTypeWarning, "ScalingBlocked",
+			"not scaling from %d to %d: no size up to %d is admitted by the admission policies", decision.From, decision.To, limit)
+		return nil
+	}
+	if size != decision.To {
+		decision.Message += fmt.Sprintf(", %d is the nearest size to %d the admission policies admit", size, decision.To)
+		decision.To = size
+	}
+
+	log.Info("Scaling Memcached", "from", decision.From, "to", decision.To, "reason", decision.Reason)
+	m.Spec.Size = decision.To
+	if err := r.Update(ctx, m); err != nil {
+		log.Error(err, "Failed to scale Memcached")
+		return err
+	}
+	event := "ScaledUp"
+	if decision.To < decision.From {
+		event = "ScaledDown"
+	}
+	r.Recorder.Eventf(m, corev1.EventTypeNormal, event, "scaled from %d to %d: %s", decision.From, decision.To, decision.Message)
+
+	if status.Autoscaling == nil {
+		status.Autoscaling = &cachev1alpha1.MemcachedAutoscalingStatus{}
+	}
+	status.Autoscaling.LastScaleTime = &decision.Time
+	status.Autoscaling.Decisions = append(status.Autoscaling.Decisions, *decision)
+	if n := len(status.Autoscaling.Decisions); n > maxScalingDecisions {
+		status.Autoscaling.Decisions = status.Autoscaling.Decisions[n-maxScalingDecisions:]
+	}
+	return nil
+}
+
+// scalingDecision returns the size a defaulted autoscaling section with a
+// policy asks for, given the current size and statistics, or nil to stay.
+// lastScaleTime is when the size was last changed, if ever.
+func scalingDecision(size int32, autoscaling *cachev1alpha1.MemcachedAutoscaling, stats *cachev1alpha1.MemcachedStats, lastScaleTime *metav1.Time, now time.Time) *cachev1alpha1.MemcachedScalingDecision {
+	decision := &cachev1alpha1.MemcachedScalingDecision{Time: metav1.NewTime(now), From: size, To: size}
+	min, max := *autoscaling.MinReplicas, autoscaling.MaxReplicas
+	switch {
+	case size < min:
+		decision.To, decision.Reason = clampSize(min, min, max), ScalingReasonBelowMinReplicas
+		decision.Message = fmt.Sprintf("size is below the minimum of %d", min)
+		return decision
+	case size > max:
+		decision.To, decision.Reason = clampSize(max, min, max), ScalingReasonAboveMaxReplicas
+		decision.Message = fmt.Sprintf("size is above the maximum of %d", max)
+		return decision
+	}
+
+	p := autoscaling.Policy
+	evicting := stats.EvictionsPerMinute != nil && *stats.EvictionsPerMinute > 0
+	missing := false
+	if p.MaxEvictionsPerMinute != nil && stats.EvictionsPerMinute != nil && *stats.EvictionsPerMinute > *p.MaxEvictionsPerMinute {
+		decision.To, decision.Reason = size+1, ScalingReasonEvictions
+		decision.Message = fmt.Sprintf("%d evictions per minute, above the maximum of %d", *stats.EvictionsPerMinute, *p.MaxEvictionsPerMinute)
+	}
+	if p.MinHitRatioPercent != nil && stats.HitRatioPercent != nil && *stats.HitRatioPercent < *p.MinHitRatioPercent {
+		missing = true
+		if decision.To == size {
+			decision.To, decision.Reason = size+1, ScalingReasonHitRatio
+			decision.Message = fmt.Sprintf("hit ratio of %d%%, below the minimum of %d%%", *stats.HitRatioPercent, *p.MinHitRatioPercent)
+		}
+	}
+	if p.TargetMemoryUtilizationPercent != nil && stats.LimitMaxBytes > 0 {
+		target := int64(*p.TargetMemoryUtilizationPercent)
+		utilization := stats.BytesUsed * 100 / stats.LimitMaxBytes
+		// The size that brings utilization to the target, rounded up
+		wanted := int32((int64(size)*utilization + target - 1) / target)
+		switch {
+		case wanted > decision.To:
+			decision.To, decision.Reason = wanted, ScalingReasonMemoryUtilization
+			decision.Message = fmt.Sprintf("memory utilization of %d%%, above the target of %d%%", utilization, target)
+		case decision.To == size && !evicting && !missing && stats.EvictionsPerMinute != nil &&
+			wanted < size:
+			// Shrink by as little as possible, every pod taken away
+			// empties part of the cache
+			decision.To, decision.Reason = size-1, ScalingReasonMemoryUtilization
+			decision.Message = fmt.Sprintf("memory utilization of %d%%, below the target of %d%%", utilization, target)
+		}
+	}
+
+	grow := decision.To > size
+	decision.To = clampSize(decision.To, min, max)
+	if decision.To == size {
+		return nil
+	}
+	cooldown := *p.ScaleDownCooldownSeconds
+	if grow {
+		cooldown = *p.ScaleUpCooldownSeconds
+	}
+	if lastScaleTime != nil && now.Before(lastScaleTime.Add(time.Duration(cooldown)*time.Second)) {
+		return nil
+	}
+	return decision
+}
+
+// admittedSize returns the first size from want to limit, in either
+// direction, that the admission policies admit for m with the rest of its
+// spec unchanged. It reports false when there is none.
+func (r *MemcachedReconciler) admittedSize(ctx context.Context, m *cachev1alpha1.Memcached, want, limit int32) (int32, bool, error) {
+	if r.Policies == nil {
+		return want, true, nil
+	}
+	step := int32(1)
+	if limit < want {
+		step = -1
+	}
+	for size := want; ; size += step {
+		candidate := m.DeepCopy()
+		candidate.Spec.Size = size
+		errs, err := r.Policies.Check(ctx, candidate)
+		if err != nil {
+			return 0, false, err
+		}
+		if len(errs) == 0 {
+			return size, true, nil
+		}
+		if size == limit {
+			return 0, false, nil
+		}
+	}
+}
+
+// clampSize keeps size within min and max.
+func clampSize(size, min, max int32) int32 {
+	if size < min {
+		return min
+	}
+	if size > max {
+		return max
+	}
+	return size
+}
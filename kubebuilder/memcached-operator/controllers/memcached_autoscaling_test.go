@@ -0,0 +1,181 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestHPAForMemcached(t *testing.T) {
+	r := newTestReconciler()
+	m := testMemcached()
+	m.Spec.Autoscaling = &cachev1alpha1.MemcachedAutoscaling{MaxReplicas: 6}
+
+	hpa := r.hpaForMemcached(m, m.Spec.AutoscalingWithDefaults())
+	ref := hpa.Spec.ScaleTargetRef
+	if ref.APIVersion != "cache.example.com/v1alpha1" || ref.Kind != "Memcached" || ref.Name != "cache" {
+		t.Errorf("scaleTargetRef = %+v, want the Memcached itself", ref)
+	}
+	if *hpa.Spec.MinReplicas != cachev1alpha1.DefaultMinReplicas || hpa.Spec.MaxReplicas != 6 {
+		t.Errorf("replicas = %d..%d, want %d..6", *hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas, cachev1alpha1.DefaultMinReplicas)
+	}
+	if len(hpa.Spec.Metrics) != 1 || hpa.Spec.Metrics[0].Resource.Name != corev1.ResourceCPU {
+		t.Errorf("metrics = %+v, want the default CPU metric", hpa.Spec.Metrics)
+	}
+	if !metav1.IsControlledBy(hpa, m) {
+		t.Errorf("HorizontalPodAutoscaler is not controlled by its Memcached")
+	}
+}
+
+func TestScalingDecision(t *testing.T) {
+	now := time.Now()
+	int32p := func(i int32) *int32 { return &i }
+	int64p := func(i int64) *int64 { return &i }
+	min := int32(1)
+	autoscaling := &cachev1alpha1.MemcachedAutoscaling{
+		MinReplicas: &min,
+		MaxReplicas: 9,
+		Policy: &cachev1alpha1.MemcachedAutoscalingPolicy{
+			MaxEvictionsPerMinute:          int64p(10),
+			TargetMemoryUtilizationPercent: int32p(80),
+			MinHitRatioPercent:             int32p(50),
+			ScaleUpCooldownSeconds:         int32p(60),
+			ScaleDownCooldownSeconds:       int32p(600),
+		},
+	}
+	// stats returns statistics with the given memory utilization, eviction
+	// rate and hit ratio
+	stats := func(utilization int64, evictions int64, hitRatio int32) *cachev1alpha1.MemcachedStats {
+		return &cachev1alpha1.MemcachedStats{
+			BytesUsed:          utilization,
+			LimitMaxBytes:      100,
+			EvictionsPerMinute: &evictions,
+			HitRatioPercent:    &hitRatio,
+		}
+	}
+	recently := metav1.NewTime(now.Add(-2 * time.Minute))
+
+	tests := []struct {
+		name       string
+		size       int32
+		stats      *cachev1alpha1.MemcachedStats
+		lastScale  *metav1.Time
+		wantSize   int32
+		wantReason string
+	}{
+		{name: "on target", size: 3, stats: stats(70, 0, 90), wantSize: 3},
+		{name: "evicting", size: 3, stats: stats(70, 20, 90), wantSize: 4, wantReason: ScalingReasonEvictions},
+		{name: "missing", size: 3, stats: stats(70, 0, 40), wantSize: 4, wantReason: ScalingReasonHitRatio},
+		{name: "memory full", size: 3, stats: stats(200, 0, 90), wantSize: 8, wantReason: ScalingReasonMemoryUtilization},
+		{name: "memory beyond max", size: 3, stats: stats(400, 0, 90), wantSize: 9, wantReason: ScalingReasonMemoryUtilization},
+		{name: "memory idle", size: 5, stats: stats(10, 0, 90), wantSize: 4, wantReason: ScalingReasonMemoryUtilization},
+		// Going from 5 to 4 pods would put utilization at 88%
+		{name: "memory idle but shrinking overshoots", size: 5, stats: stats(70, 0, 90), wantSize: 5},
+		{name: "memory idle but evicting", size: 5, stats: stats(10, 5, 90), wantSize: 5},
+		{name: "memory idle without eviction rate", size: 5, stats: &cachev1alpha1.MemcachedStats{BytesUsed: 10, LimitMaxBytes: 100}, wantSize: 5},
+		{name: "at max", size: 9, stats: stats(70, 20, 90), wantSize: 9},
+		{name: "at min", size: 1, stats: stats(10, 0, 90), wantSize: 1},
+		{name: "above max", size: 11, stats: stats(70, 0, 90), wantSize: 9, wantReason: ScalingReasonAboveMaxReplicas},
+		{name: "up after cooldown", size: 3, stats: stats(70, 20, 90), lastScale: &recently, wantSize: 4, wantReason: ScalingReasonEvictions},
+		{name: "down within cooldown", size: 5, stats: stats(10, 0, 90), lastScale: &recently, wantSize: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := scalingDecision(tt.size, autoscaling, tt.stats, tt.lastScale, now)
+			if tt.wantSize == tt.size {
+				if decision != nil {
+					t.Fatalf("unexpected decision %+v", decision)
+				}
+				return
+			}
+			if decision == nil {
+				t.Fatalf("no decision, want %d", tt.wantSize)
+			}
+			if decision.From != tt.size || decision.To != tt.wantSize || decision.Reason != tt.wantReason {
+				t.Errorf("decision = %d to %d (%s), want %d to %d (%s)",
+					decision.From, decision.To, decision.Reason, tt.size, tt.wantSize, tt.wantReason)
+			}
+		})
+	}
+}
+
+// oddSizes is a PolicyChecker that only admits odd sizes up to max.
+type oddSizes struct {
+	max int32
+}
+
+func (c oddSizes) Check(ctx context.Context, m *cachev1alpha1.Memcached) (field.ErrorList, error) {
+	if m.Spec.Size%2 == 0 || m.Spec.Size > c.max {
+		return field.ErrorList{field.Forbidden(field.NewPath("spec"), "MemcachedPolicy odd rejected the request")}, nil
+	}
+	return nil, nil
+}
+
+func (c oddSizes) ValidatePolicy(spec *cachev1alpha1.MemcachedPolicySpec, fldPath *field.Path) field.ErrorList {
+	return nil
+}
+
+func TestScalingPolicyWithAdmissionPolicies(t *testing.T) {
+	int64p := func(i int64) *int64 { return &i }
+	tests := []struct {
+		name     string
+		max      int32
+		wantSize int32
+	}{
+		// Evictions ask for 4 pods, the next odd size is 5
+		{name: "next admitted size", max: 9, wantSize: 5},
+		{name: "no admitted size", max: 3, wantSize: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMemcached()
+			m.Spec = cachev1alpha1.MemcachedSpec{
+				Size: 3,
+				Autoscaling: &cachev1alpha1.MemcachedAutoscaling{
+					MaxReplicas: 9,
+					Policy:      &cachev1alpha1.MemcachedAutoscalingPolicy{MaxEvictionsPerMinute: int64p(10)},
+				},
+			}
+			m.Default()
+			r := newTestReconciler(m)
+			r.Policies = oddSizes{max: tt.max}
+			status := &cachev1alpha1.MemcachedStatus{Stats: &cachev1alpha1.MemcachedStats{
+				CollectedTime:      metav1.Now(),
+				BytesUsed:          10,
+				LimitMaxBytes:      100,
+				EvictionsPerMinute: int64p(20),
+			}}
+			if err := r.reconcileScalingPolicy(context.TODO(), r.Log, m, status); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := &cachev1alpha1.Memcached{}
+			if err := r.Get(context.TODO(), keyOf(m), got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Spec.Size != tt.wantSize {
+				t.Errorf("size = %d, want %d", got.Spec.Size, tt.wantSize)
+			}
+		})
+	}
+}
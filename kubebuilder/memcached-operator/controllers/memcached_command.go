@@ -0,0 +1,89 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// memcachedCommand turns the configuration, TLS and auth settings of spec
+// into the memcached container command. It fails when the configuration is
+// invalid or turns on options the given memcached release does not
+// understand.
+func memcachedCommand(spec *cachev1alpha1.MemcachedSpec, version cachev1alpha1.MemcachedVersion) ([]string, error) {
+	config := spec.ConfigWithDefaults()
+	specPath := field.NewPath("spec")
+	if errs := config.Validate(specPath.Child("config")); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+	tls := spec.TLS
+	if spec.Auth != nil {
+		if errs := spec.Auth.Validate(specPath.Child("auth"), config); len(errs) > 0 {
+			return nil, errs.ToAggregate()
+		}
+	}
+	features := config.RequiredFeatures()
+	if tls != nil {
+		features = append(features, cachev1alpha1.FeatureTLS)
+	}
+	if err := version.RequireFeatures(features...); err != nil {
+		return nil, err
+	}
+
+	udpPort := int32(0)
+	if config.EnableUDP {
+		udpPort = config.Port
+	}
+	cmd := []string{
+		"memcached",
+		"-p", strconv.Itoa(int(config.Port)),
+		"-U", strconv.Itoa(int(udpPort)),
+		"-m", strconv.Itoa(int(config.MemoryMB)),
+		"-c", strconv.Itoa(int(config.MaxConnections)),
+		"-t", strconv.Itoa(int(config.Threads)),
+		"-I", strconv.FormatInt(config.MaxItemSize.Value(), 10),
+	}
+	if config.DisableEvictions {
+		cmd = append(cmd, "-M")
+	}
+	if config.Protocol != cachev1alpha1.ProtocolAuto {
+		cmd = append(cmd, "-B", string(config.Protocol))
+	}
+	if tls != nil {
+		cmd = append(cmd, "-Z")
+	}
+	if spec.Auth != nil {
+		cmd = append(cmd, "-S")
+	}
+
+	options := []string{"modern"}
+	if config.LRUCrawler {
+		options = append(options, "lru_crawler")
+	}
+	if config.LRUMaintainer {
+		options = append(options, "lru_maintainer")
+	}
+	if tls != nil {
+		options = append(options, tlsOptions(tls)...)
+	}
+	cmd = append(cmd, "-o", strings.Join(options, ","))
+	return append(cmd, "-v"), nil
+}
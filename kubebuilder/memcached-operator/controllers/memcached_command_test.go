@@ -0,0 +1,130 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestMemcachedCommand(t *testing.T) {
+	itemSize := resource.MustParse("2Mi")
+	hugeItem := resource.MustParse("64Mi")
+	v1436 := cachev1alpha1.MemcachedVersion{Major: 1, Minor: 4, Patch: 36}
+	v1420 := cachev1alpha1.MemcachedVersion{Major: 1, Minor: 4, Patch: 20}
+	v1513 := cachev1alpha1.MemcachedVersion{Major: 1, Minor: 5, Patch: 13}
+
+	tests := []struct {
+		name    string
+		config  *cachev1alpha1.MemcachedConfig
+		tls     *cachev1alpha1.MemcachedTLS
+		auth    *cachev1alpha1.MemcachedAuth
+		version cachev1alpha1.MemcachedVersion
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			version: v1436,
+			want:    []string{"memcached", "-p", "11211", "-U", "0", "-m", "64", "-c", "1024", "-t", "4", "-I", "1048576", "-o", "modern", "-v"},
+		},
+		{
+			name: "tuned",
+			config: &cachev1alpha1.MemcachedConfig{
+				MemoryMB:         512,
+				MaxConnections:   4096,
+				Threads:          8,
+				MaxItemSize:      &itemSize,
+				DisableEvictions: true,
+				LRUCrawler:       true,
+				LRUMaintainer:    true,
+				EnableUDP:        true,
+				Protocol:         cachev1alpha1.ProtocolASCII,
+				Port:             11311,
+			},
+			version: v1436,
+			want: []string{"memcached", "-p", "11311", "-U", "11311", "-m", "512", "-c", "4096", "-t", "8", "-I", "2097152",
+				"-M", "-B", "ascii", "-o", "modern,lru_crawler,lru_maintainer", "-v"},
+		},
+		{
+			name:    "release without modern",
+			version: v1420,
+			wantErr: true,
+		},
+		{
+			name:    "tls with client certificates",
+			tls:     &cachev1alpha1.MemcachedTLS{SecretName: "cert", ClientCASecretName: "ca"},
+			version: v1513,
+			want: []string{"memcached", "-p", "11211", "-U", "0", "-m", "64", "-c", "1024", "-t", "4", "-I", "1048576", "-Z",
+				"-o", "modern,ssl_chain_cert=/etc/memcached/tls/tls.crt,ssl_key=/etc/memcached/tls/tls.key," +
+					"ssl_ca_cert=/etc/memcached/client-ca/ca.crt,ssl_verify_mode=2", "-v"},
+		},
+		{
+			name:    "tls on an old release",
+			tls:     &cachev1alpha1.MemcachedTLS{SecretName: "cert"},
+			version: v1436,
+			wantErr: true,
+		},
+		{
+			name:    "sasl",
+			auth:    &cachev1alpha1.MemcachedAuth{SecretName: "users"},
+			config:  &cachev1alpha1.MemcachedConfig{Protocol: cachev1alpha1.ProtocolBinary},
+			version: v1436,
+			want:    []string{"memcached", "-p", "11211", "-U", "0", "-m", "64", "-c", "1024", "-t", "4", "-I", "1048576", "-B", "binary", "-S", "-o", "modern", "-v"},
+		},
+		{
+			name:    "sasl with the text protocol",
+			auth:    &cachev1alpha1.MemcachedAuth{SecretName: "users"},
+			config:  &cachev1alpha1.MemcachedConfig{Protocol: cachev1alpha1.ProtocolASCII},
+			version: v1436,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported option",
+			config:  &cachev1alpha1.MemcachedConfig{LRUMaintainer: true},
+			version: v1420,
+			wantErr: true,
+		},
+		{
+			name:    "item larger than half the cache",
+			config:  &cachev1alpha1.MemcachedConfig{MaxItemSize: &hugeItem},
+			version: v1436,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := cachev1alpha1.MemcachedSpec{Config: tt.config, TLS: tt.tls, Auth: tt.auth}
+			got, err := memcachedCommand(&spec, tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("command = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -22,16 +22,23 @@ package controllers
 import (
 	"context"
 	"reflect"
+	"time"
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
 )
@@ -39,14 +46,31 @@ import (
 // MemcachedReconciler reconciles a Memcached object
 type MemcachedReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// StatsInterval is how often the memcached pods are polled for their
+	// statistics. Zero turns polling off. Instances with TLS, SASL or the
+	// binary protocol are not polled.
+	StatsInterval time.Duration
+	// Policies, if set, are the admission policies the validating webhook
+	// checks. Autoscaling and sizing only move spec.size to sizes they
+	// admit.
+	Policies cachev1alpha1.PolicyChecker
 }
 
 // +kubebuilder:rbac:groups=cache.example.com,resources=memcacheds,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=cache.example.com,resources=memcacheds/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=cache.example.com,resources=memcacheds/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (r *MemcachedReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -58,7 +82,7 @@ func (r *MemcachedReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
-			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Teardown has finished, the finalizer was removed.
 			// Return and don't requeue
 			log.Info("Memcached resource not found. Ignoring since object must be deleted")
 			return ctrl.Result{}, nil
@@ -68,54 +92,228 @@ func (r *MemcachedReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	// Check if the deployment already exists, if not create a new one
-	found := &appsv1.Deployment{}
-	err = r.Get(ctx, types.NamespacedName{Name: memcached.Name, Namespace: memcached.Namespace}, found)
-	if err != nil && errors.IsNotFound(err) {
-		// Define a new deployment
-		dep := r.deploymentForMemcached(memcached)
-		log.Info("Creating a new Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
-		err = r.Create(ctx, dep)
+	// A deleted Memcached is torn down according to its deletion policy
+	if !memcached.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, log, memcached)
+	}
+	if err := r.ensureFinalizer(ctx, memcached); err != nil {
+		log.Error(err, "Failed to add finalizer")
+		return ctrl.Result{}, err
+	}
+
+	result, err := r.reconcileMemcached(ctx, log, memcached)
+	if err != nil {
+		// Record why we failed so that it shows up in status, then requeue
+		// unless retrying cannot help.
+		if statusErr := r.updateFailedStatus(ctx, memcached, err); statusErr != nil {
+			log.Error(statusErr, "Failed to update Memcached status")
+		}
+		if isSpecError(err) {
+			log.Error(err, "Invalid Memcached spec, refusing to reconcile")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	return result, nil
+}
+
+// reconcileMemcached drives the cluster towards the state described by the
+// memcached spec.
+func (r *MemcachedReconciler) reconcileMemcached(ctx context.Context, log logr.Logger, memcached *cachev1alpha1.Memcached) (ctrl.Result, error) {
+	// Work out which memcached release we are asked to run. There is no point
+	// in requeueing an invalid version, the next spec change will trigger a
+	// new reconcile.
+	version, err := memcached.Spec.ParsedVersion()
+	if err != nil {
+		return ctrl.Result{}, specError{err}
+	}
+	config := memcached.Spec.ConfigWithDefaults()
+	command, err := memcachedCommand(&memcached.Spec, version)
+	if err != nil {
+		return ctrl.Result{}, specError{err}
+	}
+	// Pods cannot start without their certificates, so wait for usable TLS
+	// Secrets before touching them
+	tlsCondition, err := r.tlsCondition(ctx, memcached)
+	if err != nil {
+		memcached.Status.SetCondition(tlsCondition)
+		return ctrl.Result{}, err
+	}
+	// Likewise, memcached exits when started with -S and no users, so keep
+	// the running pods until the users from spec.auth can be rendered
+	authCondition, err := r.reconcileAuth(ctx, log, memcached)
+	if err != nil {
+		memcached.Status.SetCondition(authCondition)
+		return ctrl.Result{}, err
+	}
+	res := memcached.Spec.ResourcesWithDefaults()
+	overhead := memoryOverheadPercent(res, memcached.Status.OOMOverheadPercent)
+	resources := resourcesForMemcached(config, res, overhead)
+
+	// Expose the pods. With a StatefulSet the Service also gives them their
+	// DNS names, so it goes first.
+	kind := workloadKind(memcached)
+	if err := r.reconcileServices(ctx, log, memcached, config.Port); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Limit how many pods voluntary disruptions may take down at once. The
+	// budget is relaxed while the instance is suspended.
+	if err := r.reconcilePDB(ctx, log, memcached); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Rotating a referenced Secret or ConfigMap rolls the pods, which only
+	// read them at startup
+	checksum, err := r.referencesChecksum(ctx, memcached)
+	if err != nil {
+		log.Error(err, "Failed to checksum referenced objects")
+		return ctrl.Result{}, err
+	}
+	template := podTemplateForMemcached(memcached, command, config.Port, resources)
+	applyChecksum(&template, checksum)
+
+	// Check if the workload already exists, if not create a new one
+	desired := r.workloadForMemcached(memcached, kind, template)
+	found, err := r.getWorkload(ctx, memcached, kind)
+	if err != nil {
+		log.Error(err, "Failed to get workload", "kind", kind)
+		return ctrl.Result{}, err
+	}
+	if found == nil {
+		// Create the workload defined above
+		log.Info("Creating a new workload", "kind", kind, "Namespace", memcached.Namespace, "Name", memcached.Name)
+		err = r.Create(ctx, desired.object())
 		if err != nil {
-			log.Error(err, "Failed to create new Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+			log.Error(err, "Failed to create new workload", "kind", kind, "Namespace", memcached.Namespace, "Name", memcached.Name)
 			return ctrl.Result{}, err
 		}
-		// Deployment created successfully - return and requeue
+		// Workload created successfully - return and requeue
 		return ctrl.Result{Requeue: true}, nil
-	} else if err != nil {
-		log.Error(err, "Failed to get Deployment")
+	}
+
+	// A suspended instance is scaled to zero and otherwise left alone
+	if isSuspended(memcached) {
+		return r.reconcileSuspended(ctx, log, memcached, found)
+	}
+
+	if err := r.reconcileHPA(ctx, log, memcached); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	// Ensure the deployment size is the same as the spec
-	size := memcached.Spec.Size
-	if *found.Spec.Replicas != size {
-		found.Spec.Replicas = &size
-		err = r.Update(ctx, found)
+	// Ensure the workload size is the same as the spec, or as it was before
+	// the instance was suspended
+	size := desiredReplicas(memcached)
+	if found.replicas() != size {
+		found.setReplicas(size)
+		err = r.Update(ctx, found.object())
 		if err != nil {
-			log.Error(err, "Failed to update Deployment", "Deployment.Namespace", found.Namespace, "Deployment.Name", found.Name)
+			log.Error(err, "Failed to update workload", "kind", kind, "Namespace", memcached.Namespace, "Name", memcached.Name)
 			return ctrl.Result{}, err
 		}
 		// Spec updated - return and requeue
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// Update the Memcached status with the pod names
-	// List the pods for this memcached's deployment
-	podList := &corev1.PodList{}
-	listOpts := []client.ListOption{
-		client.InNamespace(memcached.Namespace),
-		client.MatchingLabels(labelsForMemcached(memcached.Name)),
+	// Ensure the pod template matches the spec. Fields the API server defaults
+	// are ignored, so this only fires when the image or command changed. The
+	// rolling update strategy then replaces the pods one at a time.
+	if !found.specMatches(desired) {
+		log.Info("Rolling out new pod template", "kind", kind, "Namespace", memcached.Namespace, "Name", memcached.Name, "version", version.String())
+		found.copySpec(desired)
+		err = r.Update(ctx, found.object())
+		if err != nil {
+			log.Error(err, "Failed to update workload", "kind", kind, "Namespace", memcached.Namespace, "Name", memcached.Name)
+			return ctrl.Result{}, err
+		}
+		// Spec updated - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// When switching workload kinds, the old workload keeps serving until
+	// the new one is ready to take over
+	switching, err := r.retireWorkload(ctx, log, memcached, found)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
-	if err = r.List(ctx, podList, listOpts...); err != nil {
+
+	// Update the Memcached status with the pod names
+	// List the pods for this memcached's workloads
+	pods, err := r.listPods(ctx, memcached)
+	if err != nil {
 		log.Error(err, "Failed to list pods", "Memcached.Namespace", memcached.Namespace, "Memcached.Name", memcached.Name)
 		return ctrl.Result{}, err
 	}
-	podNames := getPodNames(podList.Items)
+	podNames := getPodNames(pods)
+
+	// Only report the new version once every pod runs it
+	runningVersion := memcached.Status.Version
+	if found.rolledOut() && !switching {
+		runningVersion = version.String()
+	}
 
-	// Update status.Nodes if needed
-	if !reflect.DeepEqual(podNames, memcached.Status.Nodes) {
-		memcached.Status.Nodes = podNames
+	// A memcached container that was OOMKilled since we last looked means the
+	// overhead is too small. Raise it, the next reconcile rolls out the new
+	// limits.
+	oomKills := memcached.Status.OOMKills
+	oomOverhead := memcached.Status.OOMOverheadPercent
+	lastOOMKillTime := memcached.Status.LastOOMKillTime
+	if last := lastOOMKill(pods); last != nil && (lastOOMKillTime == nil || lastOOMKillTime.Before(last)) {
+		oomKills++
+		lastOOMKillTime = last
+		if !res.DisableOOMCorrection && overhead < *res.MaxMemoryOverheadPercent {
+			oomOverhead += oomOverheadStep
+			raised := memoryOverheadPercent(res, oomOverhead)
+			log.Info("memcached was OOMKilled, raising memory overhead", "from", overhead, "to", raised)
+			r.Recorder.Eventf(memcached, corev1.EventTypeWarning, "OOMKilled",
+				"memcached was OOMKilled, raising memory overhead from %d%% to %d%%", overhead, raised)
+			overhead = raised
+		} else {
+			r.Recorder.Eventf(memcached, corev1.EventTypeWarning, "OOMKilled",
+				"memcached was OOMKilled, memory overhead stays at %d%%", overhead)
+		}
+	}
+
+	// Update the status if needed
+	status := memcached.Status.DeepCopy()
+	status.Nodes = podNames
+	status.Version = runningVersion
+	status.MemoryOverheadPercent = overhead
+	status.OOMOverheadPercent = oomOverhead
+	status.OOMKills = oomKills
+	status.LastOOMKillTime = lastOOMKillTime
+	status.ObservedGeneration = memcached.Generation
+	status.ReadyReplicas = found.status().ReadyReplicas
+	status.Replicas = found.status().Replicas
+	status.Selector = labels.SelectorFromSet(labelsForMemcached(memcached.Name)).String()
+	status.LastError = ""
+	if status.SuspendedReplicas != nil {
+		// The replicas sync above has restored the previous size
+		r.Recorder.Eventf(memcached, corev1.EventTypeNormal, "Resumed", "restored %d replicas", *status.SuspendedReplicas)
+		status.SuspendedReplicas = nil
+	}
+	cost, err := costForMemcached(memcached, size, resources)
+	if err != nil {
+		log.Info("Not reporting cost, spec.price is invalid", "error", err.Error())
+	}
+	status.Cost = cost
+	setWorkloadConditions(status, found, switching, memcached.Generation)
+	setSuspendedCondition(status, memcached.Generation)
+	status.SetCondition(tlsCondition)
+	status.SetCondition(authCondition)
+
+	// Poll the pods for their statistics, and come back when the next poll
+	// is due
+	nextStats := r.reconcileStats(ctx, log, memcached, status, pods, config.Port)
+	// Fresh statistics may call for a new size or memory
+	if err := r.reconcileScalingPolicy(ctx, log, memcached, status); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileSizing(ctx, log, memcached, status); err != nil {
+		return ctrl.Result{}, err
+	}
+	if !reflect.DeepEqual(*status, memcached.Status) {
+		memcached.Status = *status
 		err := r.Status().Update(ctx, memcached)
 		if err != nil {
 			log.Error(err, "Failed to update Memcached status")
@@ -123,55 +321,20 @@ func (r *MemcachedReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
-	/* If this object is suspended, we don't want to run any jobs, so we'll stop now.
-	This is useful if something's broken with the job we're running and we want to
-	pause runs to investigate or putz with the cluster, without deleting the object.
-	*/
-
-	if memcached.Spec.Suspend != nil && *memcached.Spec.Suspend {
-		log.V(1).Info("cronjob suspended, skipping")
-		return ctrl.Result{}, nil
-	}
-
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: nextStats}, nil
 }
 
-// deploymentForMemcached returns a memcached Deployment object
-func (r *MemcachedReconciler) deploymentForMemcached(m *cachev1alpha1.Memcached) *appsv1.Deployment {
-	ls := labelsForMemcached(m.Name)
-	replicas := m.Spec.Size
-
-	dep := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      m.Name,
-			Namespace: m.Namespace,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: ls,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: ls,
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{{
-						Image:   "memcached:1.4.36-alpine",
-						Name:    "memcached",
-						Command: []string{"memcached", "-m=64", "-o", "modern", "-v"},
-						Ports: []corev1.ContainerPort{{
-							ContainerPort: 11211,
-							Name:          "memcached",
-						}},
-					}},
-				},
-			},
-		},
-	}
-	// Set Memcached instance as the owner and controller
-	ctrl.SetControllerReference(m, dep, r.Scheme)
-	return dep
+// deploymentRolledOut reports whether the deployment controller has observed
+// the latest spec and every replica runs the latest pod template.
+func deploymentRolledOut(dep *appsv1.Deployment) bool {
+	if dep.Generation > dep.Status.ObservedGeneration {
+		return false
+	}
+	replicas := int32(1)
+	if dep.Spec.Replicas != nil {
+		replicas = *dep.Spec.Replicas
+	}
+	return dep.Status.UpdatedReplicas == replicas && dep.Status.Replicas == replicas
 }
 
 // labelsForMemcached returns the labels for selecting the resources
@@ -180,6 +343,19 @@ func labelsForMemcached(name string) map[string]string {
 	return map[string]string{"app": "memcached", "memcached_cr": name}
 }
 
+// listPods returns the memcached pods of m.
+func (r *MemcachedReconciler) listPods(ctx context.Context, m *cachev1alpha1.Memcached) ([]corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(m.Namespace),
+		client.MatchingLabels(labelsForMemcached(m.Name)),
+	}
+	if err := r.List(ctx, podList, listOpts...); err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
 // getPodNames returns the pod names of the array of pods passed in
 func getPodNames(pods []corev1.Pod) []string {
 	var podNames []string
@@ -189,9 +365,40 @@ func getPodNames(pods []corev1.Pod) []string {
 	return podNames
 }
 
+// memcachedForPod maps a memcached pod to the Memcached it belongs to.
+func memcachedForPod(o handler.MapObject) []reconcile.Request {
+	labels := o.Meta.GetLabels()
+	if labels["app"] != "memcached" || labels["memcached_cr"] == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: o.Meta.GetNamespace(),
+		Name:      labels["memcached_cr"],
+	}}}
+}
+
 func (r *MemcachedReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&cachev1alpha1.Memcached{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.Secret{}).
+		Owns(&policyv1beta1.PodDisruptionBudget{}).
+		Owns(&autoscalingv2beta2.HorizontalPodAutoscaler{}).
+		// Pods belong to the Deployment's ReplicaSets, not to us, so map them
+		// back to their Memcached through the memcached_cr label. This lets us
+		// notice OOM kills as they happen.
+		Watches(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(memcachedForPod),
+		}).
+		// Secrets and ConfigMaps referenced from the spec are not ours
+		// either, so map them to every Memcached referencing them.
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: r.memcachedsReferencing(secretKind),
+		}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: r.memcachedsReferencing(configMapKind),
+		}).
 		Complete(r)
 }
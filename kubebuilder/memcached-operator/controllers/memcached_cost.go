@@ -0,0 +1,46 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"math/big"
+
+	corev1 "k8s.io/api/core/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// costForMemcached returns the monthly cost of running replicas memcached
+// pods of m with the given resources, at the price per GB of memory in
+// spec.price. It returns nil when m has no price.
+func costForMemcached(m *cachev1alpha1.Memcached, replicas int32, resources corev1.ResourceRequirements) (*cachev1alpha1.MemcachedCost, error) {
+	price, currency, err := m.Spec.ParsedPrice()
+	if err != nil || price == nil {
+		return nil, err
+	}
+	memory := resources.Limits[corev1.ResourceMemory]
+	gb := new(big.Rat).SetFrac(big.NewInt(memory.Value()*int64(replicas)), big.NewInt(1e9))
+	return &cachev1alpha1.MemcachedCost{
+		MonthlyAmount: formatAmount(new(big.Rat).Mul(price, gb)),
+		Currency:      currency,
+		MemoryGB:      gb.FloatString(2),
+	}, nil
+}
+
+// formatAmount formats an amount of money with two decimal places.
+func formatAmount(amount *big.Rat) string {
+	return amount.FloatString(2)
+}
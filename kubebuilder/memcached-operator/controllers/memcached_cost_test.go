@@ -0,0 +1,119 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestCostForMemcached(t *testing.T) {
+	tests := []struct {
+		name     string
+		price    string
+		replicas int32
+		want     *cachev1alpha1.MemcachedCost
+		wantErr  bool
+	}{
+		{name: "no price"},
+		// 3 pods of 1GiB plus 25% overhead, 4.03GB
+		{name: "whole", price: "10 USD", replicas: 3, want: &cachev1alpha1.MemcachedCost{MonthlyAmount: "40.27", Currency: "USD", MemoryGB: "4.03"}},
+		{name: "decimal", price: "0.333 EUR", replicas: 3, want: &cachev1alpha1.MemcachedCost{MonthlyAmount: "1.34", Currency: "EUR", MemoryGB: "4.03"}},
+		{name: "suspended", price: "10 USD", replicas: 0, want: &cachev1alpha1.MemcachedCost{MonthlyAmount: "0.00", Currency: "USD", MemoryGB: "0.00"}},
+		{name: "invalid", price: "ten USD", replicas: 3, wantErr: true},
+		{name: "negative", price: "-5 USD", replicas: 3, wantErr: true},
+		{name: "signed", price: "+5 USD", replicas: 3, wantErr: true},
+		{name: "hexadecimal", price: "0x10 USD", replicas: 3, wantErr: true},
+		{name: "binary", price: "0b11 USD", replicas: 3, wantErr: true},
+		{name: "octal", price: "0o7 USD", replicas: 3, wantErr: true},
+		{name: "digit separators", price: "1_000 USD", replicas: 3, wantErr: true},
+		{name: "exponent", price: "1e3 USD", replicas: 3, wantErr: true},
+		{name: "fraction", price: "1/3 USD", replicas: 3, wantErr: true},
+		{name: "trailing point", price: "5. USD", replicas: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &cachev1alpha1.Memcached{Spec: cachev1alpha1.MemcachedSpec{
+				Price:  tt.price,
+				Config: &cachev1alpha1.MemcachedConfig{MemoryMB: 1024},
+			}}
+			res := m.Spec.ResourcesWithDefaults()
+			resources := resourcesForMemcached(m.Spec.ConfigWithDefaults(), res, *res.MemoryOverheadPercent)
+			got, err := costForMemcached(m, tt.replicas, resources)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("costForMemcached() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("costForMemcached() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateCosts(t *testing.T) {
+	memcached := func(namespace, team, amount, currency string) cachev1alpha1.Memcached {
+		m := cachev1alpha1.Memcached{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Labels: map[string]string{}}}
+		if team != "" {
+			m.Labels["team"] = team
+		}
+		if amount != "" {
+			m.Status.Cost = &cachev1alpha1.MemcachedCost{MonthlyAmount: amount, Currency: currency}
+		}
+		return m
+	}
+	memcacheds := []cachev1alpha1.Memcached{
+		memcached("shop", "checkout", "10.00", "USD"),
+		memcached("shop", "search", "20.00", "EUR"),
+		memcached("blog", "", "5.25", "USD"),
+		memcached("blog", "search", "1.00", "JPY"),
+		memcached("blog", "search", "", ""),
+	}
+	rates, err := parseRates(map[string]string{"EUR": "1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates["USD"] = big.NewRat(1, 1)
+
+	got := aggregateCosts(memcacheds, "USD", rates, "team")
+	want := cachev1alpha1.MemcachedCostReportStatus{
+		Currency:     "USD",
+		MonthlyTotal: "37.25",
+		Instances:    3,
+		Namespaces: []cachev1alpha1.MemcachedCostEntry{
+			{Name: "blog", MonthlyAmount: "5.25", Instances: 1},
+			{Name: "shop", MonthlyAmount: "32.00", Instances: 2},
+		},
+		Teams: []cachev1alpha1.MemcachedCostEntry{
+			{Name: "checkout", MonthlyAmount: "10.00", Instances: 1},
+			{Name: "search", MonthlyAmount: "22.00", Instances: 1},
+		},
+		UnconvertedCurrencies: []string{"JPY"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("aggregateCosts() = %+v, want %+v", got, want)
+	}
+
+	for _, rate := range []string{"", "0", "-1", "+1", "1/3", "1e3", "0x10", "0b11", "0o7", "1_000", "abc"} {
+		if _, err := parseRates(map[string]string{"EUR": rate}); err == nil {
+			t.Errorf("rate %q accepted", rate)
+		}
+	}
+}
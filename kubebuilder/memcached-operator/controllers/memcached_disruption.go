@@ -0,0 +1,87 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// reconcilePDB keeps the PodDisruptionBudget of m in line with its size and
+// spec.disruption.
+func (r *MemcachedReconciler) reconcilePDB(ctx context.Context, log logr.Logger, m *cachev1alpha1.Memcached) error {
+	pdb := r.pdbForMemcached(m)
+	found := &policyv1beta1.PodDisruptionBudget{}
+	err := r.Get(ctx, types.NamespacedName{Name: pdb.Name, Namespace: pdb.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new PodDisruptionBudget", "PodDisruptionBudget.Namespace", pdb.Namespace, "PodDisruptionBudget.Name", pdb.Name)
+		if err := r.Create(ctx, pdb); err != nil {
+			log.Error(err, "Failed to create new PodDisruptionBudget")
+			return err
+		}
+		return nil
+	} else if err != nil {
+		log.Error(err, "Failed to get PodDisruptionBudget")
+		return err
+	}
+
+	if equality.Semantic.DeepDerivative(pdb.Spec, found.Spec) {
+		return nil
+	}
+	log.Info("Updating PodDisruptionBudget", "PodDisruptionBudget.Namespace", found.Namespace, "PodDisruptionBudget.Name", found.Name,
+		"maxUnavailable", pdb.Spec.MaxUnavailable.String())
+	found.Spec = pdb.Spec
+	if err := r.Update(ctx, found); err != nil {
+		log.Error(err, "Failed to update PodDisruptionBudget")
+		return err
+	}
+	return nil
+}
+
+// pdbForMemcached returns the PodDisruptionBudget of m. A suspended instance
+// has no pods worth protecting, so its budget lets drains through.
+func (r *MemcachedReconciler) pdbForMemcached(m *cachev1alpha1.Memcached) *policyv1beta1.PodDisruptionBudget {
+	maxUnavailable := m.Spec.MaxUnavailable()
+	if isSuspended(m) {
+		maxUnavailable = intstr.FromString("100%")
+	}
+	pdb := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name,
+			Namespace: m.Namespace,
+			Labels:    labelsForMemcached(m.Name),
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labelsForMemcached(m.Name),
+			},
+		},
+	}
+	// Set Memcached instance as the owner and controller
+	ctrl.SetControllerReference(m, pdb, r.Scheme)
+	return pdb
+}
@@ -0,0 +1,53 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestPDBForMemcached(t *testing.T) {
+	r := newTestReconciler()
+	yes := true
+	half := intstr.FromString("50%")
+
+	tests := []struct {
+		name       string
+		size       int32
+		suspend    *bool
+		disruption *cachev1alpha1.MemcachedDisruption
+		want       string
+	}{
+		{name: "small", size: 3, want: "1"},
+		{name: "large", size: 13, want: "3"},
+		{name: "explicit", size: 3, disruption: &cachev1alpha1.MemcachedDisruption{MaxUnavailable: &half}, want: "50%"},
+		{name: "suspended", size: 3, suspend: &yes, want: "100%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMemcached()
+			m.Spec = cachev1alpha1.MemcachedSpec{Size: tt.size, Suspend: tt.suspend, Disruption: tt.disruption}
+			pdb := r.pdbForMemcached(m)
+			if got := pdb.Spec.MaxUnavailable.String(); got != tt.want {
+				t.Errorf("maxUnavailable = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// memcachedFinalizer keeps a deleted Memcached around until the controller
+// has torn down what it created for it.
+const memcachedFinalizer = "cache.example.com/teardown"
+
+// teardownPollInterval is how often teardown checks whether the children it
+// deleted are gone.
+const teardownPollInterval = 2 * time.Second
+
+// hasFinalizer reports whether m carries the teardown finalizer.
+func hasFinalizer(m *cachev1alpha1.Memcached) bool {
+	for _, f := range m.Finalizers {
+		if f == memcachedFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// deletionPolicy returns the deletion policy of m, defaulting to Delete.
+func deletionPolicy(m *cachev1alpha1.Memcached) cachev1alpha1.DeletionPolicy {
+	if m.Spec.DeletionPolicy == "" {
+		return cachev1alpha1.DeletionPolicyDelete
+	}
+	return m.Spec.DeletionPolicy
+}
+
+// ensureFinalizer adds the teardown finalizer to m if it is missing.
+func (r *MemcachedReconciler) ensureFinalizer(ctx context.Context, m *cachev1alpha1.Memcached) error {
+	if hasFinalizer(m) {
+		return nil
+	}
+	controllerutil.AddFinalizer(m, memcachedFinalizer)
+	return r.Update(ctx, m)
+}
+
+// publishedEndpoints returns the objects through which clients find the
+// memcached pods of m: its Service and any per-pod Services.
+func (r *MemcachedReconciler) publishedEndpoints(ctx context.Context, m *cachev1alpha1.Memcached) ([]runtime.Object, error) {
+	objs := []runtime.Object{
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: serviceName(m), Namespace: m.Namespace}},
+	}
+	perPod, err := r.listPerPodServices(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	for i := range perPod {
+		objs = append(objs, &perPod[i])
+	}
+	return objs, nil
+}
+
+// workloads returns the objects that run the memcached pods of m, the
+// PodDisruptionBudget that protects them and the SASL Secret they mount.
+func workloads(m *cachev1alpha1.Memcached) []runtime.Object {
+	return []runtime.Object{
+		&policyv1beta1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: m.Name, Namespace: m.Namespace}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: saslSecretName(m), Namespace: m.Namespace}},
+		emptyWorkload(m, cachev1alpha1.WorkloadDeployment).object(),
+		emptyWorkload(m, cachev1alpha1.WorkloadStatefulSet).object(),
+	}
+}
+
+// reconcileDelete tears down a deleted Memcached in order: it announces
+// termination and stops autoscaling, removes the published endpoints, then
+// deletes or orphans the workloads according to spec.deletionPolicy. The
+// finalizer is only removed once all of that is done.
+//
+// Announcing termination sets the Terminating condition and records an
+// Event; it does not wait for clients to move off the instance.
+func (r *MemcachedReconciler) reconcileDelete(ctx context.Context, log logr.Logger, m *cachev1alpha1.Memcached) (ctrl.Result, error) {
+	if !hasFinalizer(m) {
+		return ctrl.Result{}, nil
+	}
+	policy := deletionPolicy(m)
+
+	// Announce termination before anything disappears
+	if !m.Status.IsConditionTrue(cachev1alpha1.MemcachedTerminating) {
+		log.Info("Tearing down Memcached", "deletionPolicy", policy)
+		m.Status.SetCondition(cachev1alpha1.MemcachedCondition{
+			Type:               cachev1alpha1.MemcachedTerminating,
+			Status:             corev1.ConditionTrue,
+			ObservedGeneration: m.Generation,
+			Reason:             ReasonTearingDown,
+			Message:            fmt.Sprintf("deletion policy is %s", policy),
+		})
+		if err := r.Status().Update(ctx, m); err != nil {
+			log.Error(err, "Failed to update Memcached status")
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "Terminating", "tearing down, deletion policy is %s", policy)
+	}
+
+	// Nothing may scale the instance while it goes away
+	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: m.Name, Namespace: m.Namespace}}
+	if pending, err := r.deleteOwned(ctx, m, hpa); err != nil || pending {
+		return ctrl.Result{RequeueAfter: teardownPollInterval}, err
+	}
+
+	endpoints, err := r.publishedEndpoints(ctx, m)
+	if err != nil {
+		log.Error(err, "Failed to list Services")
+		return ctrl.Result{}, err
+	}
+	steps := [][]runtime.Object{endpoints, workloads(m)}
+	for _, objs := range steps {
+		pending := false
+		for _, obj := range objs {
+			var err error
+			if policy == cachev1alpha1.DeletionPolicyOrphan {
+				err = r.orphanOwned(ctx, m, obj)
+			} else {
+				var gone bool
+				gone, err = r.deleteOwned(ctx, m, obj)
+				pending = pending || gone
+			}
+			if err != nil {
+				log.Error(err, "Failed to tear down Memcached")
+				return ctrl.Result{}, err
+			}
+		}
+		// Finish one step before starting the next
+		if pending {
+			return ctrl.Result{RequeueAfter: teardownPollInterval}, nil
+		}
+	}
+
+	log.Info("Teardown complete, removing finalizer")
+	deleteStatsMetrics(m)
+	controllerutil.RemoveFinalizer(m, memcachedFinalizer)
+	if err := r.Update(ctx, m); err != nil {
+		log.Error(err, "Failed to remove finalizer")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// deleteOwned deletes obj if m controls it. Dependents are deleted first, so
+// obj only disappears once its pods are gone. It reports whether obj still
+// exists.
+func (r *MemcachedReconciler) deleteOwned(ctx context.Context, m *cachev1alpha1.Memcached, obj runtime.Object) (bool, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	key := types.NamespacedName{Name: accessor.GetName(), Namespace: accessor.GetNamespace()}
+	if err := r.Get(ctx, key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !metav1.IsControlledBy(accessor, m) {
+		return false, nil
+	}
+	if accessor.GetDeletionTimestamp() == nil {
+		err := r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationForeground))
+		if err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// orphanOwned removes the owner reference of m from obj, so that the garbage
+// collector leaves obj alone once m is gone.
+func (r *MemcachedReconciler) orphanOwned(ctx context.Context, m *cachev1alpha1.Memcached, obj runtime.Object) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	key := types.NamespacedName{Name: accessor.GetName(), Namespace: accessor.GetNamespace()}
+	if err := r.Get(ctx, key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	refs := accessor.GetOwnerReferences()
+	kept := refs[:0]
+	for _, ref := range refs {
+		if ref.UID != m.UID {
+			kept = append(kept, ref)
+		}
+	}
+	if len(kept) == len(refs) {
+		return nil
+	}
+	accessor.SetOwnerReferences(kept)
+	return r.Update(ctx, obj)
+}
@@ -0,0 +1,84 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestReconcileDelete(t *testing.T) {
+	for _, policy := range []cachev1alpha1.DeletionPolicy{cachev1alpha1.DeletionPolicyDelete, cachev1alpha1.DeletionPolicyOrphan} {
+		t.Run(string(policy), func(t *testing.T) {
+			now := metav1.Now()
+			m := testMemcached()
+			m.DeletionTimestamp = &now
+			m.Finalizers = []string{memcachedFinalizer}
+			m.Spec = cachev1alpha1.MemcachedSpec{Size: 1, DeletionPolicy: policy}
+			key := keyOf(m)
+			dep := newTestReconciler().deploymentForMemcached(m, podTemplateForMemcached(m, nil, cachev1alpha1.DefaultPort, corev1.ResourceRequirements{}))
+			r := newTestReconciler(m, dep)
+
+			// Teardown may take a few passes, one step at a time.
+			for i := 0; i < 5; i++ {
+				got := &cachev1alpha1.Memcached{}
+				if err := r.Get(context.TODO(), key, got); err != nil {
+					t.Fatal(err)
+				}
+				if !hasFinalizer(got) {
+					break
+				}
+				if _, err := r.reconcileDelete(context.TODO(), r.Log, got); err != nil {
+					t.Fatalf("reconcileDelete: %v", err)
+				}
+			}
+
+			got := &cachev1alpha1.Memcached{}
+			if err := r.Get(context.TODO(), key, got); err != nil {
+				t.Fatal(err)
+			}
+			if hasFinalizer(got) {
+				t.Fatalf("finalizer was not removed")
+			}
+			if !got.Status.IsConditionTrue(cachev1alpha1.MemcachedTerminating) {
+				t.Errorf("Terminating condition was not set")
+			}
+
+			found := &appsv1.Deployment{}
+			err := r.Get(context.TODO(), key, found)
+			switch policy {
+			case cachev1alpha1.DeletionPolicyDelete:
+				if !errors.IsNotFound(err) {
+					t.Errorf("Deployment was not deleted: %v", err)
+				}
+			case cachev1alpha1.DeletionPolicyOrphan:
+				if err != nil {
+					t.Fatalf("Deployment was deleted: %v", err)
+				}
+				if len(found.OwnerReferences) != 0 {
+					t.Errorf("Deployment is still owned: %v", found.OwnerReferences)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// metricsNamespace prefixes every operator series so that it does not collide
+// with the series memcached_exporter serves for the same pods.
+const metricsNamespace = "memcached_operator"
+
+// Per-instance series derived from the statistics of the memcached pods.
+// They are served with the controller-runtime metrics.
+var (
+	statsLabels = []string{"namespace", "memcached"}
+
+	statsPods = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "stats_pods",
+		Help:      "Number of memcached pods that answered the last stats poll.",
+	}, statsLabels)
+	statsHitRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "hit_ratio",
+		Help:      "Share of gets that found their item.",
+	}, statsLabels)
+	statsEvictions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "evictions",
+		Help:      "Items evicted to make room for new ones since the pods started.",
+	}, statsLabels)
+	statsBytesUsed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "bytes_used",
+		Help:      "Memory used to store items.",
+	}, statsLabels)
+	statsLimitBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "limit_bytes",
+		Help:      "Memory the pods may use to store items.",
+	}, statsLabels)
+	statsMallocedBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "malloced_bytes",
+		Help:      "Memory allocated to slabs.",
+	}, statsLabels)
+	statsConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "current_connections",
+		Help:      "Open client connections.",
+	}, statsLabels)
+	statsErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "stats_errors_total",
+		Help:      "Stats polls of a memcached pod that failed.",
+	}, statsLabels)
+)
+
+func init() {
+	metrics.Registry.MustRegister(statsPods, statsHitRatio, statsEvictions, statsBytesUsed,
+		statsLimitBytes, statsMallocedBytes, statsConnections, statsErrors)
+}
+
+// recordStatsMetrics sets the series of m from stats.
+func recordStatsMetrics(m *cachev1alpha1.Memcached, stats *cachev1alpha1.MemcachedStats) {
+	labels := prometheus.Labels{"namespace": m.Namespace, "memcached": m.Name}
+	statsPods.With(labels).Set(float64(stats.Pods))
+	if gets := stats.GetHits + stats.GetMisses; gets > 0 {
+		statsHitRatio.With(labels).Set(float64(stats.GetHits) / float64(gets))
+	}
+	statsEvictions.With(labels).Set(float64(stats.Evictions))
+	statsBytesUsed.With(labels).Set(float64(stats.BytesUsed))
+	statsLimitBytes.With(labels).Set(float64(stats.LimitMaxBytes))
+	statsMallocedBytes.With(labels).Set(float64(stats.MallocedBytes))
+	statsConnections.With(labels).Set(float64(stats.CurrentConnections))
+}
+
+// deleteStatsMetrics drops the series of m, once it is gone or no longer
+// polled.
+func deleteStatsMetrics(m *cachev1alpha1.Memcached) {
+	labels := prometheus.Labels{"namespace": m.Namespace, "memcached": m.Name}
+	for _, vec := range []*prometheus.GaugeVec{statsPods, statsHitRatio, statsEvictions, statsBytesUsed,
+		statsLimitBytes, statsMallocedBytes, statsConnections} {
+		vec.Delete(labels)
+	}
+	statsErrors.Delete(labels)
+}
@@ -0,0 +1,149 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// checksumAnnotation is set on the pod template to a checksum of the
+// contents of every object the pods read. Changing any of them changes the
+// template, which rolls the pods.
+const checksumAnnotation = "cache.example.com/config-checksum"
+
+// Kinds of the objects a Memcached may reference.
+const (
+	secretKind    = "Secret"
+	configMapKind = "ConfigMap"
+)
+
+// objectReference names a Secret or ConfigMap in the namespace of a
+// Memcached.
+type objectReference struct {
+	kind string
+	name string
+}
+
+// references returns the objects m reads its pod configuration from, in a
+// stable order.
+func references(m *cachev1alpha1.Memcached) []objectReference {
+	var refs []objectReference
+	if tls := m.Spec.TLS; tls != nil {
+		refs = append(refs, objectReference{secretKind, tls.SecretName})
+		if tls.ClientCASecretName != "" {
+			refs = append(refs, objectReference{secretKind, tls.ClientCASecretName})
+		}
+	}
+	if auth := m.Spec.Auth; auth != nil {
+		refs = append(refs, objectReference{secretKind, auth.SecretName})
+	}
+	return refs
+}
+
+// referencesChecksum returns a checksum of the contents of every object
+// referenced by m, or "" if it references none.
+func (r *MemcachedReconciler) referencesChecksum(ctx context.Context, m *cachev1alpha1.Memcached) (string, error) {
+	refs := references(m)
+	if len(refs) == 0 {
+		return "", nil
+	}
+	h := sha256.New()
+	for _, ref := range refs {
+		key := types.NamespacedName{Name: ref.name, Namespace: m.Namespace}
+		var data map[string][]byte
+		switch ref.kind {
+		case secretKind:
+			secret := &corev1.Secret{}
+			if err := r.Get(ctx, key, secret); err != nil {
+				return "", err
+			}
+			data = secret.Data
+		case configMapKind:
+			cm := &corev1.ConfigMap{}
+			if err := r.Get(ctx, key, cm); err != nil {
+				return "", err
+			}
+			data = map[string][]byte{}
+			for k, v := range cm.BinaryData {
+				data[k] = v
+			}
+			for k, v := range cm.Data {
+				data[k] = []byte(v)
+			}
+		}
+		fmt.Fprintf(h, "%s/%s\n", ref.kind, ref.name)
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		// Length prefixes keep different contents from hashing alike
+		for _, k := range keys {
+			fmt.Fprintf(h, "%d:%s%d:", len(k), k, len(data[k]))
+			h.Write(data[k])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// applyChecksum stamps the checksum of the referenced objects on template.
+func applyChecksum(template *corev1.PodTemplateSpec, checksum string) {
+	if checksum == "" {
+		return
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[checksumAnnotation] = checksum
+}
+
+// memcachedsReferencing returns a map function that maps a Secret or
+// ConfigMap to every Memcached in its namespace that references it.
+func (r *MemcachedReconciler) memcachedsReferencing(kind string) handler.ToRequestsFunc {
+	return func(o handler.MapObject) []reconcile.Request {
+		list := &cachev1alpha1.MemcachedList{}
+		if err := r.List(context.Background(), list, client.InNamespace(o.Meta.GetNamespace())); err != nil {
+			r.Log.Error(err, "Failed to list Memcacheds", "kind", kind, "Namespace", o.Meta.GetNamespace(), "Name", o.Meta.GetName())
+			return nil
+		}
+		var requests []reconcile.Request
+		for i := range list.Items {
+			m := &list.Items[i]
+			for _, ref := range references(m) {
+				if ref.kind == kind && ref.name == o.Meta.GetName() {
+					requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+						Namespace: m.Namespace,
+						Name:      m.Name,
+					}})
+					break
+				}
+			}
+		}
+		return requests
+	}
+}
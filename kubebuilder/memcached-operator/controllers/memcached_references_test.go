@@ -0,0 +1,81 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestReferences(t *testing.T) {
+	withTLS := testMemcached()
+	withTLS.Name = "tls"
+	withTLS.Spec.TLS = &cachev1alpha1.MemcachedTLS{SecretName: "cert"}
+	withAuth := testMemcached()
+	withAuth.Name = "auth"
+	withAuth.Spec.Auth = &cachev1alpha1.MemcachedAuth{SecretName: "users"}
+	plain := testMemcached()
+	plain.Name = "plain"
+	otherNamespace := withTLS.DeepCopy()
+	otherNamespace.Namespace = "other"
+	cert := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cert", Namespace: "ns"},
+		Data:       map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key")},
+	}
+	r := newTestReconciler(withTLS, withAuth, plain, otherNamespace, cert)
+	ctx := context.TODO()
+
+	// A Secret maps to the Memcacheds in its namespace that reference it
+	requests := r.memcachedsReferencing(secretKind)(handler.MapObject{Meta: cert, Object: cert})
+	if len(requests) != 1 || requests[0].Name != "tls" || requests[0].Namespace != "ns" {
+		t.Errorf("requests = %v, want ns/tls", requests)
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cert", Namespace: "ns"}}
+	if requests := r.memcachedsReferencing(configMapKind)(handler.MapObject{Meta: cm, Object: cm}); len(requests) != 0 {
+		t.Errorf("ConfigMap named like a Secret mapped to %v", requests)
+	}
+
+	// The checksum follows the contents of the referenced Secrets
+	if sum, err := r.referencesChecksum(ctx, plain); err != nil || sum != "" {
+		t.Errorf("checksum without references = %q, %v", sum, err)
+	}
+	before, err := r.referencesChecksum(ctx, withTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.Data[corev1.TLSCertKey] = []byte("rotated")
+	if err := r.Update(ctx, cert); err != nil {
+		t.Fatal(err)
+	}
+	after, err := r.referencesChecksum(ctx, withTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Errorf("checksum %s did not change with the certificate", before)
+	}
+	template := corev1.PodTemplateSpec{}
+	applyChecksum(&template, after)
+	if template.Annotations[checksumAnnotation] != after {
+		t.Errorf("annotations = %v, want checksum %s", template.Annotations, after)
+	}
+}
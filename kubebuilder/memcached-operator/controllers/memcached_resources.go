@@ -0,0 +1,78 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// oomOverheadStep is how many percentage points the memory overhead is
+// raised by every time a memcached container is OOMKilled.
+const oomOverheadStep = 25
+
+// memoryOverheadPercent returns the overhead to apply: the configured one
+// plus whatever OOM correction added, capped at the configured maximum.
+func memoryOverheadPercent(res cachev1alpha1.MemcachedResources, oomOverhead int32) int32 {
+	overhead := *res.MemoryOverheadPercent + oomOverhead
+	if overhead > *res.MaxMemoryOverheadPercent {
+		overhead = *res.MaxMemoryOverheadPercent
+	}
+	return overhead
+}
+
+// resourcesForMemcached returns the memcached container resources. Memory
+// requests equal limits so that the cache is never squeezed by its neighbours.
+func resourcesForMemcached(config cachev1alpha1.MemcachedConfig, res cachev1alpha1.MemcachedResources, overheadPercent int32) corev1.ResourceRequirements {
+	memoryMB := int64(config.MemoryMB) * int64(100+overheadPercent) / 100
+	memory := resource.NewQuantity(memoryMB*1024*1024, resource.BinarySI)
+
+	req := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: *memory},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: *memory},
+	}
+	if res.CPU != nil {
+		req.Requests[corev1.ResourceCPU] = *res.CPU
+	}
+	return req
+}
+
+// lastOOMKill returns when the most recent OOM kill of a memcached container
+// in pods finished, or nil if none was OOMKilled.
+func lastOOMKill(pods []corev1.Pod) *metav1.Time {
+	var last *metav1.Time
+	for i := range pods {
+		for _, cs := range pods[i].Status.ContainerStatuses {
+			if cs.Name != "memcached" {
+				continue
+			}
+			for _, state := range []corev1.ContainerState{cs.State, cs.LastTerminationState} {
+				t := state.Terminated
+				if t == nil || t.Reason != "OOMKilled" {
+					continue
+				}
+				if last == nil || last.Before(&t.FinishedAt) {
+					finished := t.FinishedAt
+					last = &finished
+				}
+			}
+		}
+	}
+	return last
+}
@@ -0,0 +1,83 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// zoneTopologyKey is the node label holding the zone of a node.
+const zoneTopologyKey = "topology.kubernetes.io/zone"
+
+// Weights of the default anti-affinity terms. Losing a zone costs more
+// shards than losing a node, but a node is the likelier failure, so hosts
+// come first.
+const (
+	hostAntiAffinityWeight = 100
+	zoneAntiAffinityWeight = 50
+)
+
+// applyScheduling sets the scheduling fields of a memcached pod spec from
+// spec.scheduling, adding the default spreading unless the spec has its own.
+func applyScheduling(pod *corev1.PodSpec, m *cachev1alpha1.Memcached) {
+	var s cachev1alpha1.MemcachedScheduling
+	if m.Spec.Scheduling != nil {
+		m.Spec.Scheduling.DeepCopyInto(&s)
+	}
+	pod.NodeSelector = s.NodeSelector
+	pod.Tolerations = s.Tolerations
+	pod.PriorityClassName = s.PriorityClassName
+	pod.TopologySpreadConstraints = s.TopologySpreadConstraints
+	pod.Affinity = s.Affinity
+
+	if len(s.TopologySpreadConstraints) > 0 {
+		return
+	}
+	if pod.Affinity == nil {
+		pod.Affinity = &corev1.Affinity{}
+	}
+	if pod.Affinity.PodAntiAffinity == nil {
+		pod.Affinity.PodAntiAffinity = defaultAntiAffinity(m)
+	}
+}
+
+// defaultAntiAffinity prefers placing the pods of m on different hosts and
+// zones. The preference is soft, so that small clusters can still run every
+// replica.
+func defaultAntiAffinity(m *cachev1alpha1.Memcached) *corev1.PodAntiAffinity {
+	selector := &metav1.LabelSelector{MatchLabels: labelsForMemcached(m.Name)}
+	return &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+			{
+				Weight: hostAntiAffinityWeight,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: selector,
+					TopologyKey:   corev1.LabelHostname,
+				},
+			},
+			{
+				Weight: zoneAntiAffinityWeight,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: selector,
+					TopologyKey:   zoneTopologyKey,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,91 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestApplyScheduling(t *testing.T) {
+	nodeAffinity := &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{
+					Key:      "pool",
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"cache"},
+				}},
+			}},
+		},
+	}
+	spread := []corev1.TopologySpreadConstraint{{
+		MaxSkew:           1,
+		TopologyKey:       zoneTopologyKey,
+		WhenUnsatisfiable: corev1.DoNotSchedule,
+	}}
+
+	tests := []struct {
+		name             string
+		scheduling       *cachev1alpha1.MemcachedScheduling
+		wantAntiAffinity bool
+	}{
+		{name: "defaults", wantAntiAffinity: true},
+		{
+			name:             "node affinity keeps the default spreading",
+			scheduling:       &cachev1alpha1.MemcachedScheduling{Affinity: &corev1.Affinity{NodeAffinity: nodeAffinity}},
+			wantAntiAffinity: true,
+		},
+		{
+			name:       "topology spread replaces the default spreading",
+			scheduling: &cachev1alpha1.MemcachedScheduling{TopologySpreadConstraints: spread},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &cachev1alpha1.Memcached{
+				ObjectMeta: metav1.ObjectMeta{Name: "cache"},
+				Spec:       cachev1alpha1.MemcachedSpec{Scheduling: tt.scheduling},
+			}
+			pod := &corev1.PodSpec{}
+			applyScheduling(pod, m)
+
+			hasAntiAffinity := pod.Affinity != nil && pod.Affinity.PodAntiAffinity != nil
+			if hasAntiAffinity != tt.wantAntiAffinity {
+				t.Fatalf("pod anti-affinity = %v, want %v", hasAntiAffinity, tt.wantAntiAffinity)
+			}
+			if hasAntiAffinity {
+				terms := pod.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+				if len(terms) != 2 || terms[0].PodAffinityTerm.TopologyKey != corev1.LabelHostname ||
+					terms[1].PodAffinityTerm.TopologyKey != zoneTopologyKey {
+					t.Errorf("anti-affinity terms = %+v, want host and zone", terms)
+				}
+			}
+			if tt.scheduling != nil && tt.scheduling.Affinity != nil {
+				if pod.Affinity.NodeAffinity == nil {
+					t.Error("node affinity was dropped")
+				}
+				if tt.scheduling.Affinity.PodAntiAffinity != nil {
+					t.Error("spec.scheduling.affinity was modified")
+				}
+			}
+		})
+	}
+}
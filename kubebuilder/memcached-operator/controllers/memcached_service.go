@@ -0,0 +1,257 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// perPodServiceLabel marks the Services that select a single pod.
+const perPodServiceLabel = "memcached_per_pod"
+
+// statefulSetPodNameLabel is set on every StatefulSet pod by the StatefulSet
+// controller.
+const statefulSetPodNameLabel = "statefulset.kubernetes.io/pod-name"
+
+// serviceName returns the name of the Service of m. With a StatefulSet it
+// also governs the pods, which makes them resolvable as
+// <name>-<ordinal>.<name>.
+func serviceName(m *cachev1alpha1.Memcached) string {
+	return m.Name
+}
+
+// serviceForMemcached returns the Service named after m.
+func (r *MemcachedReconciler) serviceForMemcached(m *cachev1alpha1.Memcached, spec cachev1alpha1.MemcachedService, port int32) *corev1.Service {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        serviceName(m),
+			Namespace:   m.Namespace,
+			Labels:      labelsForMemcached(m.Name),
+			Annotations: spec.Annotations,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: labelsForMemcached(m.Name),
+			Ports:    servicePorts(port),
+		},
+	}
+	if spec.Type == cachev1alpha1.ServiceHeadless {
+		svc.Spec.Type = corev1.ServiceTypeClusterIP
+		svc.Spec.ClusterIP = corev1.ClusterIPNone
+		// Consistent-hashing clients keep a shard for every pod, ready or
+		// not, so every pod keeps its DNS name.
+		svc.Spec.PublishNotReadyAddresses = true
+	} else {
+		svc.Spec.Type = corev1.ServiceType(spec.Type)
+	}
+	// Set Memcached instance as the owner and controller
+	ctrl.SetControllerReference(m, svc, r.Scheme)
+	return svc
+}
+
+// perPodServiceForMemcached returns a Service that only selects the pod with
+// the given name.
+func (r *MemcachedReconciler) perPodServiceForMemcached(m *cachev1alpha1.Memcached, spec cachev1alpha1.MemcachedService, pod string, port int32) *corev1.Service {
+	ls := labelsForMemcached(m.Name)
+	ls[perPodServiceLabel] = "true"
+	selector := labelsForMemcached(m.Name)
+	selector[statefulSetPodNameLabel] = pod
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pod,
+			Namespace:   m.Namespace,
+			Labels:      ls,
+			Annotations: spec.Annotations,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceType(spec.PerPodType),
+			Selector: selector,
+			Ports:    servicePorts(port),
+		},
+	}
+	// Set Memcached instance as the owner and controller
+	ctrl.SetControllerReference(m, svc, r.Scheme)
+	return svc
+}
+
+// servicePorts returns the ports of a memcached Service.
+func servicePorts(port int32) []corev1.ServicePort {
+	return []corev1.ServicePort{{
+		Name:       "memcached",
+		Port:       port,
+		TargetPort: intstr.FromString("memcached"),
+	}}
+}
+
+// reconcileServices keeps the Service of m, and its per-pod Services if
+// asked for, in line with spec.service.
+func (r *MemcachedReconciler) reconcileServices(ctx context.Context, log logr.Logger, m *cachev1alpha1.Memcached, port int32) error {
+	spec := m.Spec.ServiceWithDefaults()
+	if err := r.reconcileService(ctx, log, m, r.serviceForMemcached(m, spec, port)); err != nil {
+		return err
+	}
+
+	// StatefulSet pods are named <name>-<ordinal>, so the per-pod Services
+	// can be created ahead of the pods.
+	wanted := map[string]bool{}
+	if spec.PerPod && workloadKind(m) == cachev1alpha1.WorkloadStatefulSet {
+		for i := int32(0); i < desiredReplicas(m); i++ {
+			pod := fmt.Sprintf("%s-%d", m.Name, i)
+			wanted[pod] = true
+			if err := r.reconcileService(ctx, log, m, r.perPodServiceForMemcached(m, spec, pod, port)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Remove the per-pod Services of pods that are gone
+	perPod, err := r.listPerPodServices(ctx, m)
+	if err != nil {
+		log.Error(err, "Failed to list Services")
+		return err
+	}
+	for i := range perPod {
+		svc := &perPod[i]
+		if wanted[svc.Name] || !metav1.IsControlledBy(svc, m) {
+			continue
+		}
+		log.Info("Deleting per-pod Service", "Service.Namespace", svc.Namespace, "Service.Name", svc.Name)
+		if err := r.Delete(ctx, svc); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete Service")
+			return err
+		}
+	}
+	return nil
+}
+
+// listPerPodServices returns the per-pod Services of m.
+func (r *MemcachedReconciler) listPerPodServices(ctx context.Context, m *cachev1alpha1.Memcached) ([]corev1.Service, error) {
+	ls := labelsForMemcached(m.Name)
+	ls[perPodServiceLabel] = "true"
+	list := &corev1.ServiceList{}
+	if err := r.List(ctx, list, client.InNamespace(m.Namespace), client.MatchingLabels(ls)); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// reconcileService creates svc, or brings the existing Service with its name
+// in line with it.
+func (r *MemcachedReconciler) reconcileService(ctx context.Context, log logr.Logger, m *cachev1alpha1.Memcached, svc *corev1.Service) error {
+	found := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new Service", "Service.Namespace", svc.Namespace, "Service.Name", svc.Name, "type", svc.Spec.Type)
+		if err := r.Create(ctx, svc); err != nil {
+			log.Error(err, "Failed to create new Service", "Service.Namespace", svc.Namespace, "Service.Name", svc.Name)
+			return err
+		}
+		return nil
+	} else if err != nil {
+		log.Error(err, "Failed to get Service")
+		return err
+	}
+	if !metav1.IsControlledBy(found, m) {
+		return fmt.Errorf("Service %s/%s exists and is not owned by this Memcached", found.Namespace, found.Name)
+	}
+
+	// The cluster IP of a Service cannot change, so moving to or from
+	// headless means replacing it. Deleting it brings us back here through
+	// the Service watch, and the next pass creates the new one.
+	if (found.Spec.ClusterIP == corev1.ClusterIPNone) != (svc.Spec.ClusterIP == corev1.ClusterIPNone) {
+		log.Info("Replacing Service", "Service.Namespace", found.Namespace, "Service.Name", found.Name)
+		if err := r.Delete(ctx, found); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete Service")
+			return err
+		}
+		return nil
+	}
+
+	annotationsMatch := true
+	for k, v := range svc.Annotations {
+		if found.Annotations[k] != v {
+			annotationsMatch = false
+		}
+	}
+	if annotationsMatch && found.Spec.Type == svc.Spec.Type &&
+		found.Spec.PublishNotReadyAddresses == svc.Spec.PublishNotReadyAddresses &&
+		equality.Semantic.DeepDerivative(svc.Spec.Selector, found.Spec.Selector) &&
+		equality.Semantic.DeepDerivative(svc.Spec.Ports, found.Spec.Ports) {
+		return nil
+	}
+
+	// Annotations set by others, for example cloud controllers, are kept
+	for k, v := range svc.Annotations {
+		if found.Annotations == nil {
+			found.Annotations = map[string]string{}
+		}
+		found.Annotations[k] = v
+	}
+	found.Spec.Type = svc.Spec.Type
+	found.Spec.Selector = svc.Spec.Selector
+	found.Spec.PublishNotReadyAddresses = svc.Spec.PublishNotReadyAddresses
+	// An update without the node ports makes the API server allocate new
+	// ones, cutting off the clients using them, so the allocated ports are
+	// kept unless the Service no longer has any
+	ports := svc.Spec.Ports
+	if svc.Spec.Type != corev1.ServiceTypeClusterIP {
+		ports = keepNodePorts(ports, found.Spec.Ports)
+	}
+	found.Spec.Ports = ports
+	if found.Spec.Type == corev1.ServiceTypeClusterIP {
+		// Only valid for node ports and load balancers
+		found.Spec.ExternalTrafficPolicy = ""
+		found.Spec.HealthCheckNodePort = 0
+	}
+	log.Info("Updating Service", "Service.Namespace", found.Namespace, "Service.Name", found.Name, "type", found.Spec.Type)
+	if err := r.Update(ctx, found); err != nil {
+		log.Error(err, "Failed to update Service")
+		return err
+	}
+	return nil
+}
+
+// keepNodePorts returns a copy of desired with the node ports allocated to
+// the existing ports with the same name or port number.
+func keepNodePorts(desired, existing []corev1.ServicePort) []corev1.ServicePort {
+	ports := make([]corev1.ServicePort, len(desired))
+	copy(ports, desired)
+	for i := range ports {
+		if ports[i].NodePort != 0 {
+			continue
+		}
+		for _, e := range existing {
+			if (ports[i].Name != "" && e.Name == ports[i].Name) || e.Port == ports[i].Port {
+				ports[i].NodePort = e.NodePort
+				break
+			}
+		}
+	}
+	return ports
+}
@@ -0,0 +1,149 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestReconcileServices(t *testing.T) {
+	m := testMemcached()
+	m.Spec = cachev1alpha1.MemcachedSpec{
+		Size: 3,
+		Service: &cachev1alpha1.MemcachedService{
+			Type:        cachev1alpha1.ServiceLoadBalancer,
+			Annotations: map[string]string{"example.com/internal": "true"},
+		},
+	}
+	key := keyOf(m)
+	r := newTestReconciler(m)
+	ctx := context.TODO()
+
+	if err := r.reconcileServices(ctx, r.Log, m, cachev1alpha1.DefaultPort); err != nil {
+		t.Fatal(err)
+	}
+	svc := &corev1.Service{}
+	if err := r.Get(ctx, key, svc); err != nil {
+		t.Fatal(err)
+	}
+	if svc.Spec.Type != corev1.ServiceTypeLoadBalancer || svc.Annotations["example.com/internal"] != "true" {
+		t.Errorf("Service = %s %v, want an annotated LoadBalancer", svc.Spec.Type, svc.Annotations)
+	}
+
+	// Moving to a StatefulSet makes the Service headless, which takes a new
+	// Service, and adds one Service per pod.
+	m.Spec.WorkloadKind = cachev1alpha1.WorkloadStatefulSet
+	m.Spec.Service = &cachev1alpha1.MemcachedService{PerPod: true}
+	for i := 0; i < 2; i++ {
+		if err := r.reconcileServices(ctx, r.Log, m, cachev1alpha1.DefaultPort); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc = &corev1.Service{}
+	if err := r.Get(ctx, key, svc); err != nil {
+		t.Fatal(err)
+	}
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		t.Errorf("Service is not headless: clusterIP %q", svc.Spec.ClusterIP)
+	}
+	perPod, err := r.listPerPodServices(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(perPod) != 3 {
+		t.Fatalf("got %d per-pod Services, want 3", len(perPod))
+	}
+	if pod := perPod[0].Spec.Selector[statefulSetPodNameLabel]; pod != perPod[0].Name {
+		t.Errorf("per-pod Service %s selects pod %q", perPod[0].Name, pod)
+	}
+
+	// Scaling down removes the Services of the pods that are gone.
+	m.Spec.Size = 1
+	if err := r.reconcileServices(ctx, r.Log, m, cachev1alpha1.DefaultPort); err != nil {
+		t.Fatal(err)
+	}
+	perPod, err = r.listPerPodServices(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(perPod) != 1 || perPod[0].Name != "cache-0" {
+		t.Errorf("per-pod Services = %v, want only cache-0", perPod)
+	}
+}
+
+func TestReconcileServiceKeepsNodePorts(t *testing.T) {
+	m := testMemcached()
+	m.Spec = cachev1alpha1.MemcachedSpec{
+		Size:    3,
+		Service: &cachev1alpha1.MemcachedService{Type: cachev1alpha1.ServiceNodePort},
+	}
+	key := keyOf(m)
+	r := newTestReconciler(m)
+	ctx := context.TODO()
+
+	if err := r.reconcileServices(ctx, r.Log, m, cachev1alpha1.DefaultPort); err != nil {
+		t.Fatal(err)
+	}
+	// The fake client does not allocate node ports, do it the way the API
+	// server would
+	svc := &corev1.Service{}
+	if err := r.Get(ctx, key, svc); err != nil {
+		t.Fatal(err)
+	}
+	for i := range svc.Spec.Ports {
+		svc.Spec.Ports[i].NodePort = int32(30000 + i)
+	}
+	if err := r.Update(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Spec.Service.Annotations = map[string]string{"example.com/team": "cache"}
+	if err := r.reconcileServices(ctx, r.Log, m, cachev1alpha1.DefaultPort); err != nil {
+		t.Fatal(err)
+	}
+	svc = &corev1.Service{}
+	if err := r.Get(ctx, key, svc); err != nil {
+		t.Fatal(err)
+	}
+	if svc.Annotations["example.com/team"] != "cache" {
+		t.Errorf("annotations = %v, want the new annotation", svc.Annotations)
+	}
+	for i, port := range svc.Spec.Ports {
+		if port.NodePort != int32(30000+i) {
+			t.Errorf("port %s has node port %d, want %d", port.Name, port.NodePort, 30000+i)
+		}
+	}
+
+	// Going back to ClusterIP drops them
+	m.Spec.Service.Type = cachev1alpha1.ServiceClusterIP
+	if err := r.reconcileServices(ctx, r.Log, m, cachev1alpha1.DefaultPort); err != nil {
+		t.Fatal(err)
+	}
+	svc = &corev1.Service{}
+	if err := r.Get(ctx, key, svc); err != nil {
+		t.Fatal(err)
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.NodePort != 0 {
+			t.Errorf("port %s of a ClusterIP Service has node port %d", port.Name, port.NodePort)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+const (
+	// recommendedUtilizationPercent is the share of the item memory the
+	// working set should fill.
+	recommendedUtilizationPercent = 80
+	// minItemAge is how long items should survive. Items evicted younger
+	// mean the cache is too small for its working set.
+	minItemAge = time.Hour
+	// maxGrowthFactor bounds how far a single recommendation grows memory.
+	maxGrowthFactor = 4
+	// memoryStepMB is the granularity of the recommended memory.
+	memoryStepMB = 64
+	// maxRecommendedMemoryMB is the most memory recommended for a single
+	// pod. Beyond it, more replicas are recommended instead.
+	maxRecommendedMemoryMB = 8192
+	// resizeThresholdPercent is the smallest change of memory worth
+	// restarting the pods for.
+	resizeThresholdPercent = 20
+	// minResizeInterval is the least time between two applied
+	// recommendations when there are no sizing windows.
+	minResizeInterval = time.Hour
+
+	mib = 1024 * 1024
+)
+
+// Reasons of recommendations.
+const (
+	RecommendationReasonWorkingSet = "WorkingSet"
+	RecommendationReasonEvictions  = "Evictions"
+)
+
+// reconcileSizing publishes the sizing recommendation for m in status once
+// fresh statistics are in, and applies it to the spec with sizing mode Auto
+// when a window allows.
+func (r *MemcachedReconciler) reconcileSizing(ctx context.Context, log logr.Logger, m *cachev1alpha1.Memcached, status *cachev1alpha1.MemcachedStatus) error {
+	if status.Stats == nil {
+		status.Recommendation = nil
+		return nil
+	}
+	// Only recommend once per poll
+	if status.Recommendation != nil && m.Status.Stats != nil && m.Status.Stats.CollectedTime.Equal(&status.Stats.CollectedTime) {
+		return nil
+	}
+	now := time.Now()
+	rec := recommendSize(m, status.Stats, now)
+	if rec == nil {
+		return nil
+	}
+	if status.Recommendation != nil {
+		rec.AppliedTime = status.Recommendation.AppliedTime
+	}
+	status.Recommendation = rec
+
+	sizing := m.Spec.SizingWithDefaults()
+	if sizing.Mode != cachev1alpha1.SizingAuto || !resizeAllowed(sizing.Windows, rec.AppliedTime, now) {
+		return nil
+	}
+	memoryMB := m.Spec.ConfigWithDefaults().MemoryMB
+	resize := abs32(rec.MemoryMB-memoryMB)*100 > memoryMB*resizeThresholdPercent
+	// The autoscaler owns the replica count
+	grow := m.Spec.Autoscaling == nil && rec.Replicas > m.Spec.Size
+	if !resize && !grow {
+		return nil
+	}
+
+	from := fmt.Sprintf("%d x %dMB", m.Spec.Size, memoryMB)
+	resized := m.DeepCopy()
+	if resize {
+		if resized.Spec.Config == nil {
+			resized.Spec.Config = &cachev1alpha1.MemcachedConfig{}
+		}
+		resized.Spec.Config.MemoryMB = rec.MemoryMB
+	}
+	want, limit := m.Spec.Size, m.Spec.Size
+	if grow {
+		want, limit = rec.Replicas, cachev1alpha1.MaxSize
+	}
+	size, ok, err := r.admittedSize(ctx, resized, want, limit)
+	if err != nil {
+		log.Error(err, "Failed to check the admission policies")
+		return err
+	}
+	if !ok {
+		log.Info("Sizing recommendation not admitted by the admission policies", "from", from, "replicas", want)
+		r.Recorder.Eventf(m, corev1.EventTypeWarning, "ResizeBlocked", "not resizing from %s: the admission policies admit no size from %d to %d with %dMB",
+			from, want, limit, resized.Spec.ConfigWithDefaults().MemoryMB)
+		return nil
+	}
+	m.Spec.Config = resized.Spec.Config
+	m.Spec.Size = size
+	to := fmt.Sprintf("%d x %dMB", m.Spec.Size, m.Spec.ConfigWithDefaults().MemoryMB)
+	log.Info("Applying sizing recommendation", "from", from, "to", to, "reason", rec.Reason)
+	if err := r.Update(ctx, m); err != nil {
+		log.Error(err, "Failed to apply sizing recommendation")
+		return err
+	}
+	r.Recorder.Eventf(m, corev1.EventTypeNormal, "Resized", "resized from %s to %s: %s", from, to, rec.Message)
+	applied := metav1.NewTime(now)
+	rec.AppliedTime = &applied
+	return nil
+}
+
+// recommendSize works out the memory and replicas m needs from its
+// statistics. Caches that evict young items are sized so items survive for
+// minItemAge, the others so their working set fills
+// recommendedUtilizationPercent of the memory. The replica count only grows,
+// once a pod would need more than maxRecommendedMemoryMB. Neither goes past
+// the largest instance, cachev1alpha1.MaxSize pods of
+// cachev1alpha1.MaxMemoryMB each.
+func recommendSize(m *cachev1alpha1.Memcached, stats *cachev1alpha1.MemcachedStats, now time.Time) *cachev1alpha1.MemcachedRecommendation {
+	if stats.Pods == 0 || stats.LimitMaxBytes == 0 {
+		return nil
+	}
+	size := m.Spec.Size
+	if size < 1 {
+		size = 1
+	}
+	// Extrapolate from the pods that answered to all of them
+	capacity := stats.LimitMaxBytes * int64(size) / int64(stats.Pods)
+	workingSet := stats.BytesUsed * int64(size) / int64(stats.Pods)
+
+	rec := &cachev1alpha1.MemcachedRecommendation{ComputedTime: metav1.NewTime(now)}
+	var needed int64
+	evicting := stats.EvictionsPerMinute != nil && *stats.EvictionsPerMinute > 0
+	if age := stats.OldestItemAgeSeconds; evicting && age != nil && time.Duration(*age)*time.Second < minItemAge {
+		survival := *age
+		if survival < 1 {
+			survival = 1
+		}
+		// An LRU cache keeps items for about as long as its memory lasts,
+		// so keeping them longer takes proportionally more memory
+		needed = capacity * int64(minItemAge/time.Second) / survival
+		if needed > capacity*maxGrowthFactor {
+			needed = capacity * maxGrowthFactor
+		}
+		rec.Reason = RecommendationReasonEvictions
+		rec.Message = fmt.Sprintf("%d evictions per minute, items are evicted after %s instead of %s",
+			*stats.EvictionsPerMinute, time.Duration(survival)*time.Second, minItemAge)
+	} else {
+		needed = workingSet * 100 / recommendedUtilizationPercent
+		rec.Reason = RecommendationReasonWorkingSet
+		rec.Message = fmt.Sprintf("working set of %dMB fills %d%% of %dMB",
+			workingSet/mib, workingSet*100/capacity, capacity/mib)
+	}
+
+	replicas := size
+	if m.Spec.Autoscaling == nil {
+		if fewest := int32(divCeil(needed, maxRecommendedMemoryMB*mib)); fewest > replicas {
+			replicas = fewest
+			if replicas > cachev1alpha1.MaxSize {
+				replicas = cachev1alpha1.MaxSize
+			}
+		}
+	}
+	memoryMB := divCeil(divCeil(needed, int64(replicas)*mib), memoryStepMB) * memoryStepMB
+	// memcached needs room for two of the largest items
+	config := m.Spec.ConfigWithDefaults()
+	if minMB := divCeil(2*config.MaxItemSize.Value(), mib); memoryMB < minMB {
+		memoryMB = minMB
+	}
+	if memoryMB < memoryStepMB {
+		memoryMB = memoryStepMB
+	}
+	if memoryMB > cachev1alpha1.MaxMemoryMB {
+		memoryMB = cachev1alpha1.MaxMemoryMB
+	}
+	rec.MemoryMB = int32(memoryMB)
+	rec.Replicas = replicas
+	return rec
+}
+
+// resizeAllowed reports whether a recommendation may be applied now, given
+// the sizing windows and when one was last applied. Every window allows a
+// single resize.
+func resizeAllowed(windows []cachev1alpha1.MemcachedSizingWindow, applied *metav1.Time, now time.Time) bool {
+	if len(windows) == 0 {
+		return applied == nil || now.Sub(applied.Time) >= minResizeInterval
+	}
+	now = now.UTC()
+	for _, w := range windows {
+		start, err := time.Parse(cachev1alpha1.SizingWindowLayout, w.Start)
+		if err != nil {
+			continue
+		}
+		// The latest opening of the window
+		opened := time.Date(now.Year(), now.Month(), now.Day(), start.Hour(), start.Minute(), 0, 0, time.UTC)
+		if opened.After(now) {
+			opened = opened.AddDate(0, 0, -1)
+		}
+		if now.Before(opened.Add(w.Duration.Duration)) && (applied == nil || applied.Time.Before(opened)) {
+			return true
+		}
+	}
+	return false
+}
+
+// divCeil divides a by b, rounding up.
+func divCeil(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+func abs32(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
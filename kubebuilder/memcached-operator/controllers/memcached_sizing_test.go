@@ -0,0 +1,126 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestRecommendSize(t *testing.T) {
+	int64p := func(i int64) *int64 { return &i }
+	// stats returns the statistics of 3 pods with 1GB each
+	stats := func(usedMB int64, evictions, age *int64) *cachev1alpha1.MemcachedStats {
+		return &cachev1alpha1.MemcachedStats{
+			Pods:                 3,
+			BytesUsed:            usedMB * mib,
+			LimitMaxBytes:        3 * 1024 * mib,
+			EvictionsPerMinute:   evictions,
+			OldestItemAgeSeconds: age,
+		}
+	}
+	// large turns the pods of stats into pods with 4GB each
+	large := func(stats *cachev1alpha1.MemcachedStats) *cachev1alpha1.MemcachedStats {
+		stats.LimitMaxBytes *= 4
+		return stats
+	}
+	// huge turns the pods of stats into pods with 2TB each
+	huge := func(stats *cachev1alpha1.MemcachedStats) *cachev1alpha1.MemcachedStats {
+		stats.LimitMaxBytes *= 2048
+		return stats
+	}
+	tests := []struct {
+		name         string
+		autoscaling  bool
+		stats        *cachev1alpha1.MemcachedStats
+		wantMemoryMB int32
+		wantReplicas int32
+		wantReason   string
+	}{
+		// 1200MB at 80% is 1500MB, or 500MB per pod, rounded to 512MB
+		{name: "oversized", stats: stats(1200, int64p(0), int64p(7200)), wantMemoryMB: 512, wantReplicas: 3, wantReason: RecommendationReasonWorkingSet},
+		{name: "empty", stats: stats(0, nil, nil), wantMemoryMB: 64, wantReplicas: 3, wantReason: RecommendationReasonWorkingSet},
+		// Evicting old items is what a full cache does
+		{name: "evicting old items", stats: stats(3000, int64p(50), int64p(7200)), wantMemoryMB: 1280, wantReplicas: 3, wantReason: RecommendationReasonWorkingSet},
+		// Items live for 30 minutes, keeping them for an hour takes twice the memory
+		{name: "evicting young items", stats: stats(3000, int64p(50), int64p(1800)), wantMemoryMB: 2048, wantReplicas: 3, wantReason: RecommendationReasonEvictions},
+		// Growth is bounded
+		{name: "thrashing", stats: stats(3000, int64p(5000), int64p(10)), wantMemoryMB: 4096, wantReplicas: 3, wantReason: RecommendationReasonEvictions},
+		// 4 x 12GB takes 6 pods of at most 8GB
+		{name: "thrashing large pods", stats: large(stats(12000, int64p(5000), int64p(10))), wantMemoryMB: 8192, wantReplicas: 6, wantReason: RecommendationReasonEvictions},
+		{name: "thrashing large pods with autoscaling", autoscaling: true, stats: large(stats(12000, int64p(5000), int64p(10))), wantMemoryMB: 16384, wantReplicas: 3, wantReason: RecommendationReasonEvictions},
+		// 24TB would take 3072 pods of 8GB, more than an instance may have
+		{name: "thrashing huge pods", stats: huge(stats(12000, int64p(5000), int64p(10))), wantMemoryMB: cachev1alpha1.MaxMemoryMB, wantReplicas: 256, wantReason: RecommendationReasonEvictions},
+		{name: "thrashing huge pods with autoscaling", autoscaling: true, stats: huge(stats(12000, int64p(5000), int64p(10))), wantMemoryMB: cachev1alpha1.MaxMemoryMB, wantReplicas: 3, wantReason: RecommendationReasonEvictions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &cachev1alpha1.Memcached{Spec: cachev1alpha1.MemcachedSpec{
+				Size:   3,
+				Config: &cachev1alpha1.MemcachedConfig{MemoryMB: 1024},
+			}}
+			if tt.autoscaling {
+				m.Spec.Autoscaling = &cachev1alpha1.MemcachedAutoscaling{MaxReplicas: 9}
+			}
+			rec := recommendSize(m, tt.stats, time.Now())
+			if rec == nil {
+				t.Fatal("no recommendation")
+			}
+			if rec.MemoryMB != tt.wantMemoryMB || rec.Replicas != tt.wantReplicas || rec.Reason != tt.wantReason {
+				t.Errorf("recommendation = %d x %dMB (%s), want %d x %dMB (%s)",
+					rec.Replicas, rec.MemoryMB, rec.Reason, tt.wantReplicas, tt.wantMemoryMB, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestResizeAllowed(t *testing.T) {
+	now := time.Date(2020, 3, 10, 3, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(now.Add(d))
+		return &t
+	}
+	night := []cachev1alpha1.MemcachedSizingWindow{{Start: "02:00", Duration: metav1.Duration{Duration: 2 * time.Hour}}}
+	lateEvening := []cachev1alpha1.MemcachedSizingWindow{{Start: "23:30", Duration: metav1.Duration{Duration: 4 * time.Hour}}}
+	evening := []cachev1alpha1.MemcachedSizingWindow{{Start: "20:00", Duration: metav1.Duration{Duration: time.Hour}}}
+
+	tests := []struct {
+		name    string
+		windows []cachev1alpha1.MemcachedSizingWindow
+		applied *metav1.Time
+		want    bool
+	}{
+		{name: "no windows", want: true},
+		{name: "no windows, applied recently", applied: at(-10 * time.Minute)},
+		{name: "no windows, applied long ago", applied: at(-2 * time.Hour), want: true},
+		{name: "in window", windows: night, want: true},
+		{name: "in window, applied in it", windows: night, applied: at(-30 * time.Minute)},
+		{name: "in window, applied the day before", windows: night, applied: at(-24 * time.Hour), want: true},
+		{name: "in window opened yesterday", windows: lateEvening, want: true},
+		{name: "outside window", windows: evening},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resizeAllowed(tt.windows, tt.applied, now); got != tt.want {
+				t.Errorf("resizeAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+	"github.com/example-inc/memcached-operator/memcache"
+)
+
+// statsTimeout bounds the stats poll of a single pod.
+const statsTimeout = 2 * time.Second
+
+// podStats are the statistics of a single memcached pod.
+type podStats struct {
+	getHits, getMisses int64
+	evictions          int64
+	bytes              int64
+	limitMaxBytes      int64
+	totalMalloced      int64
+	currConnections    int64
+	// oldestItemAge is the shortest age of the least recently used item of
+	// a slab class, in seconds, or -1 when the pod holds no items.
+	oldestItemAge int64
+}
+
+// statsPollable reports whether the pods of m answer "stats" over a plain
+// text protocol connection. TLS, SASL and the binary protocol all get in the
+// way.
+func statsPollable(m *cachev1alpha1.Memcached) bool {
+	config := m.Spec.ConfigWithDefaults()
+	return m.Spec.TLS == nil && m.Spec.Auth == nil && config.Protocol != cachev1alpha1.ProtocolBinary
+}
+
+// reconcileStats polls the running pods of m for their statistics once
+// r.StatsInterval has passed since the last poll, and records them in status
+// and in the operator metrics. It returns when the next poll is due, or 0
+// when the pods are not polled, as with spec.monitoring.disableStats. Pods
+// that do not answer are logged and left out.
+func (r *MemcachedReconciler) reconcileStats(ctx context.Context, log logr.Logger, m *cachev1alpha1.Memcached, status *cachev1alpha1.MemcachedStatus, pods []corev1.Pod, port int32) time.Duration {
+	if r.StatsInterval <= 0 || !statsPollable(m) || (m.Spec.Monitoring != nil && m.Spec.Monitoring.DisableStats) {
+		status.Stats = nil
+		deleteStatsMetrics(m)
+		return 0
+	}
+	if status.Stats != nil {
+		if wait := r.StatsInterval - time.Since(status.Stats.CollectedTime.Time); wait > 0 {
+			return wait
+		}
+	}
+
+	var addresses []string
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" && pod.DeletionTimestamp == nil {
+			addresses = append(addresses, net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(port))))
+		}
+	}
+	if len(addresses) == 0 {
+		return r.StatsInterval
+	}
+
+	results := make([]podStats, len(addresses))
+	errs := make([]error, len(addresses))
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, statsTimeout)
+			defer cancel()
+			results[i], errs[i] = fetchPodStats(ctx, address)
+		}(i, address)
+	}
+	wg.Wait()
+
+	stats := &cachev1alpha1.MemcachedStats{CollectedTime: metav1.Now()}
+	for i, ps := range results {
+		if errs[i] != nil {
+			log.Info("Failed to collect memcached stats", "address", addresses[i], "error", errs[i].Error())
+			statsErrors.WithLabelValues(m.Namespace, m.Name).Inc()
+			continue
+		}
+		stats.Pods++
+		stats.GetHits += ps.getHits
+		stats.GetMisses += ps.getMisses
+		stats.Evictions += ps.evictions
+		stats.BytesUsed += ps.bytes
+		stats.LimitMaxBytes += ps.limitMaxBytes
+		stats.MallocedBytes += ps.totalMalloced
+		stats.CurrentConnections += ps.currConnections
+		if ps.oldestItemAge >= 0 && (stats.OldestItemAgeSeconds == nil || ps.oldestItemAge < *stats.OldestItemAgeSeconds) {
+			age := ps.oldestItemAge
+			stats.OldestItemAgeSeconds = &age
+		}
+	}
+	// Keep the last statistics when no pod answered
+	if stats.Pods == 0 {
+		return r.StatsInterval
+	}
+	if gets := stats.GetHits + stats.GetMisses; gets > 0 {
+		ratio := int32(stats.GetHits * 100 / gets)
+		stats.HitRatioPercent = &ratio
+	}
+	// The eviction rate needs the same pods as last time, a pod that
+	// restarted or went missing throws the counters off
+	if prev := status.Stats; prev != nil && prev.Pods == stats.Pods && stats.Evictions >= prev.Evictions {
+		if elapsed := stats.CollectedTime.Sub(prev.CollectedTime.Time); elapsed > 0 {
+			rate := int64(math.Round(float64(stats.Evictions-prev.Evictions) / elapsed.Minutes()))
+			stats.EvictionsPerMinute = &rate
+		}
+	}
+	status.Stats = stats
+	recordStatsMetrics(m, stats)
+	return r.StatsInterval
+}
+
+// fetchPodStats runs "stats", "stats slabs" and "stats items" against the memcached server
+// at address.
+func fetchPodStats(ctx context.Context, address string) (podStats, error) {
+	c := memcache.New(address, memcache.Options{Timeout: statsTimeout, MaxIdleConns: 1})
+	defer c.Close()
+
+	general, err := c.Stats(ctx)
+	if err != nil {
+		return podStats{}, err
+	}
+	slabs, err := c.Stats(ctx, "slabs")
+	if err != nil {
+		return podStats{}, err
+	}
+	items, err := c.Stats(ctx, "items")
+	if err != nil {
+		return podStats{}, err
+	}
+
+	ps := podStats{oldestItemAge: -1}
+	for key, value := range items {
+		// items:<class>:age is the age of the least recently used item of
+		// the slab class
+		if !strings.HasPrefix(key, "items:") || !strings.HasSuffix(key, ":age") {
+			continue
+		}
+		age, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return podStats{}, fmt.Errorf("%s: %s: %v", address, key, err)
+		}
+		if ps.oldestItemAge < 0 || age < ps.oldestItemAge {
+			ps.oldestItemAge = age
+		}
+	}
+	for _, f := range []struct {
+		stats map[string]string
+		key   string
+		dst   *int64
+	}{
+		{general, "get_hits", &ps.getHits},
+		{general, "get_misses", &ps.getMisses},
+		{general, "evictions", &ps.evictions},
+		{general, "bytes", &ps.bytes},
+		{general, "limit_maxbytes", &ps.limitMaxBytes},
+		{general, "curr_connections", &ps.currConnections},
+		{slabs, "total_malloced", &ps.totalMalloced},
+	} {
+		value, ok := f.stats[f.key]
+		if !ok {
+			return podStats{}, fmt.Errorf("%s: no %s in stats", address, f.key)
+		}
+		if *f.dst, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return podStats{}, fmt.Errorf("%s: %s: %v", address, f.key, err)
+		}
+	}
+	return ps, nil
+}
@@ -0,0 +1,133 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+	"github.com/example-inc/memcached-operator/memcache/memcachetest"
+)
+
+// statsServer starts a fake memcached server with the given statistics and
+// returns it with the port it listens on.
+func statsServer(t *testing.T, stats, slabs map[string]string) (*memcachetest.Server, int32) {
+	s := memcachetest.NewServer()
+	s.SetStats("", stats)
+	s.SetStats("slabs", slabs)
+	_, port, err := net.SplitHostPort(s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, int32(p)
+}
+
+func TestReconcileStats(t *testing.T) {
+	s, port := statsServer(t,
+		map[string]string{
+			"pid": "1", "get_hits": "90", "get_misses": "10", "evictions": "5",
+			"bytes": "1024", "limit_maxbytes": "67108864", "curr_connections": "12",
+		},
+		map[string]string{"1:chunk_size": "96", "active_slabs": "1", "total_malloced": "1048576"})
+	defer s.Close()
+	s.SetStats("items", map[string]string{"items:1:number": "5", "items:1:age": "600", "items:2:number": "1", "items:2:age": "300"})
+
+	m := testMemcached()
+	m.Spec = cachev1alpha1.MemcachedSpec{
+		Size:   3,
+		Config: &cachev1alpha1.MemcachedConfig{Port: port},
+	}
+	running := corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "127.0.0.1"}
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "cache-0"}, Status: running},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cache-1"}, Status: running},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cache-2"}, Status: corev1.PodStatus{Phase: corev1.PodPending}},
+	}
+	r := newTestReconciler(m)
+	r.StatsInterval = time.Minute
+	ctx := context.TODO()
+
+	status := m.Status.DeepCopy()
+	if next := r.reconcileStats(ctx, r.Log, m, status, pods, port); next != time.Minute {
+		t.Errorf("next poll in %s, want %s", next, time.Minute)
+	}
+	stats := status.Stats
+	if stats == nil {
+		t.Fatal("no stats collected")
+	}
+	// Both running pods answer with the same statistics
+	if stats.Pods != 2 || stats.GetHits != 180 || stats.Evictions != 10 || stats.BytesUsed != 2048 ||
+		stats.LimitMaxBytes != 2*67108864 || stats.MallocedBytes != 2*1048576 || stats.CurrentConnections != 24 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.HitRatioPercent == nil || *stats.HitRatioPercent != 90 {
+		t.Errorf("hit ratio = %v, want 90", stats.HitRatioPercent)
+	}
+	if age := stats.OldestItemAgeSeconds; age == nil || *age != 300 {
+		t.Errorf("oldest item age = %v, want 300", stats.OldestItemAgeSeconds)
+	}
+	if ratio := testutil.ToFloat64(statsHitRatio.WithLabelValues("ns", "cache")); ratio != 0.9 {
+		t.Errorf("hit ratio metric = %v, want 0.9", ratio)
+	}
+
+	// The next poll waits for the interval
+	collected := stats.CollectedTime
+	if next := r.reconcileStats(ctx, r.Log, m, status, pods, port); next <= 0 || next > time.Minute {
+		t.Errorf("next poll in %s, want within %s", next, time.Minute)
+	}
+	if !status.Stats.CollectedTime.Equal(&collected) {
+		t.Errorf("stats collected again before the interval passed")
+	}
+
+	// Once the interval has passed, the eviction rate is derived from the
+	// previous poll
+	status.Stats.CollectedTime = metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	status.Stats.Evictions -= 6
+	r.reconcileStats(ctx, r.Log, m, status, pods, port)
+	if rate := status.Stats.EvictionsPerMinute; rate == nil || *rate != 3 {
+		t.Errorf("evictions per minute = %v, want 3", status.Stats.EvictionsPerMinute)
+	}
+
+	// Pods behind TLS are not polled
+	m.Spec.TLS = &cachev1alpha1.MemcachedTLS{SecretName: "cert"}
+	if next := r.reconcileStats(ctx, r.Log, m, status, pods, port); next != 0 || status.Stats != nil {
+		t.Errorf("TLS pods polled: next %s, stats %+v", next, status.Stats)
+	}
+}
+
+func TestFetchPodStatsError(t *testing.T) {
+	// A server without total_malloced is not a memcached we understand
+	s, port := statsServer(t, map[string]string{"get_hits": "1"}, map[string]string{})
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+	if _, err := fetchPodStats(ctx, fmt.Sprintf("127.0.0.1:%d", port)); err == nil {
+		t.Error("expected an error for incomplete stats")
+	}
+}
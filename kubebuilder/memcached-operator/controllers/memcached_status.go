@@ -0,0 +1,135 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// Condition reasons set by the controller.
+const (
+	ReasonReplicasReady            = "ReplicasReady"
+	ReasonNoReplicasReady          = "NoReplicasReady"
+	ReasonScaledToZero             = "ScaledToZero"
+	ReasonRollingOut               = "RollingOut"
+	ReasonWaitingForReplicas       = "WaitingForReplicas"
+	ReasonRolloutComplete          = "RolloutComplete"
+	ReasonAsExpected               = "AsExpected"
+	ReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	ReasonReplicaFailure           = "ReplicaFailure"
+	ReasonInvalidSpec              = "InvalidSpec"
+	ReasonReconcileError           = "ReconcileError"
+	ReasonSuspended                = "Suspended"
+	ReasonNotSuspended             = "NotSuspended"
+	ReasonTearingDown              = "TearingDown"
+	ReasonSwitchingWorkload        = "SwitchingWorkload"
+	ReasonTLSEnabled               = "TLSEnabled"
+	ReasonTLSDisabled              = "TLSDisabled"
+	ReasonInvalidTLSSecret         = "InvalidTLSSecret"
+	ReasonAuthEnabled              = "AuthEnabled"
+	ReasonAuthDisabled             = "AuthDisabled"
+	ReasonInvalidAuthSecret        = "InvalidAuthSecret"
+)
+
+// specError marks errors caused by an invalid spec. Retrying cannot fix
+// them, so they are reported in status and not requeued.
+type specError struct {
+	error
+}
+
+func isSpecError(err error) bool {
+	_, ok := err.(specError)
+	return ok
+}
+
+// updateFailedStatus records a failed reconcile in the status of m.
+func (r *MemcachedReconciler) updateFailedStatus(ctx context.Context, m *cachev1alpha1.Memcached, err error) error {
+	reason := ReasonReconcileError
+	if isSpecError(err) {
+		reason = ReasonInvalidSpec
+		// The controller has acted on this generation: it rejected it.
+		m.Status.ObservedGeneration = m.Generation
+	}
+	m.Status.LastError = err.Error()
+	m.Status.SetCondition(cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedDegraded,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: m.Generation,
+		Reason:             reason,
+		Message:            err.Error(),
+	})
+	return r.Status().Update(ctx, m)
+}
+
+// setWorkloadConditions derives the Available, Progressing and Degraded
+// conditions from the state of the workload running the memcached pods.
+// switching says whether an old workload of the other kind is still around.
+func setWorkloadConditions(status *cachev1alpha1.MemcachedStatus, w workload, switching bool, generation int64) {
+	desired := w.replicas()
+	ws := w.status()
+	ready := ws.ReadyReplicas
+
+	available := cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedAvailable,
+		ObservedGeneration: generation,
+		Message:            fmt.Sprintf("%d/%d replicas ready", ready, desired),
+	}
+	switch {
+	case ready > 0:
+		available.Status, available.Reason = corev1.ConditionTrue, ReasonReplicasReady
+	case desired == 0:
+		available.Status, available.Reason = corev1.ConditionFalse, ReasonScaledToZero
+	default:
+		available.Status, available.Reason = corev1.ConditionFalse, ReasonNoReplicasReady
+	}
+	status.SetCondition(available)
+
+	progressing := cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedProgressing,
+		ObservedGeneration: generation,
+	}
+	switch {
+	case !w.rolledOut():
+		progressing.Status, progressing.Reason = corev1.ConditionTrue, ReasonRollingOut
+		progressing.Message = fmt.Sprintf("%d/%d replicas updated", ws.UpdatedReplicas, desired)
+	case ready < desired:
+		progressing.Status, progressing.Reason = corev1.ConditionTrue, ReasonWaitingForReplicas
+		progressing.Message = fmt.Sprintf("%d/%d replicas ready", ready, desired)
+	case switching:
+		progressing.Status, progressing.Reason = corev1.ConditionTrue, ReasonSwitchingWorkload
+		progressing.Message = fmt.Sprintf("moving to a %s", w.kind())
+	default:
+		progressing.Status, progressing.Reason = corev1.ConditionFalse, ReasonRolloutComplete
+		progressing.Message = "all replicas are up to date and ready"
+	}
+	status.SetCondition(progressing)
+
+	degraded := cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedDegraded,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             ReasonAsExpected,
+	}
+	if ws.FailureReason != "" {
+		degraded.Status, degraded.Reason, degraded.Message = corev1.ConditionTrue, ws.FailureReason, ws.FailureMessage
+	}
+	status.SetCondition(degraded)
+}
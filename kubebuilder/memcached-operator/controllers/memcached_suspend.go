@@ -0,0 +1,121 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// isSuspended reports whether spec.suspend is set.
+func isSuspended(m *cachev1alpha1.Memcached) bool {
+	return m.Spec.Suspend != nil && *m.Spec.Suspend
+}
+
+// desiredReplicas returns the number of replicas the Deployment should run.
+// A suspended instance runs none, a resumed one first goes back to the size
+// it had when it was suspended.
+func desiredReplicas(m *cachev1alpha1.Memcached) int32 {
+	switch {
+	case isSuspended(m):
+		return 0
+	case m.Status.SuspendedReplicas != nil:
+		return *m.Status.SuspendedReplicas
+	default:
+		return m.Spec.Size
+	}
+}
+
+// reconcileSuspended scales a suspended Memcached to zero. Nothing else about
+// the instance is changed until it is resumed.
+func (r *MemcachedReconciler) reconcileSuspended(ctx context.Context, log logr.Logger, memcached *cachev1alpha1.Memcached, found workload) (ctrl.Result, error) {
+	pods, err := r.listPods(ctx, memcached)
+	if err != nil {
+		log.Error(err, "Failed to list pods", "Memcached.Namespace", memcached.Namespace, "Memcached.Name", memcached.Name)
+		return ctrl.Result{}, err
+	}
+
+	// Record the current size before scaling down, so that it survives the
+	// workload being at zero.
+	status := memcached.Status.DeepCopy()
+	if status.SuspendedReplicas == nil {
+		previous := memcached.Spec.Size
+		if found.replicas() > 0 {
+			previous = found.replicas()
+		}
+		status.SuspendedReplicas = &previous
+	}
+	status.Nodes = getPodNames(pods)
+	status.ObservedGeneration = memcached.Generation
+	status.ReadyReplicas = found.status().ReadyReplicas
+	status.Replicas = found.status().Replicas
+	status.Selector = labels.SelectorFromSet(labelsForMemcached(memcached.Name)).String()
+	status.LastError = ""
+	setWorkloadConditions(status, found, false, memcached.Generation)
+	setSuspendedCondition(status, memcached.Generation)
+	if !reflect.DeepEqual(*status, memcached.Status) {
+		memcached.Status = *status
+		if err := r.Status().Update(ctx, memcached); err != nil {
+			log.Error(err, "Failed to update Memcached status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if found.replicas() != 0 {
+		log.Info("Suspending, scaling workload to zero", "kind", found.kind(), "Namespace", memcached.Namespace, "Name", memcached.Name,
+			"previousReplicas", *status.SuspendedReplicas)
+		found.setReplicas(0)
+		if err := r.Update(ctx, found.object()); err != nil {
+			log.Error(err, "Failed to update workload", "kind", found.kind(), "Namespace", memcached.Namespace, "Name", memcached.Name)
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(memcached, corev1.EventTypeNormal, "Suspended",
+			"scaled to zero, %d replicas will be restored on resume", *status.SuspendedReplicas)
+		// Spec updated - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
+	// Nothing serves while suspended, so a workload being switched away from
+	// can go right away
+	if _, err := r.retireWorkload(ctx, log, memcached, found); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.V(1).Info("Memcached suspended, skipping")
+	return ctrl.Result{}, nil
+}
+
+// setSuspendedCondition sets the Suspended condition from spec.suspend and
+// the replicas recorded in status.
+func setSuspendedCondition(status *cachev1alpha1.MemcachedStatus, generation int64) {
+	suspended := cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedSuspended,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             ReasonNotSuspended,
+	}
+	if status.SuspendedReplicas != nil {
+		suspended.Status, suspended.Reason = corev1.ConditionTrue, ReasonSuspended
+		suspended.Message = fmt.Sprintf("scaled to zero, %d replicas will be restored on resume", *status.SuspendedReplicas)
+	}
+	status.SetCondition(suspended)
+}
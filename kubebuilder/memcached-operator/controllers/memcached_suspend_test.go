@@ -0,0 +1,67 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestDesiredReplicas(t *testing.T) {
+	yes, no := true, false
+	previous := int32(5)
+
+	tests := []struct {
+		name              string
+		suspend           *bool
+		suspendedReplicas *int32
+		want              int32
+	}{
+		{name: "running", suspend: &no, want: 3},
+		{name: "unset", want: 3},
+		{name: "suspended", suspend: &yes, suspendedReplicas: &previous, want: 0},
+		{name: "resuming", suspend: &no, suspendedReplicas: &previous, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &cachev1alpha1.Memcached{
+				Spec:   cachev1alpha1.MemcachedSpec{Size: 3, Suspend: tt.suspend},
+				Status: cachev1alpha1.MemcachedStatus{SuspendedReplicas: tt.suspendedReplicas},
+			}
+			if got := desiredReplicas(m); got != tt.want {
+				t.Errorf("desiredReplicas = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetSuspendedCondition(t *testing.T) {
+	previous := int32(5)
+	status := &cachev1alpha1.MemcachedStatus{SuspendedReplicas: &previous}
+	setSuspendedCondition(status, 2)
+	if c := status.FindCondition(cachev1alpha1.MemcachedSuspended); c == nil || c.Status != corev1.ConditionTrue || c.Reason != ReasonSuspended {
+		t.Fatalf("Suspended condition = %+v, want True/%s", c, ReasonSuspended)
+	}
+
+	status.SuspendedReplicas = nil
+	setSuspendedCondition(status, 3)
+	if c := status.FindCondition(cachev1alpha1.MemcachedSuspended); c.Status != corev1.ConditionFalse || c.ObservedGeneration != 3 {
+		t.Fatalf("Suspended condition = %+v, want False at generation 3", c)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// Where the TLS Secrets are mounted in the memcached container.
+const (
+	tlsMountPath      = "/etc/memcached/tls"
+	clientCAMountPath = "/etc/memcached/client-ca"
+	tlsVolume         = "tls"
+	clientCAVolume    = "client-ca"
+	// clientCAKey is the Secret key holding the client CA bundle.
+	clientCAKey = "ca.crt"
+)
+
+// tlsOptions returns the "-o" options that point memcached at the mounted
+// certificates.
+func tlsOptions(tls *cachev1alpha1.MemcachedTLS) []string {
+	options := []string{
+		"ssl_chain_cert=" + path.Join(tlsMountPath, corev1.TLSCertKey),
+		"ssl_key=" + path.Join(tlsMountPath, corev1.TLSPrivateKeyKey),
+	}
+	if tls.ClientCASecretName != "" {
+		// Verify mode 2 rejects clients without a certificate signed by
+		// the CA.
+		options = append(options,
+			"ssl_ca_cert="+path.Join(clientCAMountPath, clientCAKey),
+			"ssl_verify_mode=2")
+	}
+	return options
+}
+
+// applyTLS mounts the TLS Secrets of m into the memcached container of pod.
+func applyTLS(pod *corev1.PodSpec, m *cachev1alpha1.Memcached) {
+	tls := m.Spec.TLS
+	if tls == nil {
+		return
+	}
+	mounts := []corev1.VolumeMount{{Name: tlsVolume, MountPath: tlsMountPath, ReadOnly: true}}
+	pod.Volumes = append(pod.Volumes, corev1.Volume{
+		Name:         tlsVolume,
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: tls.SecretName}},
+	})
+	if tls.ClientCASecretName != "" {
+		mounts = append(mounts, corev1.VolumeMount{Name: clientCAVolume, MountPath: clientCAMountPath, ReadOnly: true})
+		pod.Volumes = append(pod.Volumes, corev1.Volume{
+			Name:         clientCAVolume,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: tls.ClientCASecretName}},
+		})
+	}
+	for i := range pod.Containers {
+		if pod.Containers[i].Name == "memcached" {
+			pod.Containers[i].VolumeMounts = append(pod.Containers[i].VolumeMounts, mounts...)
+		}
+	}
+}
+
+// tlsCondition checks the Secrets referenced by spec.tls and returns the TLS
+// condition of m. The error is set when the Secrets cannot be used, in which
+// case rolling out pods that mount them would only leave them stuck.
+func (r *MemcachedReconciler) tlsCondition(ctx context.Context, m *cachev1alpha1.Memcached) (cachev1alpha1.MemcachedCondition, error) {
+	cond := cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedTLSReady,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: m.Generation,
+		Reason:             ReasonTLSDisabled,
+	}
+	tls := m.Spec.TLS
+	if tls == nil {
+		return cond, nil
+	}
+
+	err := r.checkSecretKeys(ctx, m.Namespace, tls.SecretName, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	if err == nil && tls.ClientCASecretName != "" {
+		err = r.checkSecretKeys(ctx, m.Namespace, tls.ClientCASecretName, clientCAKey)
+	}
+	if err != nil {
+		cond.Reason, cond.Message = ReasonInvalidTLSSecret, err.Error()
+		return cond, err
+	}
+	cond.Status, cond.Reason = corev1.ConditionTrue, ReasonTLSEnabled
+	cond.Message = fmt.Sprintf("serving TLS with the certificate from Secret %s", tls.SecretName)
+	if tls.ClientCASecretName != "" {
+		cond.Message += fmt.Sprintf(", client certificates verified against Secret %s", tls.ClientCASecretName)
+	}
+	return cond, nil
+}
+
+// checkSecretKeys fails unless the named Secret exists and has all keys.
+func (r *MemcachedReconciler) checkSecretKeys(ctx context.Context, namespace, name string, keys ...string) error {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("Secret %s not found", name)
+		}
+		return err
+	}
+	for _, key := range keys {
+		if len(secret.Data[key]) == 0 {
+			return fmt.Errorf("Secret %s has no %s", name, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// workload is the object that runs the memcached pods of a Memcached: a
+// Deployment or a StatefulSet.
+type workload interface {
+	// object returns the wrapped API object for use with the client.
+	object() runtime.Object
+	// meta returns the metadata of the wrapped API object.
+	meta() metav1.Object
+	kind() cachev1alpha1.WorkloadKind
+	replicas() int32
+	setReplicas(replicas int32)
+	// specMatches reports whether the pod template and update strategy
+	// already are those of desired. Fields defaulted by the API server are
+	// ignored.
+	specMatches(desired workload) bool
+	// copySpec copies the pod template and update strategy of desired.
+	copySpec(desired workload)
+	// rolledOut reports whether the workload controller has observed the
+	// latest spec and every replica runs the latest pod template.
+	rolledOut() bool
+	status() workloadStatus
+}
+
+// workloadStatus is the part of a workload's status the Memcached status is
+// derived from.
+type workloadStatus struct {
+	Replicas        int32
+	ReadyReplicas   int32
+	UpdatedReplicas int32
+	// FailureReason and FailureMessage say why the workload cannot make
+	// progress, if it cannot.
+	FailureReason  string
+	FailureMessage string
+}
+
+// workloadKind returns the workload kind of m, defaulting to Deployment.
+func workloadKind(m *cachev1alpha1.Memcached) cachev1alpha1.WorkloadKind {
+	if m.Spec.WorkloadKind == "" {
+		return cachev1alpha1.WorkloadDeployment
+	}
+	return m.Spec.WorkloadKind
+}
+
+// otherWorkloadKind returns the kind an instance of the given kind may be
+// switching away from.
+func otherWorkloadKind(kind cachev1alpha1.WorkloadKind) cachev1alpha1.WorkloadKind {
+	if kind == cachev1alpha1.WorkloadStatefulSet {
+		return cachev1alpha1.WorkloadDeployment
+	}
+	return cachev1alpha1.WorkloadStatefulSet
+}
+
+// emptyWorkload returns a workload of the given kind that only carries the
+// name and namespace of m, ready to be read into.
+func emptyWorkload(m *cachev1alpha1.Memcached, kind cachev1alpha1.WorkloadKind) workload {
+	objectMeta := metav1.ObjectMeta{Name: m.Name, Namespace: m.Namespace}
+	if kind == cachev1alpha1.WorkloadStatefulSet {
+		return statefulSetWorkload{&appsv1.StatefulSet{ObjectMeta: objectMeta}}
+	}
+	return deploymentWorkload{&appsv1.Deployment{ObjectMeta: objectMeta}}
+}
+
+// getWorkload reads the workload of the given kind for m. It returns nil if
+// there is none.
+func (r *MemcachedReconciler) getWorkload(ctx context.Context, m *cachev1alpha1.Memcached, kind cachev1alpha1.WorkloadKind) (workload, error) {
+	w := emptyWorkload(m, kind)
+	err := r.Get(ctx, types.NamespacedName{Name: m.Name, Namespace: m.Namespace}, w.object())
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// retireWorkload removes the workload an instance is switching away from,
+// once current has rolled out and all of its replicas are ready. It reports
+// whether the old workload is still around.
+func (r *MemcachedReconciler) retireWorkload(ctx context.Context, log logr.Logger, m *cachev1alpha1.Memcached, current workload) (bool, error) {
+	old, err := r.getWorkload(ctx, m, otherWorkloadKind(current.kind()))
+	if err != nil || old == nil {
+		return false, err
+	}
+	if !metav1.IsControlledBy(old.meta(), m) {
+		// Not ours to remove
+		return false, nil
+	}
+	if !current.rolledOut() || current.status().ReadyReplicas < current.replicas() {
+		// Keep serving from the old pods until the new ones can take over
+		return true, nil
+	}
+	log.Info("Removing replaced workload", "kind", old.kind(), "name", old.meta().GetName())
+	if err := r.Delete(ctx, old.object()); err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to delete replaced workload", "kind", old.kind())
+		return true, err
+	}
+	r.Recorder.Eventf(m, corev1.EventTypeNormal, "WorkloadSwitched", "now running on a %s, removed the %s", current.kind(), old.kind())
+	return false, nil
+}
+
+// workloadForMemcached returns the workload of the given kind for m.
+func (r *MemcachedReconciler) workloadForMemcached(m *cachev1alpha1.Memcached, kind cachev1alpha1.WorkloadKind, template corev1.PodTemplateSpec) workload {
+	if kind == cachev1alpha1.WorkloadStatefulSet {
+		return statefulSetWorkload{r.statefulSetForMemcached(m, template)}
+	}
+	return deploymentWorkload{r.deploymentForMemcached(m, template)}
+}
+
+// podTemplateForMemcached returns the pod template shared by both workload
+// kinds.
+func podTemplateForMemcached(m *cachev1alpha1.Memcached, command []string, port int32, resources corev1.ResourceRequirements) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: labelsForMemcached(m.Name),
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image:   m.Spec.ContainerImage(),
+				Name:    "memcached",
+				Command: command,
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: port,
+					Name:          "memcached",
+				}},
+				Resources: resources,
+			}},
+		},
+	}
+	applyScheduling(&template.Spec, m)
+	applyTLS(&template.Spec, m)
+	applyAuth(&template.Spec, m)
+	return template
+}
+
+// deploymentForMemcached returns a memcached Deployment object
+func (r *MemcachedReconciler) deploymentForMemcached(m *cachev1alpha1.Memcached, template corev1.PodTemplateSpec) *appsv1.Deployment {
+	ls := labelsForMemcached(m.Name)
+	replicas := desiredReplicas(m)
+	// Replace pods one at a time so that a version change never takes more
+	// than one cache shard offline.
+	maxUnavailable := intstr.FromInt(1)
+	maxSurge := intstr.FromInt(0)
+
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name,
+			Namespace: m.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: ls,
+			},
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{
+					MaxUnavailable: &maxUnavailable,
+					MaxSurge:       &maxSurge,
+				},
+			},
+			Template: template,
+		},
+	}
+	// Set Memcached instance as the owner and controller
+	ctrl.SetControllerReference(m, dep, r.Scheme)
+	return dep
+}
+
+// statefulSetForMemcached returns a memcached StatefulSet object. Its pods
+// are named <name>-<ordinal> and resolvable through the headless Service of
+// the instance.
+func (r *MemcachedReconciler) statefulSetForMemcached(m *cachev1alpha1.Memcached, template corev1.PodTemplateSpec) *appsv1.StatefulSet {
+	ls := labelsForMemcached(m.Name)
+	replicas := desiredReplicas(m)
+	partition := int32(0)
+
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name,
+			Namespace: m.Namespace,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: ls,
+			},
+			ServiceName: serviceName(m),
+			// Shards do not depend on each other, so there is no point in
+			// starting them in order.
+			PodManagementPolicy: appsv1.ParallelPodManagement,
+			// Rolling updates replace one pod at a time, highest ordinal
+			// first.
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
+					Partition: &partition,
+				},
+			},
+			Template: template,
+		},
+	}
+	// Set Memcached instance as the owner and controller
+	ctrl.SetControllerReference(m, sts, r.Scheme)
+	return sts
+}
+
+// deploymentWorkload runs the memcached pods from a Deployment.
+type deploymentWorkload struct {
+	*appsv1.Deployment
+}
+
+func (w deploymentWorkload) object() runtime.Object { return w.Deployment }
+
+func (w deploymentWorkload) meta() metav1.Object { return w.Deployment }
+
+func (w deploymentWorkload) kind() cachev1alpha1.WorkloadKind {
+	return cachev1alpha1.WorkloadDeployment
+}
+
+func (w deploymentWorkload) replicas() int32 {
+	if w.Spec.Replicas == nil {
+		return 1
+	}
+	return *w.Spec.Replicas
+}
+
+func (w deploymentWorkload) setReplicas(replicas int32) { w.Spec.Replicas = &replicas }
+
+func (w deploymentWorkload) specMatches(desired workload) bool {
+	d := desired.(deploymentWorkload)
+	return equality.Semantic.DeepDerivative(d.Spec.Template, w.Spec.Template) &&
+		equality.Semantic.DeepDerivative(d.Spec.Strategy, w.Spec.Strategy)
+}
+
+func (w deploymentWorkload) copySpec(desired workload) {
+	d := desired.(deploymentWorkload)
+	w.Spec.Template = d.Spec.Template
+	w.Spec.Strategy = d.Spec.Strategy
+}
+
+func (w deploymentWorkload) rolledOut() bool { return deploymentRolledOut(w.Deployment) }
+
+func (w deploymentWorkload) status() workloadStatus {
+	s := workloadStatus{
+		Replicas:        w.Status.Replicas,
+		ReadyReplicas:   w.Status.ReadyReplicas,
+		UpdatedReplicas: w.Status.UpdatedReplicas,
+	}
+	for _, c := range w.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Status == corev1.ConditionFalse && c.Reason == "ProgressDeadlineExceeded" {
+			s.FailureReason, s.FailureMessage = ReasonProgressDeadlineExceeded, c.Message
+			break
+		}
+		if c.Type == appsv1.DeploymentReplicaFailure && c.Status == corev1.ConditionTrue {
+			s.FailureReason, s.FailureMessage = ReasonReplicaFailure, c.Message
+			break
+		}
+	}
+	return s
+}
+
+// statefulSetWorkload runs the memcached pods from a StatefulSet.
+type statefulSetWorkload struct {
+	*appsv1.StatefulSet
+}
+
+func (w statefulSetWorkload) object() runtime.Object { return w.StatefulSet }
+
+func (w statefulSetWorkload) meta() metav1.Object { return w.StatefulSet }
+
+func (w statefulSetWorkload) kind() cachev1alpha1.WorkloadKind {
+	return cachev1alpha1.WorkloadStatefulSet
+}
+
+func (w statefulSetWorkload) replicas() int32 {
+	if w.Spec.Replicas == nil {
+		return 1
+	}
+	return *w.Spec.Replicas
+}
+
+func (w statefulSetWorkload) setReplicas(replicas int32) { w.Spec.Replicas = &replicas }
+
+func (w statefulSetWorkload) specMatches(desired workload) bool {
+	d := desired.(statefulSetWorkload)
+	return equality.Semantic.DeepDerivative(d.Spec.Template, w.Spec.Template) &&
+		equality.Semantic.DeepDerivative(d.Spec.UpdateStrategy, w.Spec.UpdateStrategy)
+}
+
+func (w statefulSetWorkload) copySpec(desired workload) {
+	d := desired.(statefulSetWorkload)
+	w.Spec.Template = d.Spec.Template
+	w.Spec.UpdateStrategy = d.Spec.UpdateStrategy
+}
+
+func (w statefulSetWorkload) rolledOut() bool {
+	if w.Generation > w.Status.ObservedGeneration {
+		return false
+	}
+	if w.Status.UpdateRevision != "" && w.Status.CurrentRevision != w.Status.UpdateRevision {
+		return false
+	}
+	replicas := w.replicas()
+	return w.Status.UpdatedReplicas == replicas && w.Status.Replicas == replicas
+}
+
+func (w statefulSetWorkload) status() workloadStatus {
+	// StatefulSets in this API version report no failure conditions
+	return workloadStatus{
+		Replicas:        w.Status.Replicas,
+		ReadyReplicas:   w.Status.ReadyReplicas,
+		UpdatedReplicas: w.Status.UpdatedReplicas,
+	}
+}
@@ -0,0 +1,74 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestSwitchToStatefulSet(t *testing.T) {
+	m := testMemcached()
+	m.Spec = cachev1alpha1.MemcachedSpec{Size: 2, WorkloadKind: cachev1alpha1.WorkloadStatefulSet}
+	key := keyOf(m)
+	// The instance used to run on a Deployment
+	dep := newTestReconciler().deploymentForMemcached(m, podTemplateForMemcached(m, nil, cachev1alpha1.DefaultPort, corev1.ResourceRequirements{}))
+	r := newTestReconciler(m, dep)
+
+	reconcileN := func(n int) {
+		for i := 0; i < n; i++ {
+			if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+		}
+	}
+	reconcileN(3)
+
+	svc := &corev1.Service{}
+	if err := r.Get(context.TODO(), key, svc); err != nil {
+		t.Fatalf("headless Service was not created: %v", err)
+	}
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		t.Errorf("Service is not headless: clusterIP %q", svc.Spec.ClusterIP)
+	}
+	sts := &appsv1.StatefulSet{}
+	if err := r.Get(context.TODO(), key, sts); err != nil {
+		t.Fatalf("StatefulSet was not created: %v", err)
+	}
+	if sts.Spec.ServiceName != svc.Name {
+		t.Errorf("StatefulSet serviceName = %q, want %q", sts.Spec.ServiceName, svc.Name)
+	}
+	// The Deployment keeps serving until the StatefulSet is ready
+	if err := r.Get(context.TODO(), key, &appsv1.Deployment{}); err != nil {
+		t.Fatalf("Deployment was removed before the StatefulSet was ready: %v", err)
+	}
+
+	sts.Status = appsv1.StatefulSetStatus{Replicas: 2, ReadyReplicas: 2, UpdatedReplicas: 2}
+	if err := r.Update(context.TODO(), sts); err != nil {
+		t.Fatal(err)
+	}
+	reconcileN(1)
+	if err := r.Get(context.TODO(), key, &appsv1.Deployment{}); !errors.IsNotFound(err) {
+		t.Fatalf("Deployment was not removed once the StatefulSet was ready: %v", err)
+	}
+}
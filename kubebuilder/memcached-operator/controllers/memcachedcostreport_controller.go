@@ -0,0 +1,248 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// MemcachedCostReportReconciler reconciles a MemcachedCostReport object
+type MemcachedCostReportReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=cache.example.com,resources=memcachedcostreports,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cache.example.com,resources=memcachedcostreports/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=cache.example.com,resources=memcacheds,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+func (r *MemcachedCostReportReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("memcachedcostreport", req.Name)
+
+	report := &cachev1alpha1.MemcachedCostReport{}
+	if err := r.Get(ctx, req.NamespacedName, report); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get MemcachedCostReport")
+		return ctrl.Result{}, err
+	}
+
+	status, err := r.costReportStatus(ctx, report)
+	if err != nil {
+		log.Error(err, "Failed to add up costs")
+		report.Status.LastError = err.Error()
+		if statusErr := r.Status().Update(ctx, report); statusErr != nil {
+			log.Error(statusErr, "Failed to update MemcachedCostReport status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Only bump the update time when the amounts change
+	status.LastUpdateTime = report.Status.LastUpdateTime
+	if reflect.DeepEqual(status, report.Status) {
+		return ctrl.Result{}, nil
+	}
+	now := metav1.Now()
+	status.LastUpdateTime = &now
+	report.Status = status
+	if err := r.Status().Update(ctx, report); err != nil {
+		log.Error(err, "Failed to update MemcachedCostReport status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// costReportStatus adds up the cost of every Memcached in the cluster for
+// report.
+func (r *MemcachedCostReportReconciler) costReportStatus(ctx context.Context, report *cachev1alpha1.MemcachedCostReport) (cachev1alpha1.MemcachedCostReportStatus, error) {
+	currency := report.Spec.Currency
+	if currency == "" {
+		currency = cachev1alpha1.DefaultReportCurrency
+	}
+	rates := map[string]*big.Rat{}
+	if ref := report.Spec.Rates; ref != nil {
+		cm := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, cm); err != nil {
+			return cachev1alpha1.MemcachedCostReportStatus{}, fmt.Errorf("rates ConfigMap %s/%s: %v", ref.Namespace, ref.Name, err)
+		}
+		var err error
+		if rates, err = parseRates(cm.Data); err != nil {
+			return cachev1alpha1.MemcachedCostReportStatus{}, fmt.Errorf("rates ConfigMap %s/%s: %v", ref.Namespace, ref.Name, err)
+		}
+	}
+	rates[currency] = big.NewRat(1, 1)
+
+	list := &cachev1alpha1.MemcachedList{}
+	if err := r.List(ctx, list); err != nil {
+		return cachev1alpha1.MemcachedCostReportStatus{}, err
+	}
+	teamLabel := report.Spec.TeamLabel
+	if teamLabel == "" {
+		teamLabel = cachev1alpha1.DefaultTeamLabel
+	}
+	return aggregateCosts(list.Items, currency, rates, teamLabel), nil
+}
+
+// parseRates reads the conversion rates of a rates ConfigMap.
+func parseRates(data map[string]string) (map[string]*big.Rat, error) {
+	rates := map[string]*big.Rat{}
+	for currency, value := range data {
+		rate, err := cachev1alpha1.ParseAmount(strings.TrimSpace(value))
+		if err != nil || rate.Sign() == 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", value, currency)
+		}
+		rates[currency] = rate
+	}
+	return rates, nil
+}
+
+// aggregateCosts adds up the status.cost of memcacheds in currency, per
+// namespace and per value of teamLabel.
+func aggregateCosts(memcacheds []cachev1alpha1.Memcached, currency string, rates map[string]*big.Rat, teamLabel string) cachev1alpha1.MemcachedCostReportStatus {
+	type group struct {
+		amount    *big.Rat
+		instances int32
+	}
+	total := new(big.Rat)
+	var instances int32
+	namespaces := map[string]*group{}
+	teams := map[string]*group{}
+	unconverted := map[string]bool{}
+	add := func(groups map[string]*group, name string, amount *big.Rat) {
+		g, ok := groups[name]
+		if !ok {
+			g = &group{amount: new(big.Rat)}
+			groups[name] = g
+		}
+		g.amount.Add(g.amount, amount)
+		g.instances++
+	}
+
+	for i := range memcacheds {
+		m := &memcacheds[i]
+		cost := m.Status.Cost
+		if cost == nil {
+			continue
+		}
+		rate, ok := rates[cost.Currency]
+		if !ok {
+			unconverted[cost.Currency] = true
+			continue
+		}
+		amount, ok := new(big.Rat).SetString(cost.MonthlyAmount)
+		if !ok {
+			continue
+		}
+		amount.Mul(amount, rate)
+		total.Add(total, amount)
+		instances++
+		add(namespaces, m.Namespace, amount)
+		if team := m.Labels[teamLabel]; team != "" {
+			add(teams, team, amount)
+		}
+	}
+
+	entries := func(groups map[string]*group) []cachev1alpha1.MemcachedCostEntry {
+		var list []cachev1alpha1.MemcachedCostEntry
+		for name, g := range groups {
+			list = append(list, cachev1alpha1.MemcachedCostEntry{
+				Name:          name,
+				MonthlyAmount: formatAmount(g.amount),
+				Instances:     g.instances,
+			})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+		return list
+	}
+	status := cachev1alpha1.MemcachedCostReportStatus{
+		Currency:     currency,
+		MonthlyTotal: formatAmount(total),
+		Instances:    instances,
+		Namespaces:   entries(namespaces),
+		Teams:        entries(teams),
+	}
+	for c := range unconverted {
+		status.UnconvertedCurrencies = append(status.UnconvertedCurrencies, c)
+	}
+	sort.Strings(status.UnconvertedCurrencies)
+	return status
+}
+
+// allCostReports maps any Memcached to every MemcachedCostReport, as each
+// report covers the whole cluster.
+func (r *MemcachedCostReportReconciler) allCostReports(o handler.MapObject) []reconcile.Request {
+	return r.costReports(func(*cachev1alpha1.MemcachedCostReport) bool { return true })
+}
+
+// costReportsForRates maps a ConfigMap to the MemcachedCostReports that
+// take their rates from it.
+func (r *MemcachedCostReportReconciler) costReportsForRates(o handler.MapObject) []reconcile.Request {
+	return r.costReports(func(report *cachev1alpha1.MemcachedCostReport) bool {
+		ref := report.Spec.Rates
+		return ref != nil && ref.Namespace == o.Meta.GetNamespace() && ref.Name == o.Meta.GetName()
+	})
+}
+
+// costReports returns requests for the MemcachedCostReports that match.
+func (r *MemcachedCostReportReconciler) costReports(match func(*cachev1alpha1.MemcachedCostReport) bool) []reconcile.Request {
+	list := &cachev1alpha1.MemcachedCostReportList{}
+	if err := r.List(context.Background(), list); err != nil {
+		r.Log.Error(err, "Failed to list MemcachedCostReports")
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range list.Items {
+		if match(&list.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: list.Items[i].Name}})
+		}
+	}
+	return requests
+}
+
+func (r *MemcachedCostReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&cachev1alpha1.MemcachedCostReport{}).
+		Watches(&source.Kind{Type: &cachev1alpha1.Memcached{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.allCostReports),
+		}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.costReportsForRates),
+		}).
+		Complete(r)
+}
@@ -0,0 +1,82 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// MemcachedPolicyReconciler reconciles a MemcachedPolicy object. It reports
+// on its status whether the policy can be checked, since the admission
+// checks skip policies that can't.
+type MemcachedPolicyReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// Policies validates the policies.
+	Policies cachev1alpha1.PolicyChecker
+}
+
+// +kubebuilder:rbac:groups=cache.example.com,resources=memcachedpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cache.example.com,resources=memcachedpolicies/status,verbs=get;update;patch
+
+func (r *MemcachedPolicyReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("memcachedpolicy", req.Name)
+
+	p := &cachev1alpha1.MemcachedPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, p); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get MemcachedPolicy")
+		return ctrl.Result{}, err
+	}
+
+	status := cachev1alpha1.MemcachedPolicyStatus{ObservedGeneration: p.Generation}
+	for _, err := range r.Policies.ValidatePolicy(&p.Spec, field.NewPath("spec")) {
+		status.Errors = append(status.Errors, err.Error())
+	}
+	if reflect.DeepEqual(status, p.Status) {
+		return ctrl.Result{}, nil
+	}
+	if len(status.Errors) > 0 {
+		log.Info("MemcachedPolicy can't be checked, skipping it", "errors", status.Errors)
+	}
+	p.Status = status
+	if err := r.Status().Update(ctx, p); err != nil {
+		log.Error(err, "Failed to update MemcachedPolicy status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *MemcachedPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&cachev1alpha1.MemcachedPolicy{}).
+		Complete(r)
+}
@@ -0,0 +1,65 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+	"github.com/example-inc/memcached-operator/policy"
+)
+
+func TestMemcachedPolicyStatus(t *testing.T) {
+	p := &cachev1alpha1.MemcachedPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "size", Generation: 2},
+		Spec: cachev1alpha1.MemcachedPolicySpec{
+			Rules: []cachev1alpha1.MemcachedPolicyRule{{Expression: "spec.size >"}},
+		},
+	}
+	mr := newTestReconciler(p)
+	r := &MemcachedPolicyReconciler{Client: mr.Client, Scheme: mr.Scheme, Log: ctrl.Log, Policies: &policy.Checker{Log: ctrl.Log}}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "size"}}
+
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if err := r.Get(context.TODO(), req.NamespacedName, p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status.ObservedGeneration != 2 || len(p.Status.Errors) != 1 {
+		t.Errorf("expected the broken rule on the status, got %+v", p.Status)
+	}
+
+	p.Spec.Rules[0].Expression = "spec.size <= 9"
+	if err := r.Update(context.TODO(), p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	fixed := &cachev1alpha1.MemcachedPolicy{}
+	if err := r.Get(context.TODO(), req.NamespacedName, fixed); err != nil {
+		t.Fatal(err)
+	}
+	if len(fixed.Status.Errors) != 0 {
+		t.Errorf("expected no errors once the rule is fixed, got %v", fixed.Status.Errors)
+	}
+}
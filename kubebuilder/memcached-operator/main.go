@@ -17,20 +17,30 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	kcachev1alpha1 "k8s.io/api/batch/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
 	cachev1alpha2 "github.com/example-inc/memcached-operator/api/v1alpha2"
+	cachev1beta1 "github.com/example-inc/memcached-operator/api/v1beta1"
 	"github.com/example-inc/memcached-operator/controllers"
+	"github.com/example-inc/memcached-operator/migration"
+	"github.com/example-inc/memcached-operator/policy"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -45,16 +55,28 @@ func init() {
 	utilruntime.Must(kcachev1alpha1.AddToScheme(scheme)) // we've added this ourselves
 	utilruntime.Must(cachev1alpha1.AddToScheme(scheme))
 	utilruntime.Must(cachev1alpha2.AddToScheme(scheme))
+	utilruntime.Must(cachev1beta1.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate-storage" {
+		os.Exit(migrateStorage(os.Args[2:]))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
+	var statsInterval time.Duration
+	var policyConfigMap string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&statsInterval, "stats-interval", 30*time.Second,
+		"How often the memcached pods are polled for their statistics. 0 turns polling off.")
+	flag.StringVar(&policyConfigMap, "policy-configmap", "",
+		"The namespace/name of a ConfigMap with admission policies for Memcacheds, in addition to the MemcachedPolicies.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -71,18 +93,56 @@ func main() {
 		os.Exit(1)
 	}
 
+	checker := &policy.Checker{
+		Reader: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("policy"),
+	}
+	if policyConfigMap != "" {
+		parts := strings.SplitN(policyConfigMap, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			setupLog.Error(fmt.Errorf("got %q", policyConfigMap), "--policy-configmap must have the form namespace/name")
+			os.Exit(1)
+		}
+		checker.ConfigMap = &types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+	}
+	cachev1alpha1.SetPolicyChecker(checker)
 	if err = (&controllers.MemcachedReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("Memcached"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("memcached-controller"),
+
+		StatsInterval: statsInterval,
+		Policies:      checker,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Memcached")
+		os.Exit(1)
+	}
+	if err = (&controllers.MemcachedCostReportReconciler{
 		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Memcached"),
+		Log:    ctrl.Log.WithName("controllers").WithName("MemcachedCostReport"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Memcached")
+		setupLog.Error(err, "unable to create controller", "controller", "MemcachedCostReport")
+		os.Exit(1)
+	}
+	if err = (&controllers.MemcachedPolicyReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("MemcachedPolicy"),
+		Scheme:   mgr.GetScheme(),
+		Policies: checker,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MemcachedPolicy")
 		os.Exit(1)
 	}
 	if err = (&cachev1alpha1.Memcached{}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Memcached")
 		os.Exit(1)
 	}
+	if err = (&cachev1alpha1.MemcachedPolicy{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "MemcachedPolicy")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -91,3 +151,41 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// migrateStorage runs the migrate-storage subcommand, which rewrites every
+// Memcached so it is stored in the current storage version, and returns the
+// exit code. It is safe to run again until it succeeds.
+func migrateStorage(args []string) int {
+	flags := flag.NewFlagSet("migrate-storage", flag.ExitOnError)
+	crd := flags.String("crd", "memcacheds.cache.example.com", "The CustomResourceDefinition whose objects are migrated.")
+	pageSize := flags.Int64("page-size", migration.DefaultPageSize, "The number of objects listed at a time.")
+	flags.Parse(args)
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		setupLog.Error(err, "unable to create client")
+		return 1
+	}
+	m := &migration.Migrator{
+		Client:   c,
+		Scheme:   scheme,
+		Log:      ctrl.Log.WithName("migration"),
+		CRD:      *crd,
+		PageSize: *pageSize,
+	}
+	result, err := m.Run(context.Background())
+	if err != nil {
+		setupLog.Error(err, "storage migration failed")
+		return 1
+	}
+	fmt.Printf("Migrated %d objects to %s, %d failed\n", result.Migrated, result.StorageVersion, len(result.Failures))
+	for key, err := range result.Failures {
+		fmt.Printf("  %s: %v\n", key, err)
+	}
+	if len(result.Failures) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,249 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package memcache is a client for a single memcached server. It speaks the
+// text protocol, including the meta commands of memcached 1.6, over plain
+// TCP or TLS and keeps a small pool of idle connections.
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout is used when Options.Timeout is not set.
+	DefaultTimeout = 2 * time.Second
+	// DefaultMaxIdleConns is used when Options.MaxIdleConns is not set.
+	DefaultMaxIdleConns = 2
+	// maxKeyLength is the longest key memcached accepts.
+	maxKeyLength = 250
+)
+
+var (
+	// ErrCacheMiss means a get or delete found no item with the key.
+	ErrCacheMiss = errors.New("memcache: cache miss")
+	// ErrNotStored means a conditional store did not happen.
+	ErrNotStored = errors.New("memcache: item not stored")
+	// ErrMalformedKey means a key is too long or contains spaces or
+	// control characters.
+	ErrMalformedKey = errors.New("memcache: malformed key")
+	// ErrUnknownCommand means the server did not understand the command,
+	// for example a meta command sent to a memcached older than 1.6.
+	ErrUnknownCommand = errors.New("memcache: unknown command")
+	// ErrClosed means the client was closed.
+	ErrClosed = errors.New("memcache: client closed")
+)
+
+// ServerError is an error reported by the server, as SERVER_ERROR or
+// CLIENT_ERROR.
+type ServerError struct {
+	// Kind is SERVER_ERROR or CLIENT_ERROR.
+	Kind    string
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("memcache: %s %s", e.Kind, e.Message)
+}
+
+// Options tune a Client.
+type Options struct {
+	// Timeout bounds dialing and every command, on top of the deadline of
+	// the context. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// MaxIdleConns is the number of idle connections kept for reuse.
+	// Defaults to DefaultMaxIdleConns.
+	MaxIdleConns int
+	// TLSConfig makes the client connect over TLS when set.
+	TLSConfig *tls.Config
+}
+
+// Client talks to the memcached server at one address. It is safe for
+// concurrent use.
+type Client struct {
+	addr string
+	opts Options
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+// conn is a connection to the server with its buffered reader and writer.
+type conn struct {
+	nc net.Conn
+	rw *bufio.ReadWriter
+}
+
+// New returns a client for the memcached server at addr, a host:port pair.
+// Connections are opened as needed.
+func New(addr string, opts Options) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = DefaultMaxIdleConns
+	}
+	return &Client{addr: addr, opts: opts}
+}
+
+// Close closes the idle connections. Connections in use are closed when
+// they are released.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, cn := range c.idle {
+		cn.nc.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+// deadline returns when a command started now must finish.
+func (c *Client) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(c.opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// getConn returns an idle connection or dials a new one.
+func (c *Client) getConn(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithDeadline(ctx, c.deadline(ctx))
+	defer cancel()
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.TLSConfig != nil {
+		config := c.opts.TLSConfig.Clone()
+		if config.ServerName == "" && !config.InsecureSkipVerify {
+			host, _, err := net.SplitHostPort(c.addr)
+			if err != nil {
+				nc.Close()
+				return nil, err
+			}
+			config.ServerName = host
+		}
+		tc := tls.Client(nc, config)
+		if deadline, ok := ctx.Deadline(); ok {
+			tc.SetDeadline(deadline)
+		}
+		if err := tc.Handshake(); err != nil {
+			nc.Close()
+			return nil, err
+		}
+		nc = tc
+	}
+	return &conn{nc: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))}, nil
+}
+
+// putConn returns a healthy connection to the pool, or closes it when the
+// pool is full.
+func (c *Client) putConn(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.idle) >= c.opts.MaxIdleConns {
+		cn.nc.Close()
+		return
+	}
+	c.idle = append(c.idle, cn)
+}
+
+// do runs fn on a pooled connection with the command deadline set. The
+// connection is only reused when fn leaves it in a known state, that is when
+// it returns nil or one of the errors the protocol reports in band.
+func (c *Client) do(ctx context.Context, fn func(rw *bufio.ReadWriter) error) error {
+	cn, err := c.getConn(ctx)
+	if err != nil {
+		return err
+	}
+	if err := cn.nc.SetDeadline(c.deadline(ctx)); err != nil {
+		cn.nc.Close()
+		return err
+	}
+	err = fn(cn.rw)
+	switch err {
+	case nil, ErrCacheMiss, ErrNotStored, ErrCASConflict:
+		c.putConn(cn)
+	default:
+		cn.nc.Close()
+	}
+	return err
+}
+
+// checkKey fails for keys memcached would not accept.
+func checkKey(key string) error {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return ErrMalformedKey
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return ErrMalformedKey
+		}
+	}
+	return nil
+}
+
+// writeCommand writes one command line and flushes it.
+func writeCommand(rw *bufio.ReadWriter, format string, args ...interface{}) error {
+	if _, err := fmt.Fprintf(rw, format+"\r\n", args...); err != nil {
+		return err
+	}
+	return rw.Flush()
+}
+
+// readLine reads one reply line without its line ending. Error replies are
+// turned into errors.
+func readLine(rw *bufio.ReadWriter) (string, error) {
+	line, err := rw.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	switch {
+	case line == "ERROR":
+		return "", ErrUnknownCommand
+	case strings.HasPrefix(line, "SERVER_ERROR"):
+		return "", &ServerError{Kind: "SERVER_ERROR", Message: strings.TrimSpace(strings.TrimPrefix(line, "SERVER_ERROR"))}
+	case strings.HasPrefix(line, "CLIENT_ERROR"):
+		return "", &ServerError{Kind: "CLIENT_ERROR", Message: strings.TrimSpace(strings.TrimPrefix(line, "CLIENT_ERROR"))}
+	}
+	return line, nil
+}
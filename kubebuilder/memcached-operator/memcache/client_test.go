@@ -0,0 +1,312 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memcache_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/example-inc/memcached-operator/memcache"
+	"github.com/example-inc/memcached-operator/memcache/memcachetest"
+)
+
+func TestClientText(t *testing.T) {
+	s := memcachetest.NewServer()
+	defer s.Close()
+	c := memcache.New(s.Addr(), memcache.Options{})
+	defer c.Close()
+	ctx := context.TODO()
+
+	if version, err := c.Version(ctx); err != nil || version != memcachetest.Version {
+		t.Errorf("Version() = %q, %v", version, err)
+	}
+	if _, err := c.Get(ctx, "key"); err != memcache.ErrCacheMiss {
+		t.Errorf("Get() of a missing key: %v, want ErrCacheMiss", err)
+	}
+	if err := c.Set(ctx, &memcache.Item{Key: "key", Value: []byte("a\r\nb"), Flags: 7}); err != nil {
+		t.Fatal(err)
+	}
+	item, err := c.Get(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(item.Value) != "a\r\nb" || item.Flags != 7 || item.CAS == 0 {
+		t.Errorf("unexpected item %+v", item)
+	}
+
+	// A stale CAS value conflicts
+	stale := *item
+	item.Value = []byte("c")
+	if err := c.CompareAndSwap(ctx, item); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CompareAndSwap(ctx, &stale); err != memcache.ErrCASConflict {
+		t.Errorf("CompareAndSwap() with a stale CAS: %v, want ErrCASConflict", err)
+	}
+
+	if err := c.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, "key"); err != memcache.ErrCacheMiss {
+		t.Errorf("Delete() of a missing key: %v, want ErrCacheMiss", err)
+	}
+
+	if err := c.Set(ctx, &memcache.Item{Key: "other", Value: []byte("x")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.FlushAll(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "other"); err != memcache.ErrCacheMiss {
+		t.Errorf("Get() after FlushAll(): %v, want ErrCacheMiss", err)
+	}
+
+	for _, key := range []string{"", "with space", "new\nline", string(make([]byte, 251))} {
+		if err := c.Set(ctx, &memcache.Item{Key: key}); err != memcache.ErrMalformedKey {
+			t.Errorf("Set() with key %q: %v, want ErrMalformedKey", key, err)
+		}
+	}
+}
+
+func TestClientStats(t *testing.T) {
+	s := memcachetest.NewServer()
+	defer s.Close()
+	c := memcache.New(s.Addr(), memcache.Options{})
+	defer c.Close()
+	ctx := context.TODO()
+
+	if err := c.Set(ctx, &memcache.Item{Key: "key", Value: []byte("value")}); err != nil {
+		t.Fatal(err)
+	}
+	c.Get(ctx, "key")
+	c.Get(ctx, "missing")
+	stats, err := c.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["get_hits"] != "1" || stats["get_misses"] != "1" || stats["curr_items"] != "1" || stats["version"] != memcachetest.Version {
+		t.Errorf("unexpected stats %v", stats)
+	}
+
+	s.SetStats("slabs", map[string]string{"total_malloced": "42"})
+	if slabs, err := c.Stats(ctx, "slabs"); err != nil || len(slabs) != 1 || slabs["total_malloced"] != "42" {
+		t.Errorf("Stats(slabs) = %v, %v", slabs, err)
+	}
+	if _, err := c.Stats(ctx, "nonsense"); err != memcache.ErrUnknownCommand {
+		t.Errorf("Stats(nonsense): %v, want ErrUnknownCommand", err)
+	}
+}
+
+func TestClientMetaDump(t *testing.T) {
+	s := memcachetest.NewServer()
+	defer s.Close()
+	c := memcache.New(s.Addr(), memcache.Options{})
+	defer c.Close()
+	ctx := context.TODO()
+
+	for _, item := range []*memcache.Item{
+		{Key: "b", Value: []byte("1")},
+		{Key: "a%2F", Value: []byte("22"), Expiration: 60},
+	} {
+		if err := c.Set(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Get(ctx, "b")
+	entries, err := c.MetaDump(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("MetaDump() = %+v, want 2 entries", entries)
+	}
+	if e := entries[0]; e.Key != "a%2F" || e.Expiration <= time.Now().Unix() || e.Fetched || e.SlabClass != 1 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if e := entries[1]; e.Key != "b" || e.Expiration != -1 || !e.Fetched || e.CAS == 0 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+func TestClientMeta(t *testing.T) {
+	s := memcachetest.NewServer()
+	defer s.Close()
+	c := memcache.New(s.Addr(), memcache.Options{})
+	defer c.Close()
+	ctx := context.TODO()
+
+	if err := c.MetaNoop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MetaGet(ctx, "key", "v"); err != memcache.ErrCacheMiss {
+		t.Errorf("MetaGet() of a missing key: %v, want ErrCacheMiss", err)
+	}
+	resp, err := c.MetaSet(ctx, "key", []byte("value"), "T60", "F3", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cas, ok := resp.Flag('c')
+	if resp.Status != "HD" || !ok {
+		t.Fatalf("unexpected reply to MetaSet() %+v", resp)
+	}
+
+	resp, err = c.MetaGet(ctx, "key", "v", "f", "t", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "VA" || string(resp.Value) != "value" {
+		t.Errorf("unexpected reply to MetaGet() %+v", resp)
+	}
+	if flags, _ := resp.Flag('f'); flags != "3" {
+		t.Errorf("flags = %q, want 3", flags)
+	}
+	if ttl, _ := resp.Flag('t'); ttl != "59" && ttl != "60" {
+		t.Errorf("ttl = %q, want 60", ttl)
+	}
+	if got, _ := resp.Flag('c'); got != cas {
+		t.Errorf("cas = %q, want %q", got, cas)
+	}
+
+	if _, err := c.MetaSet(ctx, "key", []byte("new"), "C0"); err != memcache.ErrCASConflict {
+		t.Errorf("MetaSet() with a stale CAS: %v, want ErrCASConflict", err)
+	}
+	if _, err := c.MetaDelete(ctx, "key", "C"+cas); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MetaDelete(ctx, "key"); err != memcache.ErrCacheMiss {
+		t.Errorf("MetaDelete() of a missing key: %v, want ErrCacheMiss", err)
+	}
+}
+
+func TestClientPool(t *testing.T) {
+	s := memcachetest.NewServer()
+	defer s.Close()
+	c := memcache.New(s.Addr(), memcache.Options{MaxIdleConns: 1})
+	ctx := context.TODO()
+
+	for i := 0; i < 5; i++ {
+		if _, err := c.Get(ctx, "key"); err != memcache.ErrCacheMiss {
+			t.Fatal(err)
+		}
+	}
+	if n := s.Accepted(); n != 1 {
+		t.Errorf("%d connections opened, want 1", n)
+	}
+
+	// An unknown command leaves the connection in doubt, so it is dropped
+	if _, err := c.Stats(ctx, "nonsense"); err != memcache.ErrUnknownCommand {
+		t.Fatal(err)
+	}
+	if _, err := c.Version(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Accepted(); n != 2 {
+		t.Errorf("%d connections opened, want 2", n)
+	}
+
+	c.Close()
+	if _, err := c.Version(ctx); err != memcache.ErrClosed {
+		t.Errorf("Version() after Close(): %v, want ErrClosed", err)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	s := memcachetest.NewServer()
+	defer s.Close()
+	s.SetDelay(time.Second)
+	c := memcache.New(s.Addr(), memcache.Options{Timeout: 50 * time.Millisecond})
+	defer c.Close()
+
+	start := time.Now()
+	_, err := c.Version(context.TODO())
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Errorf("Version() of a slow server: %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Version() took %s", elapsed)
+	}
+
+	// The context deadline applies when it is sooner
+	c = memcache.New(s.Addr(), memcache.Options{Timeout: time.Minute})
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Version(ctx); err == nil {
+		t.Error("Version() outlived its context")
+	}
+}
+
+func TestClientTLS(t *testing.T) {
+	cert, pool := selfSignedCertificate(t)
+	s := memcachetest.NewUnstartedServer()
+	s.StartTLS(&tls.Config{Certificates: []tls.Certificate{cert}})
+	defer s.Close()
+	ctx := context.TODO()
+
+	c := memcache.New(s.Addr(), memcache.Options{TLSConfig: &tls.Config{RootCAs: pool}})
+	defer c.Close()
+	if _, err := c.Version(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The certificate is checked against the trusted roots
+	untrusted := memcache.New(s.Addr(), memcache.Options{TLSConfig: &tls.Config{}})
+	defer untrusted.Close()
+	if _, err := untrusted.Version(ctx); err == nil {
+		t.Error("Version() trusted an unknown certificate")
+	}
+}
+
+// selfSignedCertificate returns a certificate for 127.0.0.1 and a pool that
+// trusts it.
+func selfSignedCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "memcached"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
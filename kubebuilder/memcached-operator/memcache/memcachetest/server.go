@@ -0,0 +1,619 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package memcachetest provides an in-memory memcached server for tests.
+// It speaks enough of the text and meta protocols for the memcache client:
+// version, stats, flush_all, get, gets, set, cas, delete,
+// lru_crawler metadump, mg, ms, md, mn and quit.
+package memcachetest
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Version is the release the server reports.
+	Version = "1.6.9"
+	// LimitMaxBytes is the memory limit the server reports.
+	LimitMaxBytes = 64 * 1024 * 1024
+	// maxRelativeExpiration is the longest lifetime memcached reads as a
+	// number of seconds rather than a Unix time.
+	maxRelativeExpiration = 30 * 24 * 60 * 60
+)
+
+// item is a stored value.
+type item struct {
+	value      []byte
+	flags      uint32
+	expiration time.Time
+	cas        uint64
+	fetched    bool
+	lastAccess time.Time
+}
+
+// Server is an in-memory memcached server listening on a local port.
+type Server struct {
+	ln net.Listener
+	wg sync.WaitGroup
+
+	mu       sync.Mutex
+	items    map[string]*item
+	cas      uint64
+	flushAt  time.Time
+	hits     int64
+	misses   int64
+	conns    map[net.Conn]struct{}
+	accepted int
+	stats    map[string]map[string]string
+	delay    time.Duration
+	closed   bool
+}
+
+// NewServer starts a server on a local port. Close it when done.
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a server that listens on a local port but does
+// not answer until Start or StartTLS is called.
+func NewUnstartedServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("memcachetest: failed to listen: %v", err))
+	}
+	return &Server{
+		ln:    ln,
+		items: map[string]*item{},
+		conns: map[net.Conn]struct{}{},
+		stats: map[string]map[string]string{},
+	}
+}
+
+// Start serves the plain text protocol.
+func (s *Server) Start() {
+	s.wg.Add(1)
+	go s.serve()
+}
+
+// StartTLS serves the text protocol over TLS with config.
+func (s *Server) StartTLS(config *tls.Config) {
+	s.ln = tls.NewListener(s.ln, config)
+	s.Start()
+}
+
+// Addr returns the host:port address the server listens on.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Accepted returns the number of connections accepted so far.
+func (s *Server) Accepted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+// SetStats makes "stats <group>" reply with exactly stats instead of the
+// statistics of the server. The empty group is plain "stats".
+func (s *Server) SetStats(group string, stats map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats[group] = stats
+}
+
+// SetDelay makes the server wait for delay before every reply.
+func (s *Server) SetDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = delay
+}
+
+// Close stops the server and closes its connections.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.accepted++
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(c)
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+			c.Close()
+		}()
+	}
+}
+
+// handle serves the commands of one connection until it is closed or quits.
+func (s *Server) handle(c net.Conn) {
+	rw := bufio.NewReadWriter(bufio.NewReader(c), bufio.NewWriter(c))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(strings.TrimRight(line, "\r\n"))
+		if len(fields) == 0 {
+			fmt.Fprint(rw, "ERROR\r\n")
+		} else if fields[0] == "quit" {
+			return
+		} else if err := s.command(rw, fields); err != nil {
+			return
+		}
+		s.mu.Lock()
+		delay := s.delay
+		s.mu.Unlock()
+		time.Sleep(delay)
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// command answers one command. It only fails when the connection breaks.
+func (s *Server) command(rw *bufio.ReadWriter, fields []string) error {
+	switch fields[0] {
+	case "version":
+		fmt.Fprintf(rw, "VERSION %s\r\n", Version)
+	case "stats":
+		s.statsCommand(rw, strings.Join(fields[1:], " "))
+	case "flush_all":
+		s.flushAll(rw, fields[1:])
+	case "get", "gets":
+		s.get(rw, fields[1:], fields[0] == "gets")
+	case "set", "cas":
+		return s.store(rw, fields)
+	case "delete":
+		s.delete(rw, fields[1:])
+	case "lru_crawler":
+		if len(fields) != 3 || fields[1] != "metadump" || fields[2] != "all" {
+			fmt.Fprint(rw, "CLIENT_ERROR bad command line format\r\n")
+			return nil
+		}
+		s.metaDump(rw)
+	case "mg":
+		s.metaGet(rw, fields[1:])
+	case "ms":
+		return s.metaSet(rw, fields[1:])
+	case "md":
+		s.metaDelete(rw, fields[1:])
+	case "mn":
+		fmt.Fprint(rw, "MN\r\n")
+	default:
+		fmt.Fprint(rw, "ERROR\r\n")
+	}
+	return nil
+}
+
+// lookup returns the live item with key, dropping it once expired. It is
+// called with s.mu held.
+func (s *Server) lookup(key string) *item {
+	now := time.Now()
+	if !s.flushAt.IsZero() && !now.Before(s.flushAt) {
+		s.items = map[string]*item{}
+		s.flushAt = time.Time{}
+	}
+	it, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+	if !it.expiration.IsZero() && !now.Before(it.expiration) {
+		delete(s.items, key)
+		return nil
+	}
+	return it
+}
+
+// put stores value under key and returns the new item. It is called with
+// s.mu held.
+func (s *Server) put(key string, value []byte, flags uint32, expiration int64) *item {
+	s.lookup(key)
+	s.cas++
+	it := &item{value: value, flags: flags, cas: s.cas, lastAccess: time.Now()}
+	switch {
+	case expiration < 0:
+		it.expiration = time.Now()
+	case expiration > maxRelativeExpiration:
+		it.expiration = time.Unix(expiration, 0)
+	case expiration > 0:
+		it.expiration = time.Now().Add(time.Duration(expiration) * time.Second)
+	}
+	s.items[key] = it
+	return it
+}
+
+func (s *Server) statsCommand(rw *bufio.ReadWriter, group string) {
+	s.mu.Lock()
+	stats, ok := s.stats[group]
+	if !ok {
+		stats = s.currentStats(group)
+	}
+	s.mu.Unlock()
+	if stats == nil {
+		fmt.Fprint(rw, "ERROR\r\n")
+		return
+	}
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(rw, "STAT %s %s\r\n", name, stats[name])
+	}
+	fmt.Fprint(rw, "END\r\n")
+}
+
+// currentStats returns the statistics of group, or nil for an unknown group.
+// It is called with s.mu held.
+func (s *Server) currentStats(group string) map[string]string {
+	var bytes, count int
+	for key := range s.items {
+		if it := s.lookup(key); it != nil {
+			bytes += len(key) + len(it.value)
+			count++
+		}
+	}
+	switch group {
+	case "":
+		return map[string]string{
+			"pid":               strconv.Itoa(os.Getpid()),
+			"version":           Version,
+			"curr_connections":  strconv.Itoa(len(s.conns)),
+			"total_connections": strconv.Itoa(s.accepted),
+			"get_hits":          strconv.FormatInt(s.hits, 10),
+			"get_misses":        strconv.FormatInt(s.misses, 10),
+			"evictions":         "0",
+			"curr_items":        strconv.Itoa(count),
+			"bytes":             strconv.Itoa(bytes),
+			"limit_maxbytes":    strconv.Itoa(LimitMaxBytes),
+		}
+	case "slabs":
+		return map[string]string{
+			"active_slabs":   "1",
+			"total_malloced": strconv.Itoa(1024 * 1024),
+		}
+	case "items":
+		// Every item lives in slab class 1
+		if count == 0 {
+			return map[string]string{}
+		}
+		oldest := time.Now()
+		for _, it := range s.items {
+			if it.lastAccess.Before(oldest) {
+				oldest = it.lastAccess
+			}
+		}
+		return map[string]string{
+			"items:1:number":  strconv.Itoa(count),
+			"items:1:age":     strconv.Itoa(int(time.Since(oldest).Seconds())),
+			"items:1:evicted": "0",
+		}
+	}
+	return nil
+}
+
+func (s *Server) flushAll(rw *bufio.ReadWriter, args []string) {
+	delay := 0
+	if len(args) > 0 {
+		var err error
+		if delay, err = strconv.Atoi(args[0]); err != nil || delay < 0 {
+			fmt.Fprint(rw, "CLIENT_ERROR bad command line format\r\n")
+			return
+		}
+	}
+	s.mu.Lock()
+	s.flushAt = time.Now().Add(time.Duration(delay) * time.Second)
+	s.mu.Unlock()
+	fmt.Fprint(rw, "OK\r\n")
+}
+
+func (s *Server) get(rw *bufio.ReadWriter, keys []string, withCAS bool) {
+	if len(keys) == 0 {
+		fmt.Fprint(rw, "ERROR\r\n")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		it := s.lookup(key)
+		if it == nil {
+			s.misses++
+			continue
+		}
+		s.hits++
+		it.fetched = true
+		it.lastAccess = time.Now()
+		if withCAS {
+			fmt.Fprintf(rw, "VALUE %s %d %d %d\r\n", key, it.flags, len(it.value), it.cas)
+		} else {
+			fmt.Fprintf(rw, "VALUE %s %d %d\r\n", key, it.flags, len(it.value))
+		}
+		rw.Write(it.value)
+		fmt.Fprint(rw, "\r\n")
+	}
+	fmt.Fprint(rw, "END\r\n")
+}
+
+// readData reads a data block of size bytes and its line ending. It returns
+// false, after replying, when the block is malformed.
+func readData(rw *bufio.ReadWriter, size int) ([]byte, bool, error) {
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(rw, data); err != nil {
+		return nil, false, err
+	}
+	if string(data[size:]) != "\r\n" {
+		fmt.Fprint(rw, "CLIENT_ERROR bad data chunk\r\n")
+		return nil, false, nil
+	}
+	return data[:size], true, nil
+}
+
+func (s *Server) store(rw *bufio.ReadWriter, fields []string) error {
+	want := 5
+	if fields[0] == "cas" {
+		want = 6
+	}
+	if len(fields) != want {
+		fmt.Fprint(rw, "ERROR\r\n")
+		return nil
+	}
+	flags, err1 := strconv.ParseUint(fields[2], 10, 32)
+	expiration, err2 := strconv.ParseInt(fields[3], 10, 64)
+	size, err3 := strconv.Atoi(fields[4])
+	if err1 != nil || err2 != nil || err3 != nil || size < 0 {
+		fmt.Fprint(rw, "CLIENT_ERROR bad command line format\r\n")
+		return nil
+	}
+	var cas uint64
+	if fields[0] == "cas" {
+		var err error
+		if cas, err = strconv.ParseUint(fields[5], 10, 64); err != nil {
+			fmt.Fprint(rw, "CLIENT_ERROR bad command line format\r\n")
+			return nil
+		}
+	}
+	value, ok, err := readData(rw, size)
+	if !ok {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fields[0] == "cas" {
+		it := s.lookup(fields[1])
+		if it == nil {
+			fmt.Fprint(rw, "NOT_FOUND\r\n")
+			return nil
+		}
+		if it.cas != cas {
+			fmt.Fprint(rw, "EXISTS\r\n")
+			return nil
+		}
+	}
+	s.put(fields[1], value, uint32(flags), expiration)
+	fmt.Fprint(rw, "STORED\r\n")
+	return nil
+}
+
+func (s *Server) delete(rw *bufio.ReadWriter, args []string) {
+	if len(args) != 1 {
+		fmt.Fprint(rw, "ERROR\r\n")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lookup(args[0]) == nil {
+		fmt.Fprint(rw, "NOT_FOUND\r\n")
+		return
+	}
+	delete(s.items, args[0])
+	fmt.Fprint(rw, "DELETED\r\n")
+}
+
+func (s *Server) metaDump(rw *bufio.ReadWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.items))
+	for key := range s.items {
+		if s.lookup(key) != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		it := s.items[key]
+		exp := int64(-1)
+		if !it.expiration.IsZero() {
+			exp = it.expiration.Unix()
+		}
+		fetch := "no"
+		if it.fetched {
+			fetch = "yes"
+		}
+		fmt.Fprintf(rw, "key=%s exp=%d la=%d cas=%d fetch=%s cls=1 size=%d\r\n",
+			url.QueryEscape(key), exp, it.lastAccess.Unix(), it.cas, fetch, len(key)+len(it.value)+48)
+	}
+	fmt.Fprint(rw, "END\r\n")
+}
+
+// metaFlags splits meta command flags into their letter and token.
+func metaFlags(args []string) map[byte]string {
+	flags := map[byte]string{}
+	for _, arg := range args {
+		flags[arg[0]] = arg[1:]
+	}
+	return flags
+}
+
+// metaReturn formats the flags of a meta reply that the request asked for.
+func metaReturn(key string, it *item, flags map[byte]string) string {
+	var out []string
+	for _, f := range []byte("cfkstO") {
+		token, ok := flags[f]
+		if !ok {
+			continue
+		}
+		switch f {
+		case 'c':
+			out = append(out, "c"+strconv.FormatUint(it.cas, 10))
+		case 'f':
+			out = append(out, "f"+strconv.FormatUint(uint64(it.flags), 10))
+		case 'k':
+			out = append(out, "k"+key)
+		case 's':
+			out = append(out, "s"+strconv.Itoa(len(it.value)))
+		case 't':
+			ttl := int64(-1)
+			if !it.expiration.IsZero() {
+				ttl = int64(time.Until(it.expiration).Seconds())
+			}
+			out = append(out, "t"+strconv.FormatInt(ttl, 10))
+		case 'O':
+			out = append(out, "O"+token)
+		}
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	return " " + strings.Join(out, " ")
+}
+
+func (s *Server) metaGet(rw *bufio.ReadWriter, args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(rw, "CLIENT_ERROR bad command line format\r\n")
+		return
+	}
+	key, flags := args[0], metaFlags(args[1:])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		s.misses++
+		fmt.Fprint(rw, "EN\r\n")
+		return
+	}
+	s.hits++
+	it.fetched = true
+	it.lastAccess = time.Now()
+	if _, ok := flags['v']; !ok {
+		fmt.Fprintf(rw, "HD%s\r\n", metaReturn(key, it, flags))
+		return
+	}
+	fmt.Fprintf(rw, "VA %d%s\r\n", len(it.value), metaReturn(key, it, flags))
+	rw.Write(it.value)
+	fmt.Fprint(rw, "\r\n")
+}
+
+func (s *Server) metaSet(rw *bufio.ReadWriter, args []string) error {
+	if len(args) < 2 {
+		fmt.Fprint(rw, "CLIENT_ERROR bad command line format\r\n")
+		return nil
+	}
+	key, flags := args[0], metaFlags(args[2:])
+	size, err := strconv.Atoi(args[1])
+	if err != nil || size < 0 {
+		fmt.Fprint(rw, "CLIENT_ERROR bad data chunk\r\n")
+		return nil
+	}
+	value, ok, err := readData(rw, size)
+	if !ok {
+		return err
+	}
+	var clientFlags uint64
+	var expiration int64
+	if token, ok := flags['F']; ok {
+		clientFlags, _ = strconv.ParseUint(token, 10, 32)
+	}
+	if token, ok := flags['T']; ok {
+		expiration, _ = strconv.ParseInt(token, 10, 64)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token, ok := flags['C']; ok {
+		it := s.lookup(key)
+		if it == nil {
+			fmt.Fprint(rw, "NF\r\n")
+			return nil
+		}
+		if strconv.FormatUint(it.cas, 10) != token {
+			fmt.Fprint(rw, "EX\r\n")
+			return nil
+		}
+	}
+	it := s.put(key, value, uint32(clientFlags), expiration)
+	fmt.Fprintf(rw, "HD%s\r\n", metaReturn(key, it, flags))
+	return nil
+}
+
+func (s *Server) metaDelete(rw *bufio.ReadWriter, args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(rw, "CLIENT_ERROR bad command line format\r\n")
+		return
+	}
+	key, flags := args[0], metaFlags(args[1:])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		fmt.Fprint(rw, "NF\r\n")
+		return
+	}
+	if token, ok := flags['C']; ok && strconv.FormatUint(it.cas, 10) != token {
+		fmt.Fprint(rw, "EX\r\n")
+		return
+	}
+	delete(s.items, key)
+	fmt.Fprint(rw, "HD\r\n")
+}
@@ -0,0 +1,147 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MetaResponse is the reply to a meta command.
+type MetaResponse struct {
+	// Status is the two letter code of the reply, for example HD or VA.
+	Status string
+	// Flags are the returned flags, each a letter followed by its token,
+	// for example "c42" for the CAS value.
+	Flags []string
+	// Value is the data of a VA reply.
+	Value []byte
+}
+
+// Flag returns the token of the returned flag f and whether it is set.
+func (r *MetaResponse) Flag(f byte) (string, bool) {
+	for _, flag := range r.Flags {
+		if len(flag) > 0 && flag[0] == f {
+			return flag[1:], true
+		}
+	}
+	return "", false
+}
+
+// MetaGet runs "mg" for key with the given flags, for example "v" to return
+// the value, "c" for the CAS value or "t" for the remaining lifetime. It
+// returns ErrCacheMiss when there is no item.
+func (c *Client) MetaGet(ctx context.Context, key string, flags ...string) (*MetaResponse, error) {
+	return c.meta(ctx, "mg", key, nil, flags)
+}
+
+// MetaSet runs "ms" to store value under key with the given flags, for
+// example "T60" for the lifetime or "C42" to compare the CAS value. It
+// returns ErrNotStored, ErrCASConflict or ErrCacheMiss when the item is not
+// stored.
+func (c *Client) MetaSet(ctx context.Context, key string, value []byte, flags ...string) (*MetaResponse, error) {
+	if value == nil {
+		value = []byte{}
+	}
+	return c.meta(ctx, "ms", key, value, flags)
+}
+
+// MetaDelete runs "md" for key with the given flags. It returns ErrCacheMiss
+// when there is no item and ErrCASConflict when the CAS value differs.
+func (c *Client) MetaDelete(ctx context.Context, key string, flags ...string) (*MetaResponse, error) {
+	return c.meta(ctx, "md", key, nil, flags)
+}
+
+// MetaNoop runs "mn", which checks the server speaks the meta protocol.
+func (c *Client) MetaNoop(ctx context.Context) error {
+	return c.do(ctx, func(rw *bufio.ReadWriter) error {
+		if err := writeCommand(rw, "mn"); err != nil {
+			return err
+		}
+		return expectLine(rw, "mn", "MN")
+	})
+}
+
+// meta runs a meta command. value is the data block of "ms" and nil for the
+// other commands.
+func (c *Client) meta(ctx context.Context, command, key string, value []byte, flags []string) (*MetaResponse, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	for _, flag := range flags {
+		if err := checkKey(flag); err != nil {
+			return nil, fmt.Errorf("memcache: malformed flag %q", flag)
+		}
+	}
+	line := command + " " + key
+	if value != nil {
+		line += " " + strconv.Itoa(len(value))
+	}
+	if len(flags) > 0 {
+		line += " " + strings.Join(flags, " ")
+	}
+
+	var resp *MetaResponse
+	err := c.do(ctx, func(rw *bufio.ReadWriter) error {
+		var err error
+		if value != nil {
+			err = writeCommand(rw, "%s\r\n%s", line, value)
+		} else {
+			err = writeCommand(rw, "%s", line)
+		}
+		if err != nil {
+			return err
+		}
+		reply, err := readLine(rw)
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(reply)
+		if len(fields) == 0 {
+			return fmt.Errorf("memcache: empty reply to %s", command)
+		}
+		resp = &MetaResponse{Status: fields[0], Flags: fields[1:]}
+		switch resp.Status {
+		case "HD":
+			return nil
+		case "VA":
+			if len(fields) < 2 {
+				return fmt.Errorf("memcache: unexpected reply to %s: %q", command, reply)
+			}
+			size, err := strconv.Atoi(fields[1])
+			if err != nil || size < 0 {
+				return fmt.Errorf("memcache: bad size in %q", reply)
+			}
+			resp.Flags = fields[2:]
+			resp.Value, err = readData(rw, size)
+			return err
+		case "EN", "NF":
+			return ErrCacheMiss
+		case "NS":
+			return ErrNotStored
+		case "EX":
+			return ErrCASConflict
+		}
+		return fmt.Errorf("memcache: unexpected reply to %s: %q", command, reply)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,356 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCASConflict means the item was changed since it was read.
+	ErrCASConflict = errors.New("memcache: compare-and-swap conflict")
+	// ErrBusy means the LRU crawler is already running.
+	ErrBusy = errors.New("memcache: lru crawler busy")
+)
+
+// Item is a value stored in memcached.
+type Item struct {
+	Key   string
+	Value []byte
+	// Flags are opaque client flags stored with the value.
+	Flags uint32
+	// Expiration is the lifetime in seconds, or a Unix time when larger
+	// than 30 days. Zero means the item does not expire.
+	Expiration int32
+	// CAS is the compare-and-swap token returned by Get.
+	CAS uint64
+}
+
+// MetaDumpEntry describes an item listed by "lru_crawler metadump".
+type MetaDumpEntry struct {
+	Key string
+	// Expiration is the Unix time the item expires at, or -1 if never.
+	Expiration int64
+	// LastAccess is the Unix time the item was last accessed.
+	LastAccess int64
+	CAS        uint64
+	// Fetched says whether the item was fetched since it was stored.
+	Fetched bool
+	// SlabClass is the slab class the item lives in.
+	SlabClass int
+	// Size is the size of the item in memory, in bytes.
+	Size int
+}
+
+// Version returns the release of the server, for example "1.6.9".
+func (c *Client) Version(ctx context.Context) (string, error) {
+	var version string
+	err := c.do(ctx, func(rw *bufio.ReadWriter) error {
+		if err := writeCommand(rw, "version"); err != nil {
+			return err
+		}
+		line, err := readLine(rw)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "VERSION ") {
+			return fmt.Errorf("memcache: unexpected reply to version: %q", line)
+		}
+		version = strings.TrimPrefix(line, "VERSION ")
+		return nil
+	})
+	return version, err
+}
+
+// Stats runs "stats" with the given arguments, for example "slabs" or
+// "items", and returns the statistics by name.
+func (c *Client) Stats(ctx context.Context, args ...string) (map[string]string, error) {
+	command := strings.Join(append([]string{"stats"}, args...), " ")
+	stats := map[string]string{}
+	err := c.do(ctx, func(rw *bufio.ReadWriter) error {
+		if err := writeCommand(rw, "%s", command); err != nil {
+			return err
+		}
+		for {
+			line, err := readLine(rw)
+			if err != nil {
+				return err
+			}
+			if line == "END" {
+				return nil
+			}
+			fields := strings.SplitN(line, " ", 3)
+			if len(fields) != 3 || fields[0] != "STAT" {
+				return fmt.Errorf("memcache: unexpected reply to %s: %q", command, line)
+			}
+			stats[fields[1]] = fields[2]
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// FlushAll invalidates every item, after delay if it is not zero.
+func (c *Client) FlushAll(ctx context.Context, delay time.Duration) error {
+	return c.do(ctx, func(rw *bufio.ReadWriter) error {
+		var err error
+		if delay > 0 {
+			err = writeCommand(rw, "flush_all %d", int64(delay/time.Second))
+		} else {
+			err = writeCommand(rw, "flush_all")
+		}
+		if err != nil {
+			return err
+		}
+		return expectLine(rw, "flush_all", "OK")
+	})
+}
+
+// Get returns the item with the given key, or ErrCacheMiss.
+func (c *Client) Get(ctx context.Context, key string) (*Item, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	var item *Item
+	err := c.do(ctx, func(rw *bufio.ReadWriter) error {
+		if err := writeCommand(rw, "gets %s", key); err != nil {
+			return err
+		}
+		for {
+			line, err := readLine(rw)
+			if err != nil {
+				return err
+			}
+			if line == "END" {
+				if item == nil {
+					return ErrCacheMiss
+				}
+				return nil
+			}
+			it, err := readValue(rw, line)
+			if err != nil {
+				return err
+			}
+			item = it
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// readValue reads the data of a "VALUE <key> <flags> <bytes> [<cas>]" reply.
+func readValue(rw *bufio.ReadWriter, line string) (*Item, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields) > 5 || fields[0] != "VALUE" {
+		return nil, fmt.Errorf("memcache: unexpected reply to get: %q", line)
+	}
+	flags, err := strconv.ParseUint(fields[2], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("memcache: bad flags in %q", line)
+	}
+	size, err := strconv.Atoi(fields[3])
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("memcache: bad size in %q", line)
+	}
+	item := &Item{Key: fields[1], Flags: uint32(flags)}
+	if len(fields) == 5 {
+		if item.CAS, err = strconv.ParseUint(fields[4], 10, 64); err != nil {
+			return nil, fmt.Errorf("memcache: bad cas in %q", line)
+		}
+	}
+	if item.Value, err = readData(rw, size); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// readData reads a data block of size bytes and its line ending.
+func readData(rw *bufio.ReadWriter, size int) ([]byte, error) {
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(rw, data); err != nil {
+		return nil, err
+	}
+	if string(data[size:]) != "\r\n" {
+		return nil, errors.New("memcache: corrupt data block")
+	}
+	return data[:size], nil
+}
+
+// Set stores item, whether or not an item with its key exists.
+func (c *Client) Set(ctx context.Context, item *Item) error {
+	return c.store(ctx, "set", item)
+}
+
+// CompareAndSwap stores item only if it has not changed since item.CAS was
+// read. It returns ErrCASConflict if it has, and ErrCacheMiss if it is gone.
+func (c *Client) CompareAndSwap(ctx context.Context, item *Item) error {
+	return c.store(ctx, "cas", item)
+}
+
+// store runs one of the storage commands.
+func (c *Client) store(ctx context.Context, command string, item *Item) error {
+	if err := checkKey(item.Key); err != nil {
+		return err
+	}
+	return c.do(ctx, func(rw *bufio.ReadWriter) error {
+		if command == "cas" {
+			_, err := fmt.Fprintf(rw, "cas %s %d %d %d %d\r\n", item.Key, item.Flags, item.Expiration, len(item.Value), item.CAS)
+			if err != nil {
+				return err
+			}
+		} else if _, err := fmt.Fprintf(rw, "%s %s %d %d %d\r\n", command, item.Key, item.Flags, item.Expiration, len(item.Value)); err != nil {
+			return err
+		}
+		if _, err := rw.Write(item.Value); err != nil {
+			return err
+		}
+		if err := writeCommand(rw, ""); err != nil {
+			return err
+		}
+		line, err := readLine(rw)
+		if err != nil {
+			return err
+		}
+		switch line {
+		case "STORED":
+			return nil
+		case "NOT_STORED":
+			return ErrNotStored
+		case "EXISTS":
+			return ErrCASConflict
+		case "NOT_FOUND":
+			return ErrCacheMiss
+		}
+		return fmt.Errorf("memcache: unexpected reply to %s: %q", command, line)
+	})
+}
+
+// Delete removes the item with the given key. It returns ErrCacheMiss if
+// there is none.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	return c.do(ctx, func(rw *bufio.ReadWriter) error {
+		if err := writeCommand(rw, "delete %s", key); err != nil {
+			return err
+		}
+		line, err := readLine(rw)
+		if err != nil {
+			return err
+		}
+		switch line {
+		case "DELETED":
+			return nil
+		case "NOT_FOUND":
+			return ErrCacheMiss
+		}
+		return fmt.Errorf("memcache: unexpected reply to delete: %q", line)
+	})
+}
+
+// MetaDump lists every item in the cache with "lru_crawler metadump all".
+// It needs the LRU crawler, which is on by default since memcached 1.5, and
+// returns ErrBusy when the crawler is already running.
+func (c *Client) MetaDump(ctx context.Context) ([]MetaDumpEntry, error) {
+	var entries []MetaDumpEntry
+	err := c.do(ctx, func(rw *bufio.ReadWriter) error {
+		if err := writeCommand(rw, "lru_crawler metadump all"); err != nil {
+			return err
+		}
+		for {
+			line, err := readLine(rw)
+			if err != nil {
+				return err
+			}
+			switch {
+			case line == "END":
+				return nil
+			case strings.HasPrefix(line, "BUSY"):
+				return ErrBusy
+			}
+			entry, err := parseMetaDumpEntry(line)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseMetaDumpEntry parses a "key=<key> exp=<exp> la=<la> ..." line. Keys
+// are URL encoded. Unknown fields are ignored.
+func parseMetaDumpEntry(line string) (MetaDumpEntry, error) {
+	var entry MetaDumpEntry
+	for _, field := range strings.Fields(line) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return entry, fmt.Errorf("memcache: unexpected metadump line: %q", line)
+		}
+		var err error
+		switch kv[0] {
+		case "key":
+			entry.Key, err = url.QueryUnescape(kv[1])
+		case "exp":
+			entry.Expiration, err = strconv.ParseInt(kv[1], 10, 64)
+		case "la":
+			entry.LastAccess, err = strconv.ParseInt(kv[1], 10, 64)
+		case "cas":
+			entry.CAS, err = strconv.ParseUint(kv[1], 10, 64)
+		case "fetch":
+			entry.Fetched = kv[1] == "yes"
+		case "cls":
+			entry.SlabClass, err = strconv.Atoi(kv[1])
+		case "size":
+			entry.Size, err = strconv.Atoi(kv[1])
+		}
+		if err != nil {
+			return entry, fmt.Errorf("memcache: bad %s in metadump line %q", kv[0], line)
+		}
+	}
+	if entry.Key == "" {
+		return entry, fmt.Errorf("memcache: no key in metadump line %q", line)
+	}
+	return entry, nil
+}
+
+// expectLine reads one reply line and fails unless it is want.
+func expectLine(rw *bufio.ReadWriter, command, want string) error {
+	line, err := readLine(rw)
+	if err != nil {
+		return err
+	}
+	if line != want {
+		return fmt.Errorf("memcache: unexpected reply to %s: %q", command, line)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migration moves stored custom resources to the current storage
+// version of their CustomResourceDefinition.
+//
+// The API server only converts an object to the storage version when it
+// writes it, so objects created before a storage version change stay in the
+// old schema until they are next updated. Migration updates every object
+// without changing it, which makes the API server store it again, and then
+// drops the old versions from the storedVersions of the definition so that
+// they can be removed from it.
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=get;update;patch
+
+// DefaultPageSize is the number of objects listed at a time by default.
+const DefaultPageSize = 500
+
+// Migrator rewrites every object of a custom resource so it is stored in
+// the current storage version. It is safe to run again after a failure or
+// on objects that are already migrated.
+type Migrator struct {
+	Client client.Client
+	// Scheme must know the list type of the storage version.
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// CRD is the name of the CustomResourceDefinition, for example
+	// memcacheds.cache.example.com.
+	CRD string
+	// PageSize is the number of objects listed at a time. Defaults to
+	// DefaultPageSize.
+	PageSize int64
+}
+
+// Result sums up a migration.
+type Result struct {
+	// StorageVersion is the version the objects were migrated to.
+	StorageVersion string
+	// Migrated is the number of objects stored again.
+	Migrated int
+	// Failures are the objects that could not be migrated, by
+	// namespace/name.
+	Failures map[string]error
+	// StoredVersions are the versions objects may still be stored in after
+	// the migration.
+	StoredVersions []string
+}
+
+// Run migrates every object of m.CRD. Objects that fail to migrate, for
+// example because the conversion webhook rejects them, are logged and
+// returned in the result, and the old versions are kept in storedVersions.
+// The error is only set when the migration could not run at all.
+func (m *Migrator) Run(ctx context.Context) (*Result, error) {
+	crd := &apiextensionsv1beta1.CustomResourceDefinition{}
+	if err := m.Client.Get(ctx, types.NamespacedName{Name: m.CRD}, crd); err != nil {
+		return nil, fmt.Errorf("failed to get CustomResourceDefinition %s: %v", m.CRD, err)
+	}
+	version := storageVersion(crd)
+	if version == "" {
+		return nil, fmt.Errorf("CustomResourceDefinition %s has no storage version", m.CRD)
+	}
+	log := m.Log.WithValues("crd", m.CRD, "storageVersion", version)
+	result := &Result{StorageVersion: version, Failures: map[string]error{}, StoredVersions: crd.Status.StoredVersions}
+	log.Info("Migrating storage", "storedVersions", strings.Join(crd.Status.StoredVersions, ","))
+
+	gvk := schema.GroupVersionKind{Group: crd.Spec.Group, Version: version, Kind: crd.Spec.Names.ListKind}
+	if gvk.Kind == "" {
+		gvk.Kind = crd.Spec.Names.Kind + "List"
+	}
+	pageSize := m.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	cont := ""
+	for {
+		list, err := m.Scheme.New(gvk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a %s: %v", gvk, err)
+		}
+		if err := m.Client.List(ctx, list, client.Limit(pageSize), client.Continue(cont)); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", crd.Spec.Names.Plural, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range items {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return nil, err
+			}
+			key := types.NamespacedName{Namespace: accessor.GetNamespace(), Name: accessor.GetName()}
+			if err := m.migrate(ctx, key, obj); err != nil {
+				log.Error(err, "Failed to migrate object", "object", key.String())
+				result.Failures[key.String()] = err
+				continue
+			}
+			result.Migrated++
+			log.Info("Migrated object", "object", key.String(), "migrated", result.Migrated, "failed", len(result.Failures))
+		}
+		listAccessor, err := meta.ListAccessor(list)
+		if err != nil {
+			return nil, err
+		}
+		if cont = listAccessor.GetContinue(); cont == "" {
+			break
+		}
+	}
+
+	if len(result.Failures) > 0 {
+		log.Info("Keeping old stored versions until every object is migrated", "failed", len(result.Failures))
+		return result, nil
+	}
+	if len(crd.Status.StoredVersions) == 1 && crd.Status.StoredVersions[0] == version {
+		return result, nil
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := m.Client.Get(ctx, types.NamespacedName{Name: m.CRD}, crd); err != nil {
+			return err
+		}
+		// Another storage version change since the start needs another run
+		if storageVersion(crd) != version {
+			return fmt.Errorf("storage version changed to %s during the migration", storageVersion(crd))
+		}
+		crd.Status.StoredVersions = []string{version}
+		return m.Client.Status().Update(ctx, crd)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update the stored versions of CustomResourceDefinition %s: %v", m.CRD, err)
+	}
+	result.StoredVersions = []string{version}
+	log.Info("Updated stored versions", "storedVersions", version)
+	return result, nil
+}
+
+// migrate stores obj again, reading it anew when it changed since it was
+// listed. An object deleted in the meantime needs no migration.
+func (m *Migrator) migrate(ctx context.Context, key types.NamespacedName, obj runtime.Object) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := m.Client.Update(ctx, obj)
+		if errors.IsConflict(err) {
+			if err := m.Client.Get(ctx, key, obj); err != nil {
+				return err
+			}
+		}
+		return err
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// storageVersion returns the version crd stores objects in.
+func storageVersion(crd *apiextensionsv1beta1.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	return crd.Spec.Version
+}
@@ -0,0 +1,128 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+const crdName = "memcacheds.cache.example.com"
+
+// failingClient fails to update the object named fail.
+type failingClient struct {
+	client.Client
+	fail string
+}
+
+func (c *failingClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if m, ok := obj.(*cachev1alpha1.Memcached); ok && m.Name == c.fail {
+		return errors.New("conversion webhook rejected the object")
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestRun(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := cachev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiextensionsv1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name               string
+		fail               string
+		wantMigrated       int
+		wantStoredVersions []string
+	}{
+		{name: "all migrated", wantMigrated: 2, wantStoredVersions: []string{"v1alpha1"}},
+		{name: "conversion failure", fail: "b", wantMigrated: 1, wantStoredVersions: []string{"v1alpha2", "v1alpha1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crd := &apiextensionsv1beta1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: crdName},
+				Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+					Group: cachev1alpha1.GroupVersion.Group,
+					Names: apiextensionsv1beta1.CustomResourceDefinitionNames{Kind: "Memcached", ListKind: "MemcachedList", Plural: "memcacheds"},
+					Versions: []apiextensionsv1beta1.CustomResourceDefinitionVersion{
+						{Name: "v1alpha1", Served: true, Storage: true},
+						{Name: "v1alpha2", Served: true},
+					},
+				},
+				Status: apiextensionsv1beta1.CustomResourceDefinitionStatus{StoredVersions: []string{"v1alpha2", "v1alpha1"}},
+			}
+			a := &cachev1alpha1.Memcached{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns"}, Spec: cachev1alpha1.MemcachedSpec{Size: 3}}
+			b := &cachev1alpha1.Memcached{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "other"}, Spec: cachev1alpha1.MemcachedSpec{Size: 1}}
+			c := fake.NewFakeClientWithScheme(scheme, crd, a, b)
+			m := &Migrator{Client: &failingClient{Client: c, fail: tt.fail}, Scheme: scheme, Log: ctrl.Log, CRD: crdName}
+
+			before := &cachev1alpha1.Memcached{}
+			if err := c.Get(context.TODO(), types.NamespacedName{Name: "a", Namespace: "ns"}, before); err != nil {
+				t.Fatal(err)
+			}
+
+			// Running again must give the same result
+			for run := 0; run < 2; run++ {
+				result, err := m.Run(context.TODO())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if result.StorageVersion != "v1alpha1" {
+					t.Errorf("expected storage version v1alpha1, got %s", result.StorageVersion)
+				}
+				if result.Migrated != tt.wantMigrated {
+					t.Errorf("expected %d objects migrated, got %d", tt.wantMigrated, result.Migrated)
+				}
+				if _, ok := result.Failures["other/b"]; ok != (tt.fail != "") {
+					t.Errorf("unexpected failures %v", result.Failures)
+				}
+				if !reflect.DeepEqual(result.StoredVersions, tt.wantStoredVersions) {
+					t.Errorf("expected stored versions %v, got %v", tt.wantStoredVersions, result.StoredVersions)
+				}
+			}
+
+			got := &apiextensionsv1beta1.CustomResourceDefinition{}
+			if err := c.Get(context.TODO(), types.NamespacedName{Name: crdName}, got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Status.StoredVersions, tt.wantStoredVersions) {
+				t.Errorf("expected stored versions %v in the CustomResourceDefinition, got %v", tt.wantStoredVersions, got.Status.StoredVersions)
+			}
+			migrated := &cachev1alpha1.Memcached{}
+			if err := c.Get(context.TODO(), types.NamespacedName{Name: "a", Namespace: "ns"}, migrated); err != nil {
+				t.Fatal(err)
+			}
+			if migrated.ResourceVersion == before.ResourceVersion {
+				t.Errorf("expected %s to be written again", migrated.Name)
+			}
+		})
+	}
+}
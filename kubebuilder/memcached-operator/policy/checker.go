@@ -0,0 +1,270 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy checks Memcacheds against the admission policies of the
+// cluster. Policies come from cluster scoped MemcachedPolicy objects and,
+// optionally, from a ConfigMap, and apply to the namespaces their
+// namespaceSelector selects. A Memcached is admitted when it satisfies the
+// expression of every rule of every policy that applies to it. Policies that
+// can't be checked are skipped, and reported on their status.
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=cache.example.com,resources=memcachedpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// Checker checks Memcacheds against the admission policies. It implements
+// cachev1alpha1.PolicyChecker.
+type Checker struct {
+	// Reader reads the policies, the ConfigMap and the namespaces. It is
+	// meant to be the cache of the manager, so admission requests make no
+	// API server round trips: policies and namespaces are few and change
+	// rarely, and the MemcachedCostReport controller already watches every
+	// ConfigMap. A policy change applies once it reached the cache.
+	Reader client.Reader
+	Log    logr.Logger
+
+	// ConfigMap names a ConfigMap with more policies, if set. Every key is
+	// the name of a policy and its value the spec of a MemcachedPolicy in
+	// YAML. A missing ConfigMap holds no policies.
+	ConfigMap *types.NamespacedName
+
+	// mu guards compiled.
+	mu sync.Mutex
+	// compiled holds the policies by source, so they are only compiled
+	// again once the object they come from changes.
+	compiled map[string]*policy
+}
+
+var _ cachev1alpha1.PolicyChecker = &Checker{}
+
+// policy is a compiled MemcachedPolicy spec and where it came from.
+type policy struct {
+	// source names the policy in error messages.
+	source string
+	// resourceVersion is that of the object the policy was read from.
+	resourceVersion string
+	// namespaceSelector is nil when the policy applies to every namespace.
+	namespaceSelector labels.Selector
+	rules             []rule
+	// errs is why the policy can't be checked.
+	errs field.ErrorList
+}
+
+// rule is a compiled MemcachedPolicyRule.
+type rule struct {
+	expr    *Expression
+	message string
+}
+
+// ValidatePolicy returns an error for every part of spec, at fldPath, that
+// keeps the policy from being checked.
+func (c *Checker) ValidatePolicy(spec *cachev1alpha1.MemcachedPolicySpec, fldPath *field.Path) field.ErrorList {
+	return compile("", "", spec, fldPath).errs
+}
+
+// Check returns a Forbidden error on spec for every rule of the policies
+// applying to m that m breaks, naming the policy. Rules that fail to
+// evaluate reject m too. Policies that can't be checked at all are skipped,
+// so a broken policy does not reject every Memcached.
+func (c *Checker) Check(ctx context.Context, m *cachev1alpha1.Memcached) (field.ErrorList, error) {
+	policies, err := c.policies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	vars, err := variables(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	var namespaceLabels labels.Set
+	for _, p := range policies {
+		if len(p.errs) > 0 {
+			continue
+		}
+		if p.namespaceSelector != nil {
+			if namespaceLabels == nil {
+				if namespaceLabels, err = c.namespaceLabels(ctx, m.Namespace); err != nil {
+					return nil, err
+				}
+			}
+			if !p.namespaceSelector.Matches(namespaceLabels) {
+				continue
+			}
+		}
+		for i, rule := range p.rules {
+			if err := rule.eval(vars); err != nil {
+				c.Log.Info("Rejecting Memcached", "memcached", types.NamespacedName{Namespace: m.Namespace, Name: m.Name}.String(),
+					"policy", p.source, "rule", i, "reason", err.Error())
+				allErrs = append(allErrs, field.Forbidden(specPath, fmt.Sprintf("%s rejected the request: %v", p.source, err)))
+			}
+		}
+	}
+	return allErrs, nil
+}
+
+// policies returns the MemcachedPolicies by name, followed by the policies
+// of the ConfigMap by key.
+func (c *Checker) policies(ctx context.Context) ([]*policy, error) {
+	list := &cachev1alpha1.MemcachedPolicyList{}
+	if err := c.Reader.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to list MemcachedPolicies: %v", err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Name < list.Items[j].Name })
+	cm := &corev1.ConfigMap{}
+	if c.ConfigMap != nil {
+		if err := c.Reader.Get(ctx, *c.ConfigMap, cm); err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get ConfigMap %s: %v", c.ConfigMap, err)
+		}
+	}
+	keys := make([]string, 0, len(cm.Data))
+	for key := range cm.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Only keep the policies that still exist
+	compiled := map[string]*policy{}
+	var policies []*policy
+	for i := range list.Items {
+		p := &list.Items[i]
+		source := fmt.Sprintf("MemcachedPolicy %s", p.Name)
+		compiled[source] = c.compiledPolicy(source, p.ResourceVersion, func() *policy {
+			return compile(source, p.ResourceVersion, &p.Spec, field.NewPath("spec"))
+		})
+		policies = append(policies, compiled[source])
+	}
+	for _, key := range keys {
+		source := fmt.Sprintf("policy %s in ConfigMap %s", key, c.ConfigMap)
+		compiled[source] = c.compiledPolicy(source, cm.ResourceVersion, func() *policy {
+			fldPath := field.NewPath("data").Key(key)
+			spec := &cachev1alpha1.MemcachedPolicySpec{}
+			if err := yaml.UnmarshalStrict([]byte(cm.Data[key]), spec); err != nil {
+				return &policy{source: source, resourceVersion: cm.ResourceVersion,
+					errs: field.ErrorList{field.Invalid(fldPath, cm.Data[key], err.Error())}}
+			}
+			return compile(source, cm.ResourceVersion, spec, fldPath)
+		})
+		policies = append(policies, compiled[source])
+	}
+	c.compiled = compiled
+	return policies, nil
+}
+
+// compiledPolicy returns the policy compiled for source at resourceVersion,
+// compiling it again when it changed. c.mu must be held.
+func (c *Checker) compiledPolicy(source, resourceVersion string, build func() *policy) *policy {
+	if p, ok := c.compiled[source]; ok && p.resourceVersion == resourceVersion {
+		return p
+	}
+	p := build()
+	if len(p.errs) > 0 {
+		c.Log.Info("Skipping invalid policy", "policy", source, "reason", p.errs.ToAggregate().Error())
+	}
+	return p
+}
+
+// compile compiles the policy spec at fldPath, recording why it can't be
+// checked, if it can't.
+func compile(source, resourceVersion string, spec *cachev1alpha1.MemcachedPolicySpec, fldPath *field.Path) *policy {
+	p := &policy{source: source, resourceVersion: resourceVersion}
+	if spec.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.NamespaceSelector)
+		if err != nil {
+			p.errs = append(p.errs, field.Invalid(fldPath.Child("namespaceSelector"), spec.NamespaceSelector, err.Error()))
+		}
+		p.namespaceSelector = selector
+	}
+	for i, r := range spec.Rules {
+		expr, err := Compile(r.Expression)
+		if err != nil {
+			p.errs = append(p.errs, field.Invalid(fldPath.Child("rules").Index(i).Child("expression"), r.Expression, err.Error()))
+			continue
+		}
+		p.rules = append(p.rules, rule{expr: expr, message: r.Message})
+	}
+	return p
+}
+
+// namespaceLabels returns the labels of the namespace name, which are empty
+// for cluster scoped objects.
+func (c *Checker) namespaceLabels(ctx context.Context, name string) (labels.Set, error) {
+	if name == "" {
+		return labels.Set{}, nil
+	}
+	ns := &corev1.Namespace{}
+	if err := c.Reader.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+		return nil, fmt.Errorf("failed to get namespace %s: %v", name, err)
+	}
+	return labels.Set(ns.Labels), nil
+}
+
+// variables returns the spec and metadata of m as JSON values, the
+// variables rule expressions are evaluated with.
+func variables(m *cachev1alpha1.Memcached) (map[string]interface{}, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"spec": obj["spec"], "metadata": obj["metadata"]}, nil
+}
+
+// eval returns the message of r when vars break it, or why it could not be
+// evaluated.
+func (r rule) eval(vars map[string]interface{}) error {
+	ok, err := r.expr.Eval(vars)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate %q: %v", r.expr, err)
+	}
+	if ok {
+		return nil
+	}
+	if r.message != "" {
+		return errors.New(r.message)
+	}
+	return fmt.Errorf("spec must satisfy %s", r.expr)
+}
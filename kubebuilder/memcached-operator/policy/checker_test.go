@@ -0,0 +1,194 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cachev1alpha1 "github.com/example-inc/memcached-operator/api/v1alpha1"
+)
+
+func TestCheck(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := cachev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	objs := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"environment": "production"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev"}},
+		&cachev1alpha1.MemcachedPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "production"},
+			Spec: cachev1alpha1.MemcachedPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"environment": "production"}},
+				Rules: []cachev1alpha1.MemcachedPolicyRule{
+					{Expression: "spec.size >= 3", Message: "production caches need at least 3 pods"},
+					{Expression: "spec.tls != nil"},
+				},
+			},
+		},
+		&cachev1alpha1.MemcachedPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "size"},
+			Spec: cachev1alpha1.MemcachedPolicySpec{
+				Rules: []cachev1alpha1.MemcachedPolicyRule{{Expression: "spec.size <= 9", Message: "at most 9 pods"}},
+			},
+		},
+		// Broken policies are skipped rather than rejecting everything
+		&cachev1alpha1.MemcachedPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "broken"},
+			Spec: cachev1alpha1.MemcachedPolicySpec{
+				Rules: []cachev1alpha1.MemcachedPolicyRule{{Expression: "spec.size >"}},
+			},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "policies", Namespace: "operator"},
+			Data: map[string]string{
+				"price":  "rules:\n- expression: spec.price != \"\"\n  message: caches must have a price\n",
+				"broken": "rules: [",
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		spec      cachev1alpha1.MemcachedSpec
+		configMap string
+		want      []string
+	}{
+		{name: "admitted", namespace: "dev", spec: cachev1alpha1.MemcachedSpec{Size: 1}},
+		{name: "too large", namespace: "dev", spec: cachev1alpha1.MemcachedSpec{Size: 11}, want: []string{
+			"MemcachedPolicy size rejected the request: at most 9 pods",
+		}},
+		{name: "selected namespace", namespace: "prod", spec: cachev1alpha1.MemcachedSpec{Size: 1}, want: []string{
+			"MemcachedPolicy production rejected the request: production caches need at least 3 pods",
+			"MemcachedPolicy production rejected the request: spec must satisfy spec.tls != nil",
+		}},
+		{name: "selected namespace admitted", namespace: "prod", spec: cachev1alpha1.MemcachedSpec{
+			Size: 3,
+			TLS:  &cachev1alpha1.MemcachedTLS{SecretName: "memcached-tls"},
+		}},
+		{name: "configmap", namespace: "dev", configMap: "policies", spec: cachev1alpha1.MemcachedSpec{Size: 1}, want: []string{
+			"policy price in ConfigMap operator/policies rejected the request: caches must have a price",
+		}},
+		{name: "missing configmap", namespace: "dev", configMap: "missing", spec: cachev1alpha1.MemcachedSpec{Size: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Checker{Reader: fake.NewFakeClientWithScheme(scheme, objs...), Log: ctrl.Log}
+			if tt.configMap != "" {
+				c.ConfigMap = &types.NamespacedName{Namespace: "operator", Name: tt.configMap}
+			}
+			m := &cachev1alpha1.Memcached{
+				ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: tt.namespace},
+				Spec:       tt.spec,
+			}
+			allErrs, err := c.Check(context.TODO(), m)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(allErrs) != len(tt.want) {
+				t.Fatalf("expected %d errors, got %v", len(tt.want), allErrs)
+			}
+			for i, want := range tt.want {
+				if allErrs[i].Field != "spec" || !strings.Contains(allErrs[i].Detail, want) {
+					t.Errorf("expected an error on spec containing %q, got %v", want, allErrs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckCompilesOnce(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := cachev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	p := &cachev1alpha1.MemcachedPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "size"},
+		Spec: cachev1alpha1.MemcachedPolicySpec{
+			Rules: []cachev1alpha1.MemcachedPolicyRule{{Expression: "spec.size <= 9"}},
+		},
+	}
+	cl := fake.NewFakeClientWithScheme(scheme, p)
+	c := &Checker{Reader: cl, Log: ctrl.Log}
+	m := &cachev1alpha1.Memcached{ObjectMeta: metav1.ObjectMeta{Name: "example"}, Spec: cachev1alpha1.MemcachedSpec{Size: 11}}
+	check := func() *policy {
+		if allErrs, err := c.Check(context.TODO(), m); err != nil || len(allErrs) != 1 {
+			t.Fatalf("expected the policy to reject the Memcached, got %v, %v", allErrs, err)
+		}
+		return c.compiled["MemcachedPolicy size"]
+	}
+
+	compiled := check()
+	if check() != compiled {
+		t.Errorf("policy compiled again without changing")
+	}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Name: "size"}, p); err != nil {
+		t.Fatal(err)
+	}
+	p.Spec.Rules[0].Expression = "spec.size <= 7"
+	if err := cl.Update(context.TODO(), p); err != nil {
+		t.Fatal(err)
+	}
+	if check() == compiled {
+		t.Errorf("policy not compiled again after it changed")
+	}
+
+	if err := cl.Delete(context.TODO(), p); err != nil {
+		t.Fatal(err)
+	}
+	if allErrs, err := c.Check(context.TODO(), m); err != nil || len(allErrs) != 0 {
+		t.Fatalf("expected no errors without policies, got %v, %v", allErrs, err)
+	}
+	if len(c.compiled) != 0 {
+		t.Errorf("deleted policy is still compiled: %v", c.compiled)
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	spec := &cachev1alpha1.MemcachedPolicySpec{
+		NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "environment", Operator: "Near"}}},
+		Rules: []cachev1alpha1.MemcachedPolicyRule{
+			{Expression: "spec.size <= 9"},
+			{Expression: "spec.size >"},
+			{Expression: "spec.size = 3"},
+		},
+	}
+	allErrs := (&Checker{}).ValidatePolicy(spec, field.NewPath("spec"))
+	var fields []string
+	for _, err := range allErrs {
+		fields = append(fields, err.Field)
+	}
+	want := []string{"spec.namespaceSelector", "spec.rules[1].expression", "spec.rules[2].expression"}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Errorf("expected errors on %v, got %v", want, allErrs)
+	}
+}
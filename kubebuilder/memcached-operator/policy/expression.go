@@ -0,0 +1,332 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Expression is a compiled policy rule expression. It uses Go expression
+// syntax over JSON values: numbers, strings, booleans, nil, lists and maps.
+// Fields are selected with a dot and list items and map values with an
+// index; both give nil when missing, also on nil.
+type Expression struct {
+	source string
+	expr   ast.Expr
+}
+
+// Compile parses source into an Expression.
+func Compile(source string) (*Expression, error) {
+	expr, err := parser.ParseExpr(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", source, err)
+	}
+	if err := check(expr); err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", source, err)
+	}
+	return &Expression{source: source, expr: expr}, nil
+}
+
+// String returns the source of e.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates e with the given top level variables, which must be JSON
+// values as decoded by encoding/json. It fails unless e evaluates to a
+// boolean.
+func (e *Expression) Eval(vars map[string]interface{}) (bool, error) {
+	v, err := eval(e.expr, vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q gives %s, not a boolean", e.source, describe(v))
+	}
+	return b, nil
+}
+
+// binaryOps are the binary operators expressions can use.
+var binaryOps = map[token.Token]bool{
+	token.LAND: true, token.LOR: true,
+	token.EQL: true, token.NEQ: true, token.LSS: true, token.LEQ: true, token.GTR: true, token.GEQ: true,
+	token.ADD: true, token.SUB: true, token.MUL: true, token.QUO: true, token.REM: true,
+}
+
+// check rejects the parts of Go syntax expressions cannot use, so mistakes
+// are found before the first evaluation.
+func check(expr ast.Expr) error {
+	var err error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if err != nil || n == nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.ParenExpr, *ast.SelectorExpr, *ast.IndexExpr, *ast.Ident:
+		case *ast.BinaryExpr:
+			if !binaryOps[n.Op] {
+				err = fmt.Errorf("unsupported operator %s", n.Op)
+			}
+		case *ast.UnaryExpr:
+			if n.Op != token.NOT && n.Op != token.SUB {
+				err = fmt.Errorf("unsupported operator %s", n.Op)
+			}
+		case *ast.BasicLit:
+			if n.Kind != token.INT && n.Kind != token.FLOAT && n.Kind != token.STRING {
+				err = fmt.Errorf("unsupported literal %s", n.Value)
+			}
+		case *ast.CallExpr:
+			if fun, ok := n.Fun.(*ast.Ident); !ok || fun.Name != "len" || len(n.Args) != 1 {
+				err = fmt.Errorf("unsupported call, only len(x) is known")
+			}
+		default:
+			err = fmt.Errorf("unsupported syntax at position %d", n.Pos())
+		}
+		return err == nil
+	})
+	return err
+}
+
+func eval(expr ast.Expr, vars map[string]interface{}) (interface{}, error) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return eval(e.X, vars)
+	case *ast.BasicLit:
+		if e.Kind == token.STRING {
+			return strconv.Unquote(e.Value)
+		}
+		return strconv.ParseFloat(e.Value, 64)
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "nil":
+			return nil, nil
+		}
+		v, ok := vars[e.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %s", e.Name)
+		}
+		return v, nil
+	case *ast.SelectorExpr:
+		x, err := eval(e.X, vars)
+		if err != nil {
+			return nil, err
+		}
+		return selectField(x, e.Sel.Name)
+	case *ast.IndexExpr:
+		x, err := eval(e.X, vars)
+		if err != nil {
+			return nil, err
+		}
+		index, err := eval(e.Index, vars)
+		if err != nil {
+			return nil, err
+		}
+		return item(x, index)
+	case *ast.CallExpr:
+		x, err := eval(e.Args[0], vars)
+		if err != nil {
+			return nil, err
+		}
+		return length(x)
+	case *ast.UnaryExpr:
+		x, err := eval(e.X, vars)
+		if err != nil {
+			return nil, err
+		}
+		if e.Op == token.NOT {
+			b, ok := x.(bool)
+			if !ok {
+				return nil, fmt.Errorf("cannot negate %s", describe(x))
+			}
+			return !b, nil
+		}
+		n, ok := x.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %s", describe(x))
+		}
+		return -n, nil
+	case *ast.BinaryExpr:
+		return evalBinary(e, vars)
+	}
+	return nil, fmt.Errorf("unsupported syntax at position %d", expr.Pos())
+}
+
+func evalBinary(e *ast.BinaryExpr, vars map[string]interface{}) (interface{}, error) {
+	x, err := eval(e.X, vars)
+	if err != nil {
+		return nil, err
+	}
+	// && and || only evaluate the right side when needed, so it can rely
+	// on the left side, as in `metadata.labels.tier == nil || metadata.labels.tier < "3"`
+	if e.Op == token.LAND || e.Op == token.LOR {
+		l, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs booleans, got %s", e.Op, describe(x))
+		}
+		if l == (e.Op == token.LOR) {
+			return l, nil
+		}
+		y, err := eval(e.Y, vars)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := y.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs booleans, got %s", e.Op, describe(y))
+		}
+		return r, nil
+	}
+	y, err := eval(e.Y, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Op {
+	case token.EQL:
+		return reflect.DeepEqual(x, y), nil
+	case token.NEQ:
+		return !reflect.DeepEqual(x, y), nil
+	}
+	if l, ok := x.(string); ok {
+		r, ok := y.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply %s to %s and %s", e.Op, describe(x), describe(y))
+		}
+		switch e.Op {
+		case token.ADD:
+			return l + r, nil
+		case token.LSS:
+			return l < r, nil
+		case token.LEQ:
+			return l <= r, nil
+		case token.GTR:
+			return l > r, nil
+		case token.GEQ:
+			return l >= r, nil
+		}
+		return nil, fmt.Errorf("cannot apply %s to strings", e.Op)
+	}
+	l, lok := x.(float64)
+	r, rok := y.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", e.Op, describe(x), describe(y))
+	}
+	switch e.Op {
+	case token.ADD:
+		return l + r, nil
+	case token.SUB:
+		return l - r, nil
+	case token.MUL:
+		return l * r, nil
+	case token.QUO, token.REM:
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if e.Op == token.QUO {
+			return l / r, nil
+		}
+		return math.Mod(l, r), nil
+	case token.LSS:
+		return l < r, nil
+	case token.LEQ:
+		return l <= r, nil
+	case token.GTR:
+		return l > r, nil
+	case token.GEQ:
+		return l >= r, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", e.Op)
+}
+
+// selectField returns the field name of x, or nil when x has none.
+func selectField(x interface{}, name string) (interface{}, error) {
+	switch x := x.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return x[name], nil
+	}
+	return nil, fmt.Errorf("cannot select %s of %s", name, describe(x))
+}
+
+// item returns the item of x at index, or nil when there is none.
+func item(x, index interface{}) (interface{}, error) {
+	switch x := x.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot index a map with %s", describe(index))
+		}
+		return x[key], nil
+	case []interface{}:
+		i, ok := index.(float64)
+		if !ok || i != math.Trunc(i) {
+			return nil, fmt.Errorf("cannot index a list with %s", describe(index))
+		}
+		if i < 0 || int(i) >= len(x) {
+			return nil, nil
+		}
+		return x[int(i)], nil
+	}
+	return nil, fmt.Errorf("cannot index %s", describe(x))
+}
+
+// length returns the length of x, 0 for nil.
+func length(x interface{}) (interface{}, error) {
+	switch x := x.(type) {
+	case nil:
+		return float64(0), nil
+	case string:
+		return float64(len(x)), nil
+	case []interface{}:
+		return float64(len(x)), nil
+	case map[string]interface{}:
+		return float64(len(x)), nil
+	}
+	return nil, fmt.Errorf("cannot take the length of %s", describe(x))
+}
+
+// describe names the type of a JSON value for error messages.
+func describe(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "a boolean"
+	case float64:
+		return fmt.Sprintf("the number %v", v)
+	case string:
+		return fmt.Sprintf("the string %q", v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a map"
+	}
+	return fmt.Sprintf("a %T", v)
+}
@@ -0,0 +1,97 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExpression(t *testing.T) {
+	var spec map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"size": 3,
+		"price": "10 USD",
+		"config": {"memoryMB": 64},
+		"sizing": {"windows": [{"start": "02:30"}]}
+	}`), &spec); err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]interface{}{
+		"spec":     spec,
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{"tier": "2"}},
+	}
+
+	tests := []struct {
+		expression string
+		want       bool
+		wantErr    bool
+	}{
+		{expression: "spec.size % 2 == 1", want: true},
+		{expression: "spec.size >= 5"},
+		{expression: "spec.config.memoryMB * spec.size <= 256", want: true},
+		{expression: `spec.price != "" && spec.tls == nil`, want: true},
+		{expression: "spec.tls.secretName == nil", want: true},
+		{expression: `spec.sizing.windows[0].start == "02:30"`, want: true},
+		{expression: "spec.sizing.windows[1] == nil", want: true},
+		{expression: `metadata.labels["tier"] < "3"`, want: true},
+		{expression: "len(spec.sizing.windows) == 1 && len(spec.auth) == 0", want: true},
+		{expression: "!(spec.size > 1) || -spec.size < 0", want: true},
+		// The right side is not evaluated
+		{expression: `metadata.labels.team == nil || metadata.labels.team < 3`, want: true},
+		{expression: `metadata.labels.tier < 3`, wantErr: true},
+		{expression: "spec.size", wantErr: true},
+		{expression: "status.size == 3", wantErr: true},
+		{expression: "spec.size % 0 == 1", wantErr: true},
+		{expression: "spec.size.value == 3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			e, err := Compile(tt.expression)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := e.Eval(vars)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileRejects(t *testing.T) {
+	for _, expression := range []string{
+		"spec.size ==",
+		"func() bool { return true }()",
+		"spec.size << 1 == 6",
+		"print(spec.size)",
+		"[]int{1}[0] == 1",
+		"'a' == 'a'",
+	} {
+		if _, err := Compile(expression); err == nil {
+			t.Errorf("expected %q to be rejected", expression)
+		}
+	}
+}
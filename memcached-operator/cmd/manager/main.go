@@ -17,6 +17,7 @@ import (
 	"github.com/operator-framework/operator-sdk-samples/go/memcached-operator/pkg/controller"
 	"github.com/operator-framework/operator-sdk-samples/go/memcached-operator/version"
 
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	kubemetrics "github.com/operator-framework/operator-sdk/pkg/kube-metrics"
 	"github.com/operator-framework/operator-sdk/pkg/leader"
@@ -122,6 +123,13 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The controller creates ServiceMonitors and PrometheusRules for the
+	// caches when the prometheus-operator is installed
+	if err := monitoringv1.AddToScheme(mgr.GetScheme()); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Setup all Controllers
 	if err := controller.AddToManager(mgr); err != nil {
 		log.Error(err, "")
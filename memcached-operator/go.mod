@@ -3,6 +3,8 @@ module github.com/operator-framework/operator-sdk-samples/go/memcached-operator
 go 1.13
 
 require (
+	github.com/coreos/prometheus-operator v0.38.0
+	github.com/go-logr/logr v0.1.0
 	github.com/operator-framework/operator-sdk v0.17.0
 	github.com/spf13/pflag v1.0.5
 	k8s.io/api v0.17.4
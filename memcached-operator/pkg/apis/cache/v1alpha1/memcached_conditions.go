@@ -0,0 +1,36 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FindCondition returns the condition of the given type, or nil if the
+// status does not have one.
+func (s *MemcachedStatus) FindCondition(t MemcachedConditionType) *MemcachedCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates the condition of c's type. The transition
+// time is only moved when the status of the condition changes.
+func (s *MemcachedStatus) SetCondition(c MemcachedCondition) {
+	existing := s.FindCondition(c.Type)
+	if existing == nil {
+		if c.LastTransitionTime.IsZero() {
+			c.LastTransitionTime = metav1.Now()
+		}
+		s.Conditions = append(s.Conditions, c)
+		return
+	}
+	if existing.Status != c.Status {
+		existing.Status = c.Status
+		existing.LastTransitionTime = metav1.Now()
+	}
+	existing.Reason = c.Reason
+	existing.Message = c.Message
+	existing.ObservedGeneration = c.ObservedGeneration
+}
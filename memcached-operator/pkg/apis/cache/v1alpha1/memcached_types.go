@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -16,6 +17,90 @@ type MemcachedSpec struct {
 
 	// Size is the size of the memcached deployment
 	Size int32 `json:"size"`
+
+	// Version is the memcached release to run, for example "1.6.9". When
+	// changed, pods are replaced one at a time. Defaults to the tag of Image,
+	// or 1.4.36 when Image is not set either.
+	// +kubebuilder:validation:Pattern=`^v?[0-9]+\.[0-9]+\.[0-9]+$`
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Image overrides the memcached container image. Defaults to the alpine
+	// variant of the official image for Version.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// DeletionPolicy says what happens to the memcached pods when this
+	// Memcached is deleted: Delete removes them, Orphan leaves them and their
+	// Service running. Defaults to Delete.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Monitoring exports memcached metrics to Prometheus.
+	// +optional
+	Monitoring *MemcachedMonitoring `json:"monitoring,omitempty"`
+}
+
+// MemcachedMonitoring configures the Prometheus exporter of a Memcached.
+type MemcachedMonitoring struct {
+	// Enabled adds a memcached exporter sidecar to every pod and a metrics
+	// port to the Service. When the prometheus-operator is installed, a
+	// ServiceMonitor and alerting rules for evictions, connection
+	// saturation and pod restarts are created as well.
+	Enabled bool `json:"enabled"`
+
+	// ExporterImage overrides the exporter container image.
+	// +optional
+	ExporterImage string `json:"exporterImage,omitempty"`
+}
+
+// DeletionPolicy says what happens to the objects created for a Memcached
+// when it is deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete removes every object created for the instance.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan detaches the memcached pods and their Service from
+	// the instance, so that they keep serving after it is gone.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// MemcachedConditionType is the type of a Memcached condition.
+type MemcachedConditionType string
+
+const (
+	// MemcachedAvailable means at least one memcached pod is ready to serve.
+	MemcachedAvailable MemcachedConditionType = "Available"
+	// MemcachedProgressing means pods are being created, scaled or replaced.
+	MemcachedProgressing MemcachedConditionType = "Progressing"
+	// MemcachedDegraded means the controller cannot bring the instance to
+	// the desired state. The reason and message say why.
+	MemcachedDegraded MemcachedConditionType = "Degraded"
+	// MemcachedTerminating means the instance is being deleted and the
+	// controller is tearing down what it created for it.
+	MemcachedTerminating MemcachedConditionType = "Terminating"
+)
+
+// MemcachedCondition describes one aspect of the state of a Memcached.
+type MemcachedCondition struct {
+	// Type of the condition.
+	Type MemcachedConditionType `json:"type"`
+	// Status of the condition, one of True, False or Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+	// ObservedGeneration is the .metadata.generation the condition was set from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastTransitionTime is the last time the condition changed status.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a CamelCase reason for the last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable description of the last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // MemcachedStatus defines the observed state of Memcached
@@ -28,6 +113,30 @@ type MemcachedStatus struct {
 	// Nodes are the names of the memcached pods
 	// +listType=set
 	Nodes []string `json:"nodes"`
+
+	// Version is the memcached release every pod is running. It is only
+	// updated once a version change has been fully rolled out.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// ObservedGeneration is the most recent .metadata.generation the
+	// controller has acted on.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ReadyReplicas is the number of memcached pods ready to serve.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// LastError is the error that stopped the last reconcile, if any.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// Conditions are the latest observations of the instance's state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []MemcachedCondition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -36,6 +145,12 @@ type MemcachedStatus struct {
 // +k8s:openapi-gen=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=memcacheds,scope=Namespaced
+// +kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.spec.size`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="Degraded",type=string,JSONPath=`.status.conditions[?(@.type=="Degraded")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type Memcached struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
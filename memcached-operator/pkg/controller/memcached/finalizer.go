@@ -0,0 +1,179 @@
+package memcached
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cachev1alpha1 "github.com/operator-framework/operator-sdk-samples/go/memcached-operator/pkg/apis/cache/v1alpha1"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// memcachedFinalizer keeps a deleted Memcached around until the controller
+// has torn down what it created for it.
+const memcachedFinalizer = "cache.example.com/teardown"
+
+// teardownPollInterval is how often teardown checks whether the children it
+// deleted are gone.
+const teardownPollInterval = 2 * time.Second
+
+// hasFinalizer reports whether m carries the teardown finalizer.
+func hasFinalizer(m *cachev1alpha1.Memcached) bool {
+	for _, f := range m.Finalizers {
+		if f == memcachedFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// deletionPolicy returns the deletion policy of m, defaulting to Delete.
+func deletionPolicy(m *cachev1alpha1.Memcached) cachev1alpha1.DeletionPolicy {
+	if m.Spec.DeletionPolicy == "" {
+		return cachev1alpha1.DeletionPolicyDelete
+	}
+	return m.Spec.DeletionPolicy
+}
+
+// ensureFinalizer adds the teardown finalizer to m if it is missing.
+func (r *ReconcileMemcached) ensureFinalizer(m *cachev1alpha1.Memcached) error {
+	if hasFinalizer(m) {
+		return nil
+	}
+	controllerutil.AddFinalizer(m, memcachedFinalizer)
+	return r.client.Update(context.TODO(), m)
+}
+
+// reconcileDelete tears down a deleted Memcached in order: it announces
+// termination, removes the Service clients find the pods through, then
+// deletes or orphans the Deployment according to spec.deletionPolicy. The
+// finalizer is only removed once all of that is done.
+//
+// Announcing termination only sets the Terminating condition; it does not
+// wait for clients to move off the instance.
+func (r *ReconcileMemcached) reconcileDelete(reqLogger logr.Logger, m *cachev1alpha1.Memcached) (reconcile.Result, error) {
+	if !hasFinalizer(m) {
+		return reconcile.Result{}, nil
+	}
+	policy := deletionPolicy(m)
+
+	// Announce termination before anything disappears.
+	if c := m.Status.FindCondition(cachev1alpha1.MemcachedTerminating); c == nil || c.Status != corev1.ConditionTrue {
+		reqLogger.Info("Tearing down Memcached.", "DeletionPolicy", policy)
+		m.Status.SetCondition(cachev1alpha1.MemcachedCondition{
+			Type:               cachev1alpha1.MemcachedTerminating,
+			Status:             corev1.ConditionTrue,
+			ObservedGeneration: m.Generation,
+			Reason:             reasonTearingDown,
+			Message:            fmt.Sprintf("deletion policy is %s", policy),
+		})
+		if err := r.client.Status().Update(context.TODO(), m); err != nil {
+			reqLogger.Error(err, "Failed to update Memcached status.")
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Remove the published endpoints and their monitoring first, then the
+	// pods behind them.
+	endpoints := append([]runtime.Object{&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: m.Name, Namespace: m.Namespace}}},
+		r.monitoringObjects(m)...)
+	steps := [][]runtime.Object{
+		endpoints,
+		{&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: m.Name, Namespace: m.Namespace}}},
+	}
+	for _, objs := range steps {
+		pending := false
+		for _, obj := range objs {
+			var err error
+			if policy == cachev1alpha1.DeletionPolicyOrphan {
+				err = r.orphanOwned(m, obj)
+			} else {
+				var exists bool
+				exists, err = r.deleteOwned(m, obj)
+				pending = pending || exists
+			}
+			if err != nil {
+				reqLogger.Error(err, "Failed to tear down Memcached.")
+				return reconcile.Result{}, err
+			}
+		}
+		// Finish one step before starting the next.
+		if pending {
+			return reconcile.Result{RequeueAfter: teardownPollInterval}, nil
+		}
+	}
+
+	reqLogger.Info("Teardown complete, removing finalizer.")
+	controllerutil.RemoveFinalizer(m, memcachedFinalizer)
+	if err := r.client.Update(context.TODO(), m); err != nil {
+		reqLogger.Error(err, "Failed to remove finalizer.")
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// deleteOwned deletes obj if m controls it. Dependents are deleted first, so
+// obj only disappears once its pods are gone. It reports whether obj still
+// exists.
+func (r *ReconcileMemcached) deleteOwned(m *cachev1alpha1.Memcached, obj runtime.Object) (bool, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	key := types.NamespacedName{Name: accessor.GetName(), Namespace: accessor.GetNamespace()}
+	if err := r.client.Get(context.TODO(), key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !metav1.IsControlledBy(accessor, m) {
+		return false, nil
+	}
+	if accessor.GetDeletionTimestamp() == nil {
+		err := r.client.Delete(context.TODO(), obj, client.PropagationPolicy(metav1.DeletePropagationForeground))
+		if err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// orphanOwned removes the owner reference of m from obj, so that the garbage
+// collector leaves obj alone once m is gone.
+func (r *ReconcileMemcached) orphanOwned(m *cachev1alpha1.Memcached, obj runtime.Object) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	key := types.NamespacedName{Name: accessor.GetName(), Namespace: accessor.GetNamespace()}
+	if err := r.client.Get(context.TODO(), key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	refs := accessor.GetOwnerReferences()
+	kept := refs[:0]
+	for _, ref := range refs {
+		if ref.UID != m.UID {
+			kept = append(kept, ref)
+		}
+	}
+	if len(kept) == len(refs) {
+		return nil
+	}
+	accessor.SetOwnerReferences(kept)
+	return r.client.Update(context.TODO(), obj)
+}
@@ -6,12 +6,15 @@ import (
 
 	cachev1alpha1 "github.com/operator-framework/operator-sdk-samples/go/memcached-operator/pkg/apis/cache/v1alpha1"
 
+	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -31,12 +34,20 @@ func Add(mgr manager.Manager) error {
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileMemcached{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager) *ReconcileMemcached {
+	r := &ReconcileMemcached{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+	// The prometheus-operator may not be installed, in which case the
+	// caches are monitored without ServiceMonitors and alerts.
+	serviceMonitors, prometheusRules, err := monitoringAPIs(mgr.GetConfig())
+	if err != nil {
+		log.Info("Could not discover the prometheus-operator APIs.", "error", err.Error())
+	}
+	r.serviceMonitors, r.prometheusRules = serviceMonitors, prometheusRules
+	return r
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+func add(mgr manager.Manager, r *ReconcileMemcached) error {
 	// Create a new controller
 	c, err := controller.New("memcached-controller", mgr, controller.Options{Reconciler: r})
 	if err != nil {
@@ -67,6 +78,18 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watching a kind the cluster does not serve fails, so only watch the
+	// prometheus-operator kinds that were discovered.
+	for _, obj := range r.monitoringObjects(&cachev1alpha1.Memcached{}) {
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &cachev1alpha1.Memcached{},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -79,6 +102,11 @@ type ReconcileMemcached struct {
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
 	scheme *runtime.Scheme
+
+	// serviceMonitors and prometheusRules say whether the cluster serves the
+	// prometheus-operator ServiceMonitor and PrometheusRule kinds.
+	serviceMonitors bool
+	prometheusRules bool
 }
 
 // Reconcile reads that state of the cluster for a Memcached object and makes changes based on the state read
@@ -98,7 +126,7 @@ func (r *ReconcileMemcached) Reconcile(request reconcile.Request) (reconcile.Res
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
-			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Teardown has finished, the finalizer was removed.
 			// Return and don't requeue
 			reqLogger.Info("Memcached resource not found. Ignoring since object must be deleted.")
 			return reconcile.Result{}, nil
@@ -108,12 +136,47 @@ func (r *ReconcileMemcached) Reconcile(request reconcile.Request) (reconcile.Res
 		return reconcile.Result{}, err
 	}
 
+	// A deleted Memcached is torn down according to its deletion policy.
+	if !memcached.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(reqLogger, memcached)
+	}
+	if err := r.ensureFinalizer(memcached); err != nil {
+		reqLogger.Error(err, "Failed to add finalizer.")
+		return reconcile.Result{}, err
+	}
+
+	result, err := r.reconcileMemcached(reqLogger, memcached)
+	if err != nil {
+		// Record why we failed so that it shows up in status.
+		if statusErr := r.updateFailedStatus(memcached, err); statusErr != nil {
+			reqLogger.Error(statusErr, "Failed to update Memcached status.")
+		}
+		// An invalid spec is not requeued: the next spec change triggers a
+		// new reconcile anyway.
+		if isSpecError(err) {
+			reqLogger.Error(err, "Invalid Memcached spec, refusing to reconcile.")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	return result, nil
+}
+
+// reconcileMemcached drives the cluster towards the state described by the
+// Memcached spec.
+func (r *ReconcileMemcached) reconcileMemcached(reqLogger logr.Logger, memcached *cachev1alpha1.Memcached) (reconcile.Result, error) {
+	// Work out which memcached release to run.
+	version, err := versionForMemcached(memcached)
+	if err != nil {
+		return reconcile.Result{}, specError{err}
+	}
+
 	// Check if the Deployment already exists, if not create a new one
+	dep := r.deploymentForMemcached(memcached)
 	deployment := &appsv1.Deployment{}
 	err = r.client.Get(context.TODO(), types.NamespacedName{Name: memcached.Name, Namespace: memcached.Namespace}, deployment)
 	if err != nil && errors.IsNotFound(err) {
-		// Define a new Deployment
-		dep := r.deploymentForMemcached(memcached)
+		// Create the Deployment defined above
 		reqLogger.Info("Creating a new Deployment.", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
 		err = r.client.Create(context.TODO(), dep)
 		if err != nil {
@@ -140,13 +203,30 @@ func (r *ReconcileMemcached) Reconcile(request reconcile.Request) (reconcile.Res
 		}
 	}
 
+	// Ensure the pod template matches the spec. Fields defaulted by the API
+	// server are ignored, so this only fires when the image or command changed,
+	// or when the exporter sidecar was added or removed. DeepDerivative
+	// accepts extra trailing containers, hence the length check.
+	if len(dep.Spec.Template.Spec.Containers) != len(deployment.Spec.Template.Spec.Containers) ||
+		!equality.Semantic.DeepDerivative(dep.Spec.Template, deployment.Spec.Template) ||
+		!equality.Semantic.DeepDerivative(dep.Spec.Strategy, deployment.Spec.Strategy) {
+		reqLogger.Info("Rolling out new pod template.", "Deployment.Namespace", deployment.Namespace, "Deployment.Name", deployment.Name, "Version", version.String())
+		deployment.Spec.Template = dep.Spec.Template
+		deployment.Spec.Strategy = dep.Spec.Strategy
+		err = r.client.Update(context.TODO(), deployment)
+		if err != nil {
+			reqLogger.Error(err, "Failed to update Deployment.", "Deployment.Namespace", deployment.Namespace, "Deployment.Name", deployment.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
 	// Check if the Service already exists, if not create a new one
 	// NOTE: The Service is used to expose the Deployment. However, the Service is not required at all for the memcached example to work. The purpose is to add more examples of what you can do in your operator project.
+	ser := r.serviceForMemcached(memcached)
 	service := &corev1.Service{}
 	err = r.client.Get(context.TODO(), types.NamespacedName{Name: memcached.Name, Namespace: memcached.Namespace}, service)
 	if err != nil && errors.IsNotFound(err) {
-		// Define a new Service object
-		ser := r.serviceForMemcached(memcached)
+		// Create the Service defined above
 		reqLogger.Info("Creating a new Service.", "Service.Namespace", ser.Namespace, "Service.Name", ser.Name)
 		err = r.client.Create(context.TODO(), ser)
 		if err != nil {
@@ -156,6 +236,28 @@ func (r *ReconcileMemcached) Reconcile(request reconcile.Request) (reconcile.Res
 	} else if err != nil {
 		reqLogger.Error(err, "Failed to get Service.")
 		return reconcile.Result{}, err
+	} else if len(service.Spec.Ports) != len(ser.Spec.Ports) || !equality.Semantic.DeepDerivative(ser.Spec.Ports, service.Spec.Ports) ||
+		!equality.Semantic.DeepDerivative(ser.Labels, service.Labels) {
+		// The metrics port comes and goes with spec.monitoring, and the
+		// ServiceMonitor finds the Service by its labels.
+		reqLogger.Info("Updating Service.", "Service.Namespace", service.Namespace, "Service.Name", service.Name)
+		service.Spec.Ports = ser.Spec.Ports
+		if service.Labels == nil {
+			service.Labels = map[string]string{}
+		}
+		for k, v := range ser.Labels {
+			service.Labels[k] = v
+		}
+		err = r.client.Update(context.TODO(), service)
+		if err != nil {
+			reqLogger.Error(err, "Failed to update Service.", "Service.Namespace", service.Namespace, "Service.Name", service.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Tell the prometheus-operator to scrape the exporters and what to alert on.
+	if err := r.reconcileMonitoring(reqLogger, memcached); err != nil {
+		return reconcile.Result{}, err
 	}
 
 	// Update the Memcached status with the pod names
@@ -172,9 +274,22 @@ func (r *ReconcileMemcached) Reconcile(request reconcile.Request) (reconcile.Res
 	}
 	podNames := getPodNames(podList.Items)
 
-	// Update status.Nodes if needed
-	if !reflect.DeepEqual(podNames, memcached.Status.Nodes) {
-		memcached.Status.Nodes = podNames
+	// Only report the new version once every pod runs it
+	runningVersion := memcached.Status.Version
+	if deploymentRolledOut(deployment) {
+		runningVersion = version.String()
+	}
+
+	// Update the status if needed
+	status := memcached.Status.DeepCopy()
+	status.Nodes = podNames
+	status.Version = runningVersion
+	status.ObservedGeneration = memcached.Generation
+	status.ReadyReplicas = deployment.Status.ReadyReplicas
+	status.LastError = ""
+	setDeploymentConditions(status, deployment, memcached.Generation)
+	if !reflect.DeepEqual(*status, memcached.Status) {
+		memcached.Status = *status
 		err := r.client.Status().Update(context.TODO(), memcached)
 		if err != nil {
 			reqLogger.Error(err, "Failed to update Memcached status.")
@@ -189,6 +304,10 @@ func (r *ReconcileMemcached) Reconcile(request reconcile.Request) (reconcile.Res
 func (r *ReconcileMemcached) deploymentForMemcached(m *cachev1alpha1.Memcached) *appsv1.Deployment {
 	ls := labelsForMemcached(m.Name)
 	replicas := m.Spec.Size
+	// Replace pods one at a time so that an upgrade never takes more than
+	// one cache shard offline.
+	maxUnavailable := intstr.FromInt(1)
+	maxSurge := intstr.FromInt(0)
 
 	dep := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -200,13 +319,20 @@ func (r *ReconcileMemcached) deploymentForMemcached(m *cachev1alpha1.Memcached)
 			Selector: &metav1.LabelSelector{
 				MatchLabels: ls,
 			},
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{
+					MaxUnavailable: &maxUnavailable,
+					MaxSurge:       &maxSurge,
+				},
+			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: ls,
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{{
-						Image:   "memcached:1.4.36-alpine",
+						Image:   imageForMemcached(m),
 						Name:    "memcached",
 						Command: []string{"memcached", "-m=64", "-o", "modern", "-v"},
 						Ports: []corev1.ContainerPort{{
@@ -218,6 +344,9 @@ func (r *ReconcileMemcached) deploymentForMemcached(m *cachev1alpha1.Memcached)
 			},
 		},
 	}
+	if monitoringEnabled(m) {
+		dep.Spec.Template.Spec.Containers = append(dep.Spec.Template.Spec.Containers, exporterContainer(m))
+	}
 	// Set Memcached instance as the owner of the Deployment.
 	controllerutil.SetControllerReference(m, dep, r.scheme)
 	return dep
@@ -230,6 +359,7 @@ func (r *ReconcileMemcached) serviceForMemcached(m *cachev1alpha1.Memcached) *co
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      m.Name,
 			Namespace: m.Namespace,
+			Labels:    ls,
 		},
 		Spec: corev1.ServiceSpec{
 			Selector: ls,
@@ -241,11 +371,31 @@ func (r *ReconcileMemcached) serviceForMemcached(m *cachev1alpha1.Memcached) *co
 			},
 		},
 	}
+	if monitoringEnabled(m) {
+		ser.Spec.Ports = append(ser.Spec.Ports, corev1.ServicePort{
+			Port:       exporterPort,
+			Name:       metricsPortName,
+			TargetPort: intstr.FromString(metricsPortName),
+		})
+	}
 	// Set Memcached instance as the owner of the Service.
 	controllerutil.SetControllerReference(m, ser, r.scheme)
 	return ser
 }
 
+// deploymentRolledOut reports whether the Deployment controller has observed
+// the latest spec and every replica runs the latest pod template.
+func deploymentRolledOut(dep *appsv1.Deployment) bool {
+	if dep.Generation > dep.Status.ObservedGeneration {
+		return false
+	}
+	replicas := int32(1)
+	if dep.Spec.Replicas != nil {
+		replicas = *dep.Spec.Replicas
+	}
+	return dep.Status.UpdatedReplicas == replicas && dep.Status.Replicas == replicas
+}
+
 // labelsForMemcached returns the labels for selecting the resources
 // belonging to the given memcached CR name.
 func labelsForMemcached(name string) map[string]string {
@@ -9,8 +9,10 @@ import (
 
 	cachev1alpha1 "github.com/operator-framework/operator-sdk-samples/go/memcached-operator/pkg/apis/cache/v1alpha1"
 
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -140,3 +142,412 @@ func TestMemcachedController(t *testing.T) {
 		t.Errorf("pod names %v did not match expected %v", nodes, podNames)
 	}
 }
+
+// TestMemcachedControllerVersion checks that changing spec.version rolls the
+// new image out and that status.version follows once the rollout completes.
+func TestMemcachedControllerVersion(t *testing.T) {
+	var (
+		name            = "memcached-operator"
+		namespace       = "memcached"
+		replicas  int32 = 3
+	)
+
+	memcached := &cachev1alpha1.Memcached{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: cachev1alpha1.MemcachedSpec{
+			Size:    replicas,
+			Version: "1.5.22",
+		},
+	}
+	s := scheme.Scheme
+	s.AddKnownTypes(cachev1alpha1.SchemeGroupVersion, memcached)
+	cl := fake.NewFakeClient(memcached)
+	r := &ReconcileMemcached{client: cl, scheme: s}
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	dep := &appsv1.Deployment{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, dep); err != nil {
+		t.Fatalf("get deployment: (%v)", err)
+	}
+	if image := dep.Spec.Template.Spec.Containers[0].Image; image != "memcached:1.5.22-alpine" {
+		t.Errorf("deployment image (%s) is not the expected image", image)
+	}
+
+	// Upgrade the instance.
+	if err := cl.Get(context.TODO(), req.NamespacedName, memcached); err != nil {
+		t.Fatalf("get memcached: (%v)", err)
+	}
+	memcached.Spec.Version = "1.6.9"
+	if err := cl.Update(context.TODO(), memcached); err != nil {
+		t.Fatalf("update memcached: (%v)", err)
+	}
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if err := cl.Get(context.TODO(), req.NamespacedName, dep); err != nil {
+		t.Fatalf("get deployment: (%v)", err)
+	}
+	if image := dep.Spec.Template.Spec.Containers[0].Image; image != "memcached:1.6.9-alpine" {
+		t.Errorf("deployment image (%s) was not upgraded", image)
+	}
+	if err := cl.Get(context.TODO(), req.NamespacedName, memcached); err != nil {
+		t.Fatalf("get memcached: (%v)", err)
+	}
+	if memcached.Status.Version != "" {
+		t.Errorf("status version (%s) reported before the rollout finished", memcached.Status.Version)
+	}
+
+	// Pretend the Deployment controller finished the rollout.
+	dep.Status.Replicas = replicas
+	dep.Status.UpdatedReplicas = replicas
+	if err := cl.Status().Update(context.TODO(), dep); err != nil {
+		t.Fatalf("update deployment status: (%v)", err)
+	}
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if err := cl.Get(context.TODO(), req.NamespacedName, memcached); err != nil {
+		t.Fatalf("get memcached: (%v)", err)
+	}
+	if memcached.Status.Version != "1.6.9" {
+		t.Errorf("status version (%s) is not the expected version (1.6.9)", memcached.Status.Version)
+	}
+}
+
+// TestMemcachedControllerConditions checks the conditions reported for an
+// invalid spec and for a fully rolled out instance.
+func TestMemcachedControllerConditions(t *testing.T) {
+	var (
+		name            = "memcached-operator"
+		namespace       = "memcached"
+		replicas  int32 = 3
+	)
+
+	memcached := &cachev1alpha1.Memcached{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: cachev1alpha1.MemcachedSpec{
+			Size:    replicas,
+			Version: "not-a-version",
+		},
+	}
+	s := scheme.Scheme
+	s.AddKnownTypes(cachev1alpha1.SchemeGroupVersion, memcached)
+	cl := fake.NewFakeClient(memcached)
+	r := &ReconcileMemcached{client: cl, scheme: s}
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+
+	// An invalid spec is reported, not retried.
+	res, err := r.Reconcile(req)
+	if err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if res != (reconcile.Result{}) {
+		t.Error("reconcile requeued an invalid spec")
+	}
+	if err := cl.Get(context.TODO(), req.NamespacedName, memcached); err != nil {
+		t.Fatalf("get memcached: (%v)", err)
+	}
+	degraded := memcached.Status.FindCondition(cachev1alpha1.MemcachedDegraded)
+	if degraded == nil || degraded.Status != corev1.ConditionTrue || degraded.Reason != reasonInvalidSpec {
+		t.Errorf("expected Degraded=True with reason %s, got %+v", reasonInvalidSpec, degraded)
+	}
+	if memcached.Status.LastError == "" {
+		t.Error("status.lastError was not set")
+	}
+
+	// Fix the spec and let the Deployment become ready.
+	memcached.Spec.Version = "1.6.9"
+	if err := cl.Update(context.TODO(), memcached); err != nil {
+		t.Fatalf("update memcached: (%v)", err)
+	}
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	dep := &appsv1.Deployment{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, dep); err != nil {
+		t.Fatalf("get deployment: (%v)", err)
+	}
+	dep.Status.Replicas = replicas
+	dep.Status.UpdatedReplicas = replicas
+	dep.Status.ReadyReplicas = replicas
+	if err := cl.Status().Update(context.TODO(), dep); err != nil {
+		t.Fatalf("update deployment status: (%v)", err)
+	}
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	memcached = &cachev1alpha1.Memcached{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, memcached); err != nil {
+		t.Fatalf("get memcached: (%v)", err)
+	}
+	want := map[cachev1alpha1.MemcachedConditionType]corev1.ConditionStatus{
+		cachev1alpha1.MemcachedAvailable:   corev1.ConditionTrue,
+		cachev1alpha1.MemcachedProgressing: corev1.ConditionFalse,
+		cachev1alpha1.MemcachedDegraded:    corev1.ConditionFalse,
+	}
+	for typ, status := range want {
+		if c := memcached.Status.FindCondition(typ); c == nil || c.Status != status {
+			t.Errorf("expected %s=%s, got %+v", typ, status, c)
+		}
+	}
+	if memcached.Status.ReadyReplicas != replicas {
+		t.Errorf("status ready replicas (%d) is not the expected size (%d)", memcached.Status.ReadyReplicas, replicas)
+	}
+	if memcached.Status.LastError != "" {
+		t.Errorf("status.lastError was not cleared: %s", memcached.Status.LastError)
+	}
+}
+
+// TestMemcachedControllerDeletionPolicy deletes a Memcached under both
+// deletion policies and checks what is left behind.
+func TestMemcachedControllerDeletionPolicy(t *testing.T) {
+	var (
+		name            = "memcached-operator"
+		namespace       = "memcached"
+		replicas  int32 = 3
+	)
+
+	for _, policy := range []cachev1alpha1.DeletionPolicy{cachev1alpha1.DeletionPolicyDelete, cachev1alpha1.DeletionPolicyOrphan} {
+		t.Run(string(policy), func(t *testing.T) {
+			memcached := &cachev1alpha1.Memcached{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+					UID:       "memcached-uid",
+				},
+				Spec: cachev1alpha1.MemcachedSpec{
+					Size:           replicas,
+					DeletionPolicy: policy,
+				},
+			}
+			s := scheme.Scheme
+			s.AddKnownTypes(cachev1alpha1.SchemeGroupVersion, memcached)
+			cl := fake.NewFakeClient(memcached)
+			r := &ReconcileMemcached{client: cl, scheme: s}
+			req := reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      name,
+					Namespace: namespace,
+				},
+			}
+
+			// Create the children and register the finalizer.
+			for i := 0; i < 2; i++ {
+				if _, err := r.Reconcile(req); err != nil {
+					t.Fatalf("reconcile: (%v)", err)
+				}
+			}
+			memcached = &cachev1alpha1.Memcached{}
+			if err := cl.Get(context.TODO(), req.NamespacedName, memcached); err != nil {
+				t.Fatalf("get memcached: (%v)", err)
+			}
+			if !hasFinalizer(memcached) {
+				t.Fatal("finalizer was not added")
+			}
+
+			// The fake client does not implement graceful deletion, so mark
+			// the Memcached as deleted by hand.
+			now := metav1.Now()
+			memcached.DeletionTimestamp = &now
+			if err := cl.Update(context.TODO(), memcached); err != nil {
+				t.Fatalf("update memcached: (%v)", err)
+			}
+			for i := 0; i < 5; i++ {
+				if _, err := r.Reconcile(req); err != nil {
+					t.Fatalf("reconcile: (%v)", err)
+				}
+			}
+			memcached = &cachev1alpha1.Memcached{}
+			if err := cl.Get(context.TODO(), req.NamespacedName, memcached); err != nil {
+				t.Fatalf("get memcached: (%v)", err)
+			}
+			if hasFinalizer(memcached) {
+				t.Fatal("finalizer was not removed")
+			}
+
+			dep := &appsv1.Deployment{}
+			depErr := cl.Get(context.TODO(), req.NamespacedName, dep)
+			svc := &corev1.Service{}
+			svcErr := cl.Get(context.TODO(), req.NamespacedName, svc)
+			switch policy {
+			case cachev1alpha1.DeletionPolicyDelete:
+				if !errors.IsNotFound(depErr) || !errors.IsNotFound(svcErr) {
+					t.Errorf("children were not deleted: deployment (%v), service (%v)", depErr, svcErr)
+				}
+			case cachev1alpha1.DeletionPolicyOrphan:
+				if depErr != nil || svcErr != nil {
+					t.Fatalf("children were deleted: deployment (%v), service (%v)", depErr, svcErr)
+				}
+				if len(dep.OwnerReferences) != 0 || len(svc.OwnerReferences) != 0 {
+					t.Error("children are still owned by the deleted Memcached")
+				}
+			}
+		})
+	}
+}
+
+// TestMemcachedControllerMonitoring checks that spec.monitoring adds the
+// exporter, the metrics port and the prometheus-operator objects, and that
+// turning it off removes them again.
+func TestMemcachedControllerMonitoring(t *testing.T) {
+	var (
+		name            = "memcached-operator"
+		namespace       = "memcached"
+		replicas  int32 = 3
+	)
+	memcached := &cachev1alpha1.Memcached{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			UID:       "memcached-uid",
+		},
+		Spec: cachev1alpha1.MemcachedSpec{
+			Size:       replicas,
+			Monitoring: &cachev1alpha1.MemcachedMonitoring{Enabled: true},
+		},
+	}
+	s := scheme.Scheme
+	s.AddKnownTypes(cachev1alpha1.SchemeGroupVersion, memcached)
+	if err := monitoringv1.AddToScheme(s); err != nil {
+		t.Fatalf("add monitoring types: (%v)", err)
+	}
+	cl := fake.NewFakeClient(memcached)
+	r := &ReconcileMemcached{client: cl, scheme: s, serviceMonitors: true, prometheusRules: true}
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := r.Reconcile(req); err != nil {
+			t.Fatalf("reconcile: (%v)", err)
+		}
+	}
+
+	dep := &appsv1.Deployment{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, dep); err != nil {
+		t.Fatalf("get deployment: (%v)", err)
+	}
+	if containers := dep.Spec.Template.Spec.Containers; len(containers) != 2 || containers[1].Image != defaultExporterImage {
+		t.Errorf("pod template has no exporter sidecar: %v", containers)
+	}
+	svc := &corev1.Service{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, svc); err != nil {
+		t.Fatalf("get service: (%v)", err)
+	}
+	if ports := svc.Spec.Ports; len(ports) != 2 || ports[1].Name != metricsPortName {
+		t.Errorf("service has no metrics port: %v", ports)
+	}
+	sm := &monitoringv1.ServiceMonitor{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, sm); err != nil {
+		t.Fatalf("get servicemonitor: (%v)", err)
+	}
+	if !reflect.DeepEqual(sm.Spec.Selector.MatchLabels, svc.Labels) {
+		t.Errorf("servicemonitor selects %v, service has labels %v", sm.Spec.Selector.MatchLabels, svc.Labels)
+	}
+	rule := &monitoringv1.PrometheusRule{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, rule); err != nil {
+		t.Fatalf("get prometheusrule: (%v)", err)
+	}
+	if len(rule.Spec.Groups) != 1 || len(rule.Spec.Groups[0].Rules) != 3 {
+		t.Errorf("prometheusrule has unexpected rules: %v", rule.Spec.Groups)
+	}
+
+	// Turn monitoring off again.
+	memcached = &cachev1alpha1.Memcached{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, memcached); err != nil {
+		t.Fatalf("get memcached: (%v)", err)
+	}
+	memcached.Spec.Monitoring.Enabled = false
+	if err := cl.Update(context.TODO(), memcached); err != nil {
+		t.Fatalf("update memcached: (%v)", err)
+	}
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	dep = &appsv1.Deployment{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, dep); err != nil {
+		t.Fatalf("get deployment: (%v)", err)
+	}
+	if containers := dep.Spec.Template.Spec.Containers; len(containers) != 1 {
+		t.Errorf("exporter sidecar was not removed: %v", containers)
+	}
+	svc = &corev1.Service{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, svc); err != nil {
+		t.Fatalf("get service: (%v)", err)
+	}
+	if len(svc.Spec.Ports) != 1 {
+		t.Errorf("metrics port was not removed: %v", svc.Spec.Ports)
+	}
+	if err := cl.Get(context.TODO(), req.NamespacedName, &monitoringv1.ServiceMonitor{}); !errors.IsNotFound(err) {
+		t.Errorf("servicemonitor was not deleted: (%v)", err)
+	}
+	if err := cl.Get(context.TODO(), req.NamespacedName, &monitoringv1.PrometheusRule{}); !errors.IsNotFound(err) {
+		t.Errorf("prometheusrule was not deleted: (%v)", err)
+	}
+}
+
+// TestMemcachedControllerUnsupportedVersion checks that a release without
+// "-o modern" is refused rather than run with a different command.
+func TestMemcachedControllerUnsupportedVersion(t *testing.T) {
+	var (
+		name      = "memcached-operator"
+		namespace = "memcached"
+	)
+
+	memcached := &cachev1alpha1.Memcached{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: cachev1alpha1.MemcachedSpec{
+			Size:    1,
+			Version: "1.4.20",
+		},
+	}
+	s := scheme.Scheme
+	s.AddKnownTypes(cachev1alpha1.SchemeGroupVersion, memcached)
+	cl := fake.NewFakeClient(memcached)
+	r := &ReconcileMemcached{client: cl, scheme: s}
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	dep := &appsv1.Deployment{}
+	if err := cl.Get(context.TODO(), req.NamespacedName, dep); !errors.IsNotFound(err) {
+		t.Errorf("expected no deployment for an unsupported version, got (%v)", err)
+	}
+	if err := cl.Get(context.TODO(), req.NamespacedName, memcached); err != nil {
+		t.Fatalf("get memcached: (%v)", err)
+	}
+	degraded := memcached.Status.FindCondition(cachev1alpha1.MemcachedDegraded)
+	if degraded == nil || degraded.Status != corev1.ConditionTrue || degraded.Reason != reasonInvalidSpec {
+		t.Errorf("expected Degraded=True with reason %s, got %+v", reasonInvalidSpec, degraded)
+	}
+}
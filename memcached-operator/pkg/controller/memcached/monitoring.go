@@ -0,0 +1,222 @@
+package memcached
+
+import (
+	"context"
+	"fmt"
+
+	cachev1alpha1 "github.com/operator-framework/operator-sdk-samples/go/memcached-operator/pkg/apis/cache/v1alpha1"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/go-logr/logr"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// defaultExporterImage is used when spec.monitoring.exporterImage is not
+	// set.
+	defaultExporterImage = "prom/memcached-exporter:v0.6.0"
+	// exporterPort is the port the exporter serves metrics on.
+	exporterPort = 9150
+	// metricsPortName names the exporter port on the pods and the Service.
+	metricsPortName = "metrics"
+)
+
+// monitoringAPIs reports which of the prometheus-operator kinds the cluster
+// serves. Without them the exporter still runs, but nothing tells
+// Prometheus to scrape it.
+func monitoringAPIs(cfg *rest.Config) (serviceMonitors, prometheusRules bool, err error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return false, false, err
+	}
+	gv := monitoringv1.SchemeGroupVersion.String()
+	if serviceMonitors, err = k8sutil.ResourceExists(dc, gv, monitoringv1.ServiceMonitorsKind); err != nil {
+		return false, false, err
+	}
+	if prometheusRules, err = k8sutil.ResourceExists(dc, gv, monitoringv1.PrometheusRuleKind); err != nil {
+		return false, false, err
+	}
+	return serviceMonitors, prometheusRules, nil
+}
+
+// monitoringEnabled reports whether m asks for the exporter.
+func monitoringEnabled(m *cachev1alpha1.Memcached) bool {
+	return m.Spec.Monitoring != nil && m.Spec.Monitoring.Enabled
+}
+
+// exporterContainer returns the exporter sidecar for m. It reads the stats
+// of the memcached container next to it.
+func exporterContainer(m *cachev1alpha1.Memcached) corev1.Container {
+	image := m.Spec.Monitoring.ExporterImage
+	if image == "" {
+		image = defaultExporterImage
+	}
+	return corev1.Container{
+		Image: image,
+		Name:  "exporter",
+		Args:  []string{"--memcached.address=localhost:11211"},
+		Ports: []corev1.ContainerPort{{
+			ContainerPort: exporterPort,
+			Name:          metricsPortName,
+		}},
+	}
+}
+
+// serviceMonitorForMemcached returns a ServiceMonitor scraping the exporter
+// of every pod behind the Service of m, which it selects by its labels.
+func (r *ReconcileMemcached) serviceMonitorForMemcached(m *cachev1alpha1.Memcached) *monitoringv1.ServiceMonitor {
+	sm := &monitoringv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name,
+			Namespace: m.Namespace,
+			Labels:    labelsForMemcached(m.Name),
+		},
+		Spec: monitoringv1.ServiceMonitorSpec{
+			Selector:  metav1.LabelSelector{MatchLabels: labelsForMemcached(m.Name)},
+			Endpoints: []monitoringv1.Endpoint{{Port: metricsPortName}},
+		},
+	}
+	// Set Memcached instance as the owner of the ServiceMonitor.
+	controllerutil.SetControllerReference(m, sm, r.scheme)
+	return sm
+}
+
+// prometheusRuleForMemcached returns the default alerts for m: items being
+// evicted, connections running out and memcached containers restarting.
+// The restart alert relies on kube-state-metrics exporting the memcached_cr
+// pod label.
+func (r *ReconcileMemcached) prometheusRuleForMemcached(m *cachev1alpha1.Memcached) *monitoringv1.PrometheusRule {
+	selector := fmt.Sprintf(`namespace=%q,service=%q`, m.Namespace, m.Name)
+	labels := map[string]string{"severity": "warning"}
+	rule := &monitoringv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name,
+			Namespace: m.Namespace,
+			Labels:    labelsForMemcached(m.Name),
+		},
+		Spec: monitoringv1.PrometheusRuleSpec{
+			Groups: []monitoringv1.RuleGroup{{
+				Name: "memcached-" + m.Name,
+				Rules: []monitoringv1.Rule{
+					{
+						Alert:  "MemcachedEvictions",
+						Expr:   intstr.FromString(fmt.Sprintf(`sum(rate(memcached_items_evicted_total{%s}[5m])) > 0`, selector)),
+						For:    "15m",
+						Labels: labels,
+						Annotations: map[string]string{
+							"summary": fmt.Sprintf("Memcached %s/%s is evicting items, the cache is too small.", m.Namespace, m.Name),
+						},
+					},
+					{
+						Alert:  "MemcachedConnectionsSaturated",
+						Expr:   intstr.FromString(fmt.Sprintf(`max(memcached_current_connections{%s} / memcached_max_connections{%s}) > 0.9`, selector, selector)),
+						For:    "5m",
+						Labels: labels,
+						Annotations: map[string]string{
+							"summary": fmt.Sprintf("Memcached %s/%s uses more than 90%% of its connections.", m.Namespace, m.Name),
+						},
+					},
+					{
+						// Pods are matched on their memcached_cr label, which
+						// kube-state-metrics exports as label_memcached_cr
+						Alert: "MemcachedPodRestarts",
+						Expr: intstr.FromString(fmt.Sprintf(`sum(increase(kube_pod_container_status_restarts_total{namespace=%q,container="memcached"}[1h]) * on(namespace, pod) group_left() kube_pod_labels{namespace=%q,label_memcached_cr=%q}) > 2`,
+							m.Namespace, m.Namespace, m.Name)),
+						Labels: labels,
+						Annotations: map[string]string{
+							"summary": fmt.Sprintf("Memcached %s/%s pods restarted more than twice in the last hour.", m.Namespace, m.Name),
+						},
+					},
+				},
+			}},
+		},
+	}
+	// Set Memcached instance as the owner of the PrometheusRule.
+	controllerutil.SetControllerReference(m, rule, r.scheme)
+	return rule
+}
+
+// monitoringObjects returns the prometheus-operator objects the cluster
+// serves for m, named after it.
+func (r *ReconcileMemcached) monitoringObjects(m *cachev1alpha1.Memcached) []runtime.Object {
+	var objs []runtime.Object
+	if r.serviceMonitors {
+		objs = append(objs, &monitoringv1.ServiceMonitor{ObjectMeta: metav1.ObjectMeta{Name: m.Name, Namespace: m.Namespace}})
+	}
+	if r.prometheusRules {
+		objs = append(objs, &monitoringv1.PrometheusRule{ObjectMeta: metav1.ObjectMeta{Name: m.Name, Namespace: m.Namespace}})
+	}
+	return objs
+}
+
+// reconcileMonitoring creates or updates the ServiceMonitor and
+// PrometheusRule of m when monitoring is enabled, and deletes them when it is
+// not. Kinds the cluster does not serve are skipped.
+func (r *ReconcileMemcached) reconcileMonitoring(reqLogger logr.Logger, m *cachev1alpha1.Memcached) error {
+	if !monitoringEnabled(m) {
+		for _, obj := range r.monitoringObjects(m) {
+			if _, err := r.deleteOwned(m, obj); err != nil {
+				reqLogger.Error(err, "Failed to delete monitoring object.")
+				return err
+			}
+		}
+		return nil
+	}
+
+	if r.serviceMonitors {
+		desired := r.serviceMonitorForMemcached(m)
+		found := &monitoringv1.ServiceMonitor{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, found)
+		if err != nil && errors.IsNotFound(err) {
+			reqLogger.Info("Creating a new ServiceMonitor.", "ServiceMonitor.Namespace", desired.Namespace, "ServiceMonitor.Name", desired.Name)
+			if err := r.client.Create(context.TODO(), desired); err != nil {
+				reqLogger.Error(err, "Failed to create new ServiceMonitor.")
+				return err
+			}
+		} else if err != nil {
+			reqLogger.Error(err, "Failed to get ServiceMonitor.")
+			return err
+		} else if !equality.Semantic.DeepDerivative(desired.Spec, found.Spec) {
+			reqLogger.Info("Updating ServiceMonitor.", "ServiceMonitor.Namespace", found.Namespace, "ServiceMonitor.Name", found.Name)
+			found.Spec = desired.Spec
+			if err := r.client.Update(context.TODO(), found); err != nil {
+				reqLogger.Error(err, "Failed to update ServiceMonitor.")
+				return err
+			}
+		}
+	}
+
+	if r.prometheusRules {
+		desired := r.prometheusRuleForMemcached(m)
+		found := &monitoringv1.PrometheusRule{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, found)
+		if err != nil && errors.IsNotFound(err) {
+			reqLogger.Info("Creating a new PrometheusRule.", "PrometheusRule.Namespace", desired.Namespace, "PrometheusRule.Name", desired.Name)
+			if err := r.client.Create(context.TODO(), desired); err != nil {
+				reqLogger.Error(err, "Failed to create new PrometheusRule.")
+				return err
+			}
+		} else if err != nil {
+			reqLogger.Error(err, "Failed to get PrometheusRule.")
+			return err
+		} else if !equality.Semantic.DeepEqual(desired.Spec, found.Spec) {
+			reqLogger.Info("Updating PrometheusRule.", "PrometheusRule.Namespace", found.Namespace, "PrometheusRule.Name", found.Name)
+			found.Spec = desired.Spec
+			if err := r.client.Update(context.TODO(), found); err != nil {
+				reqLogger.Error(err, "Failed to update PrometheusRule.")
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package memcached
+
+import (
+	"context"
+	"fmt"
+
+	cachev1alpha1 "github.com/operator-framework/operator-sdk-samples/go/memcached-operator/pkg/apis/cache/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Condition reasons set by the controller.
+const (
+	reasonReplicasReady            = "ReplicasReady"
+	reasonNoReplicasReady          = "NoReplicasReady"
+	reasonScaledToZero             = "ScaledToZero"
+	reasonRollingOut               = "RollingOut"
+	reasonWaitingForReplicas       = "WaitingForReplicas"
+	reasonRolloutComplete          = "RolloutComplete"
+	reasonAsExpected               = "AsExpected"
+	reasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	reasonReplicaFailure           = "ReplicaFailure"
+	reasonInvalidSpec              = "InvalidSpec"
+	reasonReconcileError           = "ReconcileError"
+	reasonTearingDown              = "TearingDown"
+)
+
+// specError marks errors caused by an invalid spec. Retrying cannot fix
+// them, so they are reported in status and not requeued.
+type specError struct {
+	error
+}
+
+func isSpecError(err error) bool {
+	_, ok := err.(specError)
+	return ok
+}
+
+// updateFailedStatus records a failed reconcile in the status of m.
+func (r *ReconcileMemcached) updateFailedStatus(m *cachev1alpha1.Memcached, err error) error {
+	reason := reasonReconcileError
+	if isSpecError(err) {
+		reason = reasonInvalidSpec
+		// The controller has acted on this generation: it rejected it.
+		m.Status.ObservedGeneration = m.Generation
+	}
+	m.Status.LastError = err.Error()
+	m.Status.SetCondition(cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedDegraded,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: m.Generation,
+		Reason:             reason,
+		Message:            err.Error(),
+	})
+	return r.client.Status().Update(context.TODO(), m)
+}
+
+// setDeploymentConditions derives the Available, Progressing and Degraded
+// conditions from the state of the memcached Deployment.
+func setDeploymentConditions(status *cachev1alpha1.MemcachedStatus, dep *appsv1.Deployment, generation int64) {
+	desired := int32(1)
+	if dep.Spec.Replicas != nil {
+		desired = *dep.Spec.Replicas
+	}
+	ready := dep.Status.ReadyReplicas
+
+	available := cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedAvailable,
+		ObservedGeneration: generation,
+		Message:            fmt.Sprintf("%d/%d replicas ready", ready, desired),
+	}
+	switch {
+	case ready > 0:
+		available.Status, available.Reason = corev1.ConditionTrue, reasonReplicasReady
+	case desired == 0:
+		available.Status, available.Reason = corev1.ConditionFalse, reasonScaledToZero
+	default:
+		available.Status, available.Reason = corev1.ConditionFalse, reasonNoReplicasReady
+	}
+	status.SetCondition(available)
+
+	progressing := cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedProgressing,
+		ObservedGeneration: generation,
+	}
+	switch {
+	case !deploymentRolledOut(dep):
+		progressing.Status, progressing.Reason = corev1.ConditionTrue, reasonRollingOut
+		progressing.Message = fmt.Sprintf("%d/%d replicas updated", dep.Status.UpdatedReplicas, desired)
+	case ready < desired:
+		progressing.Status, progressing.Reason = corev1.ConditionTrue, reasonWaitingForReplicas
+		progressing.Message = fmt.Sprintf("%d/%d replicas ready", ready, desired)
+	default:
+		progressing.Status, progressing.Reason = corev1.ConditionFalse, reasonRolloutComplete
+		progressing.Message = "all replicas are up to date and ready"
+	}
+	status.SetCondition(progressing)
+
+	degraded := cachev1alpha1.MemcachedCondition{
+		Type:               cachev1alpha1.MemcachedDegraded,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             reasonAsExpected,
+	}
+	for _, c := range dep.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Status == corev1.ConditionFalse && c.Reason == "ProgressDeadlineExceeded" {
+			degraded.Status, degraded.Reason, degraded.Message = corev1.ConditionTrue, reasonProgressDeadlineExceeded, c.Message
+			break
+		}
+		if c.Type == appsv1.DeploymentReplicaFailure && c.Status == corev1.ConditionTrue {
+			degraded.Status, degraded.Reason, degraded.Message = corev1.ConditionTrue, reasonReplicaFailure, c.Message
+			break
+		}
+	}
+	status.SetCondition(degraded)
+}
@@ -0,0 +1,107 @@
+package memcached
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	cachev1alpha1 "github.com/operator-framework/operator-sdk-samples/go/memcached-operator/pkg/apis/cache/v1alpha1"
+)
+
+const (
+	// defaultVersion is the memcached version run when neither spec.version
+	// nor spec.image is set.
+	defaultVersion = "1.4.36"
+	// defaultImageRepository is the repository used when spec.image is not set.
+	defaultImageRepository = "memcached"
+)
+
+var (
+	// minimumVersion is the oldest memcached release the operator can run.
+	minimumVersion = memcachedVersion{1, 4, 0}
+	// modernVersion is the first release that understands "-o modern".
+	modernVersion = memcachedVersion{1, 4, 33}
+)
+
+// memcachedVersion is a parsed <major>.<minor>.<patch> memcached release.
+type memcachedVersion [3]int
+
+// parseVersion parses versions such as "1.6.9", "v1.6.9" or "1.6.9-alpine".
+func parseVersion(s string) (memcachedVersion, error) {
+	var v memcachedVersion
+	trimmed := strings.TrimPrefix(s, "v")
+	if i := strings.Index(trimmed, "-"); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+	parts := strings.Split(trimmed, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("invalid memcached version %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid memcached version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+func (v memcachedVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// atLeast reports whether v is the same as or newer than o.
+func (v memcachedVersion) atLeast(o memcachedVersion) bool {
+	for i := range v {
+		if v[i] != o[i] {
+			return v[i] > o[i]
+		}
+	}
+	return true
+}
+
+// imageForMemcached returns the container image to run for m.
+func imageForMemcached(m *cachev1alpha1.Memcached) string {
+	if m.Spec.Image != "" {
+		return m.Spec.Image
+	}
+	version := m.Spec.Version
+	if version == "" {
+		version = defaultVersion
+	}
+	return fmt.Sprintf("%s:%s-alpine", defaultImageRepository, version)
+}
+
+// versionForMemcached returns the memcached release m asks for: spec.version,
+// else the tag of spec.image, else defaultVersion.
+func versionForMemcached(m *cachev1alpha1.Memcached) (memcachedVersion, error) {
+	s := m.Spec.Version
+	if s == "" && m.Spec.Image != "" {
+		image := m.Spec.Image
+		if i := strings.Index(image, "@"); i >= 0 {
+			image = image[:i]
+		}
+		colon := strings.LastIndex(image, ":")
+		if colon <= strings.LastIndex(image, "/") {
+			return memcachedVersion{}, fmt.Errorf("spec.version is required when spec.image %q has no version tag", m.Spec.Image)
+		}
+		s = image[colon+1:]
+	}
+	if s == "" {
+		s = defaultVersion
+	}
+	v, err := parseVersion(s)
+	if err != nil {
+		return v, err
+	}
+	if !v.atLeast(minimumVersion) {
+		return v, fmt.Errorf("memcached %s is not supported, the minimum version is %s", v, minimumVersion)
+	}
+	// Every instance runs with -o modern, so older releases are refused
+	// rather than run with a different command than the one asked for.
+	if !v.atLeast(modernVersion) {
+		return v, fmt.Errorf("memcached %s does not support -o modern, which requires %s", v, modernVersion)
+	}
+	return v, nil
+}